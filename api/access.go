@@ -3,9 +3,13 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
 	"github.com/jasoncolburne/better-auth-go/pkg/messages"
 	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
 )
@@ -14,6 +18,80 @@ type AccessVerifier[AttributesType any] struct {
 	crypto   *VerifierCryptoContainer
 	encoding *VerifierEncodingContainer
 	store    *VerifierStoreContainer
+	options  accessVerifierOptions
+}
+
+type accessVerifierOptions struct {
+	batchSize  int
+	cache      storageinterfaces.TokenCache
+	freshness  time.Duration
+	revocation storageinterfaces.RevocationStore
+	skew       messages.ClockSkew
+	tenant     string
+	versions   []string
+}
+
+type AccessVerifierOption func(*accessVerifierOptions)
+
+// RequireTenant rejects tokens issued for any tenant other than tenant.
+func RequireTenant(tenant string) AccessVerifierOption {
+	return func(o *accessVerifierOptions) {
+		o.tenant = tenant
+	}
+}
+
+// CheckRevocation rejects tokens belonging to sessions revoked in store, e.g. after refresh reuse
+// is detected or a session is terminated.
+func CheckRevocation(store storageinterfaces.RevocationStore) AccessVerifierOption {
+	return func(o *accessVerifierOptions) {
+		o.revocation = store
+	}
+}
+
+// CacheTokens remembers tokens once their signatures verify, so that later requests presenting the
+// same token skip decompressing it and verifying the token signature. The server access key must
+// still be in the key store, and the token's lifetime, the request signature and nonce, and every
+// other check still run on each request. Sessions found revoked and keys found retired have their
+// tokens dropped from the cache. Each request gets its own copy of a cached token.
+func CacheTokens(cache storageinterfaces.TokenCache) AccessVerifierOption {
+	return func(o *accessVerifierOptions) {
+		o.cache = cache
+	}
+}
+
+// RequireFreshAuthentication rejects tokens whose session was created (the device key was last
+// proven) more than maximumAge ago. Clients should respond to the resulting error by running
+// RequestSession/CreateSession again.
+func RequireFreshAuthentication(maximumAge time.Duration) AccessVerifierOption {
+	return func(o *accessVerifierOptions) {
+		o.freshness = maximumAge
+	}
+}
+
+// AllowClockSkew accepts tokens and requests timestamped up to future ahead of the verifier's
+// clock, and tokens up to past after their expiry, for deployments whose clocks drift apart.
+func AllowClockSkew(future, past time.Duration) AccessVerifierOption {
+	return func(o *accessVerifierOptions) {
+		o.skew = messages.ClockSkew{Future: future, Past: past}
+	}
+}
+
+// AcceptProtocolVersions sets the protocol versions of access requests the verifier accepts.
+// Defaults to messages.DefaultProtocolVersion.
+func AcceptProtocolVersions(versions ...string) AccessVerifierOption {
+	return func(o *accessVerifierOptions) {
+		o.versions = versions
+	}
+}
+
+// DefaultMaximumBatchSize is the most sub-requests VerifyBatch accepts unless LimitBatchSize is given.
+const DefaultMaximumBatchSize = 64
+
+// LimitBatchSize sets the most sub-requests VerifyBatch accepts in one batch.
+func LimitBatchSize(maximum int) AccessVerifierOption {
+	return func(o *accessVerifierOptions) {
+		o.batchSize = maximum
+	}
 }
 
 type VerifierCryptoContainer struct {
@@ -27,6 +105,7 @@ type VerifierCryptoPublicKey struct {
 type VerifierEncodingContainer struct {
 	TokenEncoder encodinginterfaces.TokenEncoder
 	Timestamper  encodinginterfaces.Timestamper
+	MessageCodec encodinginterfaces.MessageCodec
 }
 
 type VerifierStoreContainer struct {
@@ -38,12 +117,39 @@ func NewAccessVerifier[AttributesType any](
 	crypto *VerifierCryptoContainer,
 	encoding *VerifierEncodingContainer,
 	store *VerifierStoreContainer,
+	options ...AccessVerifierOption,
 ) *AccessVerifier[AttributesType] {
-	return &AccessVerifier[AttributesType]{
+	av := &AccessVerifier[AttributesType]{
 		crypto:   crypto,
 		encoding: encoding,
 		store:    store,
 	}
+
+	for _, option := range options {
+		option(&av.options)
+	}
+
+	if len(av.options.versions) == 0 {
+		av.options.versions = []string{messages.DefaultProtocolVersion}
+	}
+
+	if av.options.batchSize == 0 {
+		av.options.batchSize = DefaultMaximumBatchSize
+	}
+
+	return av
+}
+
+// With returns a verifier sharing this verifier's containers, with additional options applied.
+// This is useful for guarding sensitive operations, e.g. av.With(RequireFreshAuthentication(5 * time.Minute)).
+func (av *AccessVerifier[AttributesType]) With(options ...AccessVerifierOption) *AccessVerifier[AttributesType] {
+	derived := *av
+
+	for _, option := range options {
+		option(&derived.options)
+	}
+
+	return &derived
 }
 
 type AccessScanner[AttributesType any] = messages.AccessRequest[json.RawMessage, AttributesType]
@@ -53,23 +159,197 @@ func ParseAccessScanner[AttributesType any](message string) (*AccessScanner[Attr
 }
 
 func (av *AccessVerifier[AttributesType]) Verify(ctx context.Context, message string, attributes *AttributesType) (json.RawMessage, *messages.AccessToken[AttributesType], string, error) {
+	message, err := decodeMessage(av.encoding.MessageCodec, message)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
 	request, err := ParseAccessScanner[AttributesType](message)
 	if err != nil {
 		return nil, nil, "", err
 	}
 
-	token, err := request.VerifyAccess(
+	if request.Payload.Access.Batch {
+		return nil, nil, "", errors.NewInvalidMessageError("payload.access.batch", "batch requests must be verified with VerifyBatch")
+	}
+
+	if err := av.acceptVersion(&request.Payload.Access); err != nil {
+		return nil, nil, "", err
+	}
+
+	token, err := av.verifyAccess(ctx, request, attributes)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return request.Payload.Request, token, request.Payload.Access.Nonce, nil
+}
+
+type BatchAccessScanner[AttributesType any] = messages.BatchAccessRequest[json.RawMessage, AttributesType]
+
+func ParseBatchAccessScanner[AttributesType any](message string) (*BatchAccessScanner[AttributesType], error) {
+	return messages.ParseBatchAccessRequest[json.RawMessage, AttributesType](message)
+}
+
+// VerifyBatch verifies a batch access request, returning the sub-requests in order. The batch is
+// authorized as a whole: one token, one signature and one nonce reservation cover every item, so
+// respond with a single messages.BatchResponse under the returned nonce. Batches larger than
+// LimitBatchSize allows are rejected.
+func (av *AccessVerifier[AttributesType]) VerifyBatch(ctx context.Context, message string, attributes *AttributesType) ([]json.RawMessage, *messages.AccessToken[AttributesType], string, error) {
+	message, err := decodeMessage(av.encoding.MessageCodec, message)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	request, err := ParseBatchAccessScanner[AttributesType](message)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if len(request.Payload.Request) > av.options.batchSize {
+		return nil, nil, "", errors.NewInvalidMessageError(
+			"payload.request",
+			fmt.Sprintf("batch of %d exceeds the maximum of %d", len(request.Payload.Request), av.options.batchSize),
+		)
+	}
+
+	if err := av.acceptVersion(&request.Payload.Access); err != nil {
+		return nil, nil, "", err
+	}
+
+	token, err := av.verifyAccess(ctx, request, attributes)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return request.Payload.Request, token, request.Payload.Access.Nonce, nil
+}
+
+func (av *AccessVerifier[AttributesType]) acceptVersion(access *messages.AccessRequestAccess) error {
+	version := access.ProtocolVersion()
+	if !slices.Contains(av.options.versions, version) {
+		return errors.NewUnsupportedVersionError(version, av.options.versions)
+	}
+
+	return nil
+}
+
+type accessRequest[AttributesType any] interface {
+	Token() string
+	VerifyToken(
+		ctx context.Context,
+		accessKeyStore storageinterfaces.VerificationKeyStore,
+		tokenEncoder encodinginterfaces.TokenEncoder,
+		timestamper encodinginterfaces.Timestamper,
+		skew messages.ClockSkew,
+	) (*messages.AccessToken[AttributesType], error)
+	VerifyRequest(
+		ctx context.Context,
+		nonceStore storageinterfaces.TimeLockStore,
+		verifier cryptointerfaces.Verifier,
+		accessToken *messages.AccessToken[AttributesType],
+		timestamper encodinginterfaces.Timestamper,
+		skew messages.ClockSkew,
+	) error
+}
+
+func (av *AccessVerifier[AttributesType]) verifyAccess(ctx context.Context, request accessRequest[AttributesType], attributes *AttributesType) (*messages.AccessToken[AttributesType], error) {
+	token, err := av.verifyToken(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// before the request's nonce is reserved, so a token from another tenant can't burn it
+	if token.Tenant != av.options.tenant {
+		return nil, errors.NewMismatchedTenantError(av.options.tenant, token.Tenant)
+	}
+
+	if err := request.VerifyRequest(
 		ctx,
 		av.store.AccessNonce,
-		av.crypto.Verifier,
+		messages.EncodedVerifier(av.crypto.Verifier, av.encoding.MessageCodec),
+		token,
+		av.encoding.Timestamper,
+		av.options.skew,
+	); err != nil {
+		return nil, err
+	}
+
+	if av.options.revocation != nil {
+		revoked, err := isRevoked(ctx, av.options.revocation, token)
+		if err != nil {
+			return nil, err
+		}
+
+		if revoked {
+			if av.options.cache != nil {
+				av.options.cache.InvalidateSession(token.Session)
+			}
+
+			return nil, errors.NewRevokedSessionError(token.Session)
+		}
+	}
+
+	if av.options.freshness > 0 {
+		if err := token.VerifyFreshness(av.encoding.Timestamper, av.options.freshness); err != nil {
+			return nil, err
+		}
+	}
+
+	return token, nil
+}
+
+func (av *AccessVerifier[AttributesType]) verifyToken(ctx context.Context, request accessRequest[AttributesType]) (*messages.AccessToken[AttributesType], error) {
+	cache := av.options.cache
+	if cache != nil {
+		if cached, ok := cache.Get(request.Token()); ok {
+			// a cache shared by verifiers of different attribute types can hold foreign tokens
+			if token, ok := cached.Token.(*messages.AccessToken[AttributesType]); ok {
+				// removing a server access key from the store retires its tokens, cached or not
+				if _, err := av.store.AccessKey.Get(ctx, token.ServerIdentity); err != nil {
+					cache.InvalidateServerIdentity(token.ServerIdentity)
+					return nil, err
+				}
+
+				if err := token.VerifyLifetime(av.encoding.Timestamper, av.options.skew); err != nil {
+					return nil, err
+				}
+
+				// every request gets its own copy, so a caller modifying it can't affect the next
+				return token.Clone()
+			}
+		}
+	}
+
+	token, err := request.VerifyToken(
+		ctx,
 		av.store.AccessKey,
 		av.encoding.TokenEncoder,
 		av.encoding.Timestamper,
-		attributes,
+		av.options.skew,
 	)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, err
 	}
 
-	return request.Payload.Request, token, request.Payload.Access.Nonce, nil
+	if cache != nil {
+		expiry, err := av.encoding.Timestamper.Parse(token.Expiry)
+		if err != nil {
+			return nil, err
+		}
+
+		cached, err := token.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		cache.Put(request.Token(), &storageinterfaces.CachedToken{
+			Token:          cached,
+			ServerIdentity: token.ServerIdentity,
+			Session:        token.Session,
+			Expiry:         expiry.Add(av.options.skew.Past),
+		})
+	}
+
+	return token, nil
 }
@@ -8,12 +8,21 @@ import (
 )
 
 func (ba *BetterAuthServer[AttributesType]) CreateAccount(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseCreateAccountRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Authentication.PublicKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Authentication.PublicKey); err != nil {
 		return "", err
 	}
 
@@ -63,12 +72,13 @@ func (ba *BetterAuthServer[AttributesType]) CreateAccount(ctx context.Context, m
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -77,12 +87,21 @@ func (ba *BetterAuthServer[AttributesType]) CreateAccount(ctx context.Context, m
 }
 
 func (ba *BetterAuthServer[AttributesType]) RecoverAccount(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseRecoverAccountRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Authentication.RecoveryKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Authentication.RecoveryKey); err != nil {
 		return "", err
 	}
 
@@ -129,12 +148,13 @@ func (ba *BetterAuthServer[AttributesType]) RecoverAccount(ctx context.Context,
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -143,16 +163,25 @@ func (ba *BetterAuthServer[AttributesType]) RecoverAccount(ctx context.Context,
 }
 
 func (ba *BetterAuthServer[AttributesType]) DeleteAccount(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseDeleteAccountRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Authentication.PublicKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Authentication.PublicKey); err != nil {
 		return "", err
 	}
 
-	if err := ba.store.Authentication.Key.Rotate(
+	if err := ba.rotateKey(
 		ctx,
 		request.Payload.Request.Authentication.Identity,
 		request.Payload.Request.Authentication.Device,
@@ -179,12 +208,13 @@ func (ba *BetterAuthServer[AttributesType]) DeleteAccount(ctx context.Context, m
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -193,16 +223,25 @@ func (ba *BetterAuthServer[AttributesType]) DeleteAccount(ctx context.Context, m
 }
 
 func (ba *BetterAuthServer[AttributesType]) ChangeRecoveryKey(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseChangeRecoveryKeyRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Authentication.PublicKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Authentication.PublicKey); err != nil {
 		return "", err
 	}
 
-	if err := ba.store.Authentication.Key.Rotate(
+	if err := ba.rotateKey(
 		ctx,
 		request.Payload.Request.Authentication.Identity,
 		request.Payload.Request.Authentication.Device,
@@ -231,12 +270,13 @@ func (ba *BetterAuthServer[AttributesType]) ChangeRecoveryKey(ctx context.Contex
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -2,16 +2,20 @@ package api_test
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"io"
 	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
 	"github.com/jasoncolburne/better-auth-go/examples/crypto"
 	"github.com/jasoncolburne/better-auth-go/examples/encoding"
 	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/messages"
 )
@@ -42,14 +46,44 @@ func NewFakeAccessRequest(
 }
 
 func TestAccess(t *testing.T) {
-	if err := testFlow(); err != nil {
+	if _, err := testFlow(rand.Reader, clock.NewSystemClock()); err != nil {
 		fmt.Printf("error: %v\n", err)
 		t.Fail()
 	}
 }
 
-func testFlow() error {
+// TestAccessTranscript replays the flow from a seed and a stopped clock, which reproduces every
+// message and reply.
+func TestAccessTranscript(t *testing.T) {
+	transcript := func(seed string) []string {
+		transcript, err := testFlow(
+			crypto.NewSeededEntropy([]byte(seed)),
+			clock.NewManualClock(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		)
+		if err != nil {
+			t.Fatalf("flow failed: %v", err)
+		}
+
+		return transcript
+	}
+
+	first := transcript("seed")
+	second := transcript("seed")
+
+	if !slices.Equal(first, second) {
+		t.Fatalf("expected the seeded flow to reproduce its transcript")
+	}
+
+	if slices.Equal(first, transcript("other seed")) {
+		t.Fatalf("expected different seeds to produce different transcripts")
+	}
+}
+
+// testFlow runs every operation once, drawing keys and nonces from entropy. It returns each message
+// with the reply it received.
+func testFlow(entropy io.Reader, clock clockinterfaces.Clock) ([]string, error) {
 	ctx := context.Background()
+	transcript := []string{}
 
 	accessLifetime := 15 * time.Minute
 	accessWindow := 30 * time.Second
@@ -58,36 +92,38 @@ func testFlow() error {
 
 	hasher := crypto.NewBlake3()
 	verifier := crypto.NewSecp256r1Verifier()
-	noncer := crypto.NewNoncer()
+	noncer := crypto.NewNoncerWithEntropy(entropy)
 
-	accessKeyHashStore := storage.NewInMemoryTimeLockStore(refreshLifetime)
-	accessNonceStore := storage.NewInMemoryTimeLockStore(accessWindow)
-	authenticationKeyStore := storage.NewInMemoryAuthenticationKeyStore(hasher)
-	authenticationNonceStore := storage.NewInMemoryAuthenticationNonceStore(authenticationChallengeLifetime)
+	accessKeyHashStore := storage.NewInMemoryTimeLockStoreWithClock(refreshLifetime, clock)
+	accessNonceStore := storage.NewInMemoryTimeLockStoreWithClock(accessWindow, clock)
+	revocationStore := storage.NewInMemoryRevocationStoreWithClock(refreshLifetime, clock)
+	authenticationKeyStore := storage.NewInMemoryAuthenticationKeyStore()
+	authenticationNonceStore := storage.NewInMemoryAuthenticationNonceStoreWithNoncer(authenticationChallengeLifetime, clock, crypto.NewNoncerWithEntropy(entropy))
 	recoveryHashStore := storage.NewInMemoryRecoveryHashStore()
+	sessionStore := storage.NewInMemorySessionStoreWithClock(clock)
 
 	identityVerifier := encoding.NewMockIdentityVerifier(hasher)
-	timestamper := encoding.NewRfc3339()
+	timestamper := encoding.NewRfc3339WithClock(clock)
 	tokenEncoder := encoding.NewTokenEncoder[MockAttributes]()
 
-	serverResponseKey, err := crypto.NewSecp256r1()
+	serverResponseKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	serverResponsePublicKey, err := serverResponseKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	serverAccessKey, err := crypto.NewSecp256r1()
+	serverAccessKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	accessIdentity, err := serverAccessKey.Identity()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	accessKeyStore := storage.NewVerificationKeyStore()
@@ -115,6 +151,7 @@ func testFlow() error {
 		&api.StoresContainer{
 			Access: &api.AccessStoreContainer{
 				KeyHash:         accessKeyHashStore,
+				Revocation:      revocationStore,
 				VerificationKey: accessKeyStore,
 			},
 			Authentication: &api.AuthenticationStoreContainer{
@@ -124,6 +161,9 @@ func testFlow() error {
 			Recovery: &api.RecoveryStoreContainer{
 				Hash: recoveryHashStore,
 			},
+			Session: &api.SessionStoreContainer{
+				Registry: sessionStore,
+			},
 		},
 	)
 
@@ -141,45 +181,45 @@ func testFlow() error {
 		},
 	)
 
-	currentAuthenticationKey, err := crypto.NewSecp256r1()
+	currentAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	nextAuthenticationKey, err := crypto.NewSecp256r1()
+	nextAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	nextNextAuthenticationKey, err := crypto.NewSecp256r1()
+	nextNextAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	recoveryKey, err := crypto.NewSecp256r1()
+	recoveryKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	nextAuthenticationPublicKey, err := nextAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	nextNextAuthenticationPublicKey, err := nextNextAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rotationHash := hasher.Sum([]byte(nextAuthenticationPublicKey))
 	currentKey, err := currentAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	recoveryPublicKey, err := recoveryKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	recoveryHash := hasher.Sum([]byte(recoveryPublicKey))
@@ -190,7 +230,7 @@ func testFlow() error {
 
 	nonce, err := noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	createRequest := messages.NewCreateAccountRequest(
@@ -207,35 +247,37 @@ func testFlow() error {
 	)
 
 	if err := createRequest.Sign(currentAuthenticationKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err := createRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reply, err := ba.CreateAccount(ctx, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	createResponse, err := messages.ParseCreateAccountResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := createResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, createResponse.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce 1")
+		return nil, fmt.Errorf("bad nonce 1")
 	}
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rotationHash = hasher.Sum([]byte(nextNextAuthenticationPublicKey))
@@ -253,35 +295,37 @@ func testFlow() error {
 	)
 
 	if err := rotateDeviceRequest.Sign(nextAuthenticationKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = rotateDeviceRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reply, err = ba.RotateDevice(ctx, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	rotateDeviceResponse, err := messages.ParseRotateDeviceResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := rotateDeviceResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, rotateDeviceResponse.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce 2")
+		return nil, fmt.Errorf("bad nonce 2")
 	}
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	requestSessionRequest := messages.NewRequestSessionRequest(
@@ -295,60 +339,62 @@ func testFlow() error {
 
 	message, err = requestSessionRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reply, err = ba.RequestSession(ctx, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	requestSessionResponse, err := messages.ParseRequestSessionResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := requestSessionResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, requestSessionResponse.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce 3")
+		return nil, fmt.Errorf("bad nonce 3")
 	}
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	clientAccessKey, err := crypto.NewSecp256r1()
+	clientAccessKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	clientNextAccessKey, err := crypto.NewSecp256r1()
+	clientNextAccessKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	clientNextNextAccessKey, err := crypto.NewSecp256r1()
+	clientNextNextAccessKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	clientAccessPublicKey, err := clientAccessKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	clientNextAccessPublicKey, err := clientNextAccessKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	clientNextNextAccessPublicKey, err := clientNextNextAccessKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rotationHash = hasher.Sum([]byte(clientNextAccessPublicKey))
@@ -368,12 +414,12 @@ func testFlow() error {
 	)
 
 	if err := createSessionRequest.Sign(nextAuthenticationKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = createSessionRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	attributes := MockAttributes{
@@ -384,25 +430,27 @@ func testFlow() error {
 
 	reply, err = ba.CreateSession(ctx, message, attributes)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	createSessionResponse, err := messages.ParseCreateSessionResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := createSessionResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, createSessionRequest.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce")
+		return nil, fmt.Errorf("bad nonce")
 	}
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rotationHash = hasher.Sum([]byte(clientNextNextAccessPublicKey))
@@ -419,35 +467,37 @@ func testFlow() error {
 	)
 
 	if err := refreshSessionRequest.Sign(clientNextAccessKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = refreshSessionRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reply, err = ba.RefreshSession(ctx, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	refreshSessionResponse, err := messages.ParseRefreshSessionResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := refreshSessionResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, refreshSessionResponse.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce")
+		return nil, fmt.Errorf("bad nonce")
 	}
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	accessRequest := NewFakeAccessRequest(
@@ -461,67 +511,69 @@ func testFlow() error {
 	)
 
 	if err := accessRequest.Sign(clientNextAccessKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = accessRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	request, token, verifiedNonce, err := av.Verify(ctx, message, &MockAttributes{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, string(request))
+
 	if verifiedNonce != nonce {
-		return fmt.Errorf("bad nonce")
+		return nil, fmt.Errorf("bad nonce")
 	}
 
 	if request == nil {
-		return fmt.Errorf("null request")
+		return nil, fmt.Errorf("null request")
 	}
 
 	if token == nil {
-		return fmt.Errorf("null token")
+		return nil, fmt.Errorf("null token")
 	}
 
 	if !strings.EqualFold(token.Identity, identity) {
-		return fmt.Errorf("incorrect identity verified")
+		return nil, fmt.Errorf("incorrect identity verified")
 	}
 
 	if !slices.Equal(attributes.PermissionsByRole["admin"], token.Attributes.PermissionsByRole["admin"]) {
-		return fmt.Errorf("attribute mismatch")
+		return nil, fmt.Errorf("attribute mismatch")
 	}
 
-	recoveredAuthenticationKey, err := crypto.NewSecp256r1()
+	recoveredAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	recoveredNextAuthenticationKey, err := crypto.NewSecp256r1()
+	recoveredNextAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	nextRecoveryKey, err := crypto.NewSecp256r1()
+	nextRecoveryKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	recoveredAuthenticationPublicKey, err := recoveredAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	recoveredNextAuthenticationPublicKey, err := recoveredNextAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	nextRecoveryPublicKey, err := nextRecoveryKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rotationHash = hasher.Sum([]byte(recoveredNextAuthenticationPublicKey))
@@ -530,7 +582,7 @@ func testFlow() error {
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	recoverAccountRequest := messages.NewRecoverAccountRequest(
@@ -548,50 +600,52 @@ func testFlow() error {
 	)
 
 	if err := recoverAccountRequest.Sign(recoveryKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = recoverAccountRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reply, err = ba.RecoverAccount(ctx, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	recoverAccountResponse, err := messages.ParseRecoverAccountResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := recoverAccountResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, recoverAccountResponse.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce")
+		return nil, fmt.Errorf("bad nonce")
 	}
 
-	linkedAuthenticationKey, err := crypto.NewSecp256r1()
+	linkedAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	linkedNextAuthenticationKey, err := crypto.NewSecp256r1()
+	linkedNextAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	linkedAuthenticationPublicKey, err := linkedAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	linkedNextAuthenticationPublicKey, err := linkedNextAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rotationHash = hasher.Sum([]byte(linkedNextAuthenticationPublicKey))
@@ -599,7 +653,7 @@ func testFlow() error {
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	linkContainer := messages.NewLinkContainer(
@@ -615,17 +669,17 @@ func testFlow() error {
 	)
 
 	if err := linkContainer.Sign(linkedAuthenticationKey); err != nil {
-		return err
+		return nil, err
 	}
 
-	recoveredNextNextAuthenticationKey, err := crypto.NewSecp256r1()
+	recoveredNextNextAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	recoveredNextNextAuthenticationPublicKey, err := recoveredNextNextAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	recoveredNextRotationHash := hasher.Sum([]byte(recoveredNextNextAuthenticationPublicKey))
@@ -644,47 +698,49 @@ func testFlow() error {
 	)
 
 	if err := linkDeviceRequest.Sign(recoveredNextAuthenticationKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = linkDeviceRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reply, err = ba.LinkDevice(ctx, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	linkDeviceResponse, err := messages.ParseLinkDeviceResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := linkDeviceResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, linkDeviceResponse.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce")
+		return nil, fmt.Errorf("bad nonce")
 	}
 
-	linkedNextNextAuthenticationKey, err := crypto.NewSecp256r1()
+	linkedNextNextAuthenticationKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	linkedNextNextAuthenticationPublicKey, err := linkedNextNextAuthenticationKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	linkedNextRotationHash := hasher.Sum([]byte(linkedNextNextAuthenticationPublicKey))
 
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	deleteAccountRequest := messages.NewDeleteAccountRequest(
@@ -700,46 +756,48 @@ func testFlow() error {
 	)
 
 	if err := deleteAccountRequest.Sign(linkedNextAuthenticationKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = deleteAccountRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	reply, err = ba.DeleteAccount(ctx, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transcript = append(transcript, message, reply)
+
 	deleteAccountResponse, err := messages.ParseDeleteAccountResponse(reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := deleteAccountResponse.Verify(serverResponseKey.Verifier(), serverResponsePublicKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.EqualFold(nonce, deleteAccountResponse.Payload.Access.Nonce) {
-		return fmt.Errorf("bad nonce")
+		return nil, fmt.Errorf("bad nonce")
 	}
 
 	// Try to refresh session after account deletion - should fail
 	nonce, err = noncer.Generate128()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	nextAccessKey, err := crypto.NewSecp256r1()
+	nextAccessKey, err := crypto.NewSecp256r1WithEntropy(entropy)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	nextAccessPublicKey, err := nextAccessKey.Public()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rotationHash = hasher.Sum([]byte(nextAccessPublicKey))
@@ -756,22 +814,22 @@ func testFlow() error {
 	)
 
 	if err := refreshSessionRequest.Sign(clientNextNextAccessKey); err != nil {
-		return err
+		return nil, err
 	}
 
 	message, err = refreshSessionRequest.Serialize()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	_, err = ba.RefreshSession(ctx, message)
 	if err == nil {
-		return fmt.Errorf("expected refresh to fail for deleted account")
+		return nil, fmt.Errorf("expected refresh to fail for deleted account")
 	}
 
 	if !strings.Contains(err.Error(), "not found") {
-		return fmt.Errorf("expected 'not found' error, got: %v", err)
+		return nil, fmt.Errorf("expected 'not found' error, got: %v", err)
 	}
 
-	return nil
+	return transcript, nil
 }
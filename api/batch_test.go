@@ -0,0 +1,160 @@
+package api_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+func (h *testHarness) batchMessage(t *testing.T, session *testSession, payloads []FakeAccessRequestPayload) string {
+	t.Helper()
+
+	request := messages.NewBatchAccessRequest[FakeAccessRequestPayload, MockAttributes](
+		payloads,
+		h.timestamper,
+		session.token,
+		h.newNonce(t),
+	)
+
+	if err := request.Sign(session.currentKey); err != nil {
+		t.Fatalf("failed to sign batch request: %v", err)
+	}
+
+	message, err := request.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize batch request: %v", err)
+	}
+
+	return message
+}
+
+func TestBatchAccess(t *testing.T) {
+	h := newTestHarness(t)
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	message := h.batchMessage(t, session, []FakeAccessRequestPayload{
+		{Foo: "a", Bar: "b"},
+		{Foo: "c", Bar: "d"},
+	})
+
+	requests, token, nonce, err := h.av.VerifyBatch(h.ctx, message, &MockAttributes{})
+	if err != nil {
+		t.Fatalf("failed to verify batch: %v", err)
+	}
+
+	if token.Identity != account.identity {
+		t.Errorf("expected identity %s, got %s", account.identity, token.Identity)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	responses := make([]FakeAccessRequestPayload, len(requests))
+	for i, raw := range requests {
+		if err := json.Unmarshal(raw, &responses[i]); err != nil {
+			t.Fatalf("failed to decode request %d: %v", i, err)
+		}
+	}
+
+	if responses[0].Foo != "a" || responses[1].Foo != "c" {
+		t.Fatalf("unexpected requests: %+v", responses)
+	}
+
+	serverIdentity, err := h.serverResponseKey.Identity()
+	if err != nil {
+		t.Fatalf("failed to get server identity: %v", err)
+	}
+
+	response := messages.NewBatchResponse(responses, serverIdentity, nonce)
+	if err := response.Sign(h.serverResponseKey); err != nil {
+		t.Fatalf("failed to sign batch response: %v", err)
+	}
+
+	reply, err := response.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize batch response: %v", err)
+	}
+
+	parsed, err := messages.ParseBatchResponse[FakeAccessRequestPayload](reply)
+	if err != nil {
+		t.Fatalf("failed to parse batch response: %v", err)
+	}
+
+	if err := parsed.Verify(h.serverResponseKey.Verifier(), h.publicKey(t, h.serverResponseKey)); err != nil {
+		t.Fatalf("failed to verify batch response: %v", err)
+	}
+
+	if len(parsed.Payload.Response) != 2 || parsed.Payload.Access.Nonce != nonce {
+		t.Fatalf("unexpected batch response: %+v", parsed.Payload)
+	}
+
+	if _, _, _, err := h.av.VerifyBatch(h.ctx, message, &MockAttributes{}); err == nil {
+		t.Fatalf("expected replayed batch to be rejected")
+	}
+}
+
+func TestBatchAccessRejectsTampering(t *testing.T) {
+	h := newTestHarness(t)
+	session := h.createSession(t, h.createAccount(t), MockAttributes{})
+
+	message := h.batchMessage(t, session, []FakeAccessRequestPayload{
+		{Foo: "a", Bar: "b"},
+		{Foo: "c", Bar: "d"},
+	})
+
+	tampered := strings.Replace(message, `"foo":"c"`, `"foo":"x"`, 1)
+	if _, _, _, err := h.av.VerifyBatch(h.ctx, tampered, &MockAttributes{}); err == nil {
+		t.Fatalf("expected tampered batch to be rejected")
+	}
+}
+
+func TestEmptyBatch(t *testing.T) {
+	h := newTestHarness(t)
+	session := h.createSession(t, h.createAccount(t), MockAttributes{})
+
+	_, _, _, err := h.av.VerifyBatch(h.ctx, h.batchMessage(t, session, nil), &MockAttributes{})
+	expectErrorCode(t, err, "BA101")
+}
+
+func TestBatchSizeLimit(t *testing.T) {
+	h := newTestHarness(t)
+	session := h.createSession(t, h.createAccount(t), MockAttributes{})
+
+	payloads := make([]FakeAccessRequestPayload, 3)
+
+	_, _, _, err := h.av.With(api.LimitBatchSize(2)).VerifyBatch(h.ctx, h.batchMessage(t, session, payloads), &MockAttributes{})
+	expectErrorCode(t, err, "BA101")
+
+	if _, _, _, err := h.av.With(api.LimitBatchSize(3)).VerifyBatch(h.ctx, h.batchMessage(t, session, payloads), &MockAttributes{}); err != nil {
+		t.Fatalf("failed to verify batch within the limit: %v", err)
+	}
+
+	_, _, _, err = h.av.VerifyBatch(h.ctx, h.batchMessage(t, session, make([]FakeAccessRequestPayload, api.DefaultMaximumBatchSize+1)), &MockAttributes{})
+	expectErrorCode(t, err, "BA101")
+}
+
+func TestBatchSignatureIsNotASingleRequest(t *testing.T) {
+	h := newTestHarness(t)
+	session := h.createSession(t, h.createAccount(t), MockAttributes{})
+
+	payloads := []FakeAccessRequestPayload{{Foo: "a", Bar: "b"}}
+
+	_, _, _, err := h.av.Verify(h.ctx, h.batchMessage(t, session, payloads), &MockAttributes{})
+	expectErrorCode(t, err, "BA101")
+
+	// a single request whose payload happens to be an array is not a batch either
+	request := messages.NewAccessRequest[[]FakeAccessRequestPayload, MockAttributes, messages.AccessRequest[[]FakeAccessRequestPayload, MockAttributes]](
+		payloads,
+		h.timestamper,
+		session.token,
+		h.newNonce(t),
+	)
+
+	_, _, _, err = h.av.VerifyBatch(h.ctx, h.encodeRequest(t, request, session.currentKey), &MockAttributes{})
+	expectErrorCode(t, err, "BA101")
+}
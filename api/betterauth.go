@@ -1,10 +1,16 @@
 package api
 
 import (
+	"context"
+	stderrors "errors"
+	"slices"
 	"time"
 
 	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/eventinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
 	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
 )
 
@@ -13,6 +19,33 @@ type BetterAuthServer[AttributesType any] struct {
 	encoding *EncodingContainer
 	expiry   *ExpiryContainer
 	store    *StoresContainer
+	options  betterAuthServerOptions
+}
+
+type betterAuthServerOptions struct {
+	canonical bool
+	events    eventinterfaces.SecurityEventEmitter
+	skew      time.Duration
+	tenant    string
+	versions  []string
+}
+
+type BetterAuthServerOption func(*betterAuthServerOptions)
+
+func WithSecurityEventEmitter(emitter eventinterfaces.SecurityEventEmitter) BetterAuthServerOption {
+	return func(o *betterAuthServerOptions) {
+		o.events = emitter
+	}
+}
+
+// WithClockSkew honours refresh tokens for up to past after their refresh or session expiry, for
+// replicas whose clocks drift apart. The server only checks timestamps it issued itself, none of
+// which can be ahead of it, so there is no forward allowance here; see AllowClockSkew. The access
+// key hash store must retain reservations for the refresh window plus past.
+func WithClockSkew(past time.Duration) BetterAuthServerOption {
+	return func(o *betterAuthServerOptions) {
+		o.skew = past
+	}
 }
 
 type CryptoContainer struct {
@@ -22,9 +55,13 @@ type CryptoContainer struct {
 	Verifier cryptointerfaces.Verifier
 }
 
+// ExpiryContainer configures token lifetimes. Refresh is an idle timeout: each refresh must happen
+// within Refresh of the previous one. Session is the absolute lifetime of a session regardless of
+// activity, and defaults to Refresh (a fixed refresh deadline) when zero.
 type ExpiryContainer struct {
 	Access  time.Duration
 	Refresh time.Duration
+	Session time.Duration
 }
 
 type KeyPairContainer struct {
@@ -32,21 +69,29 @@ type KeyPairContainer struct {
 	Response cryptointerfaces.SigningKey
 }
 
+// EncodingContainer configures message and token encodings. MessageCodec is optional; when set,
+// requests are decoded and responses encoded with it, and everything is signed canonically, or
+// over the encoded bytes when the codec or token encoder is an encodinginterfaces.SignedEncoding.
 type EncodingContainer struct {
 	IdentityVerifier encodinginterfaces.IdentityVerifier
 	Timestamper      encodinginterfaces.Timestamper
 	TokenEncoder     encodinginterfaces.TokenEncoder
+	MessageCodec     encodinginterfaces.MessageCodec
 }
 
 type StoresContainer struct {
 	Access         *AccessStoreContainer
 	Authentication *AuthenticationStoreContainer
 	Recovery       *RecoveryStoreContainer
+	// Session is optional, and without it sessions cannot be listed or terminated.
+	Session *SessionStoreContainer
 }
 
 type AccessStoreContainer struct {
 	VerificationKey storageinterfaces.VerificationKeyStore
 	KeyHash         storageinterfaces.TimeLockStore
+	// Revocation is optional, and without it reused refresh chains are reported but not revoked.
+	Revocation storageinterfaces.RevocationStore
 }
 
 type AuthenticationStoreContainer struct {
@@ -58,16 +103,138 @@ type RecoveryStoreContainer struct {
 	Hash storageinterfaces.RecoveryHashStore
 }
 
+type SessionStoreContainer struct {
+	Registry storageinterfaces.SessionStore
+}
+
 func NewBetterAuthServer[AttributesType any](
 	crypto *CryptoContainer,
 	encoding *EncodingContainer,
 	expiry *ExpiryContainer,
 	store *StoresContainer,
+	options ...BetterAuthServerOption,
 ) *BetterAuthServer[AttributesType] {
-	return &BetterAuthServer[AttributesType]{
+	ba := &BetterAuthServer[AttributesType]{
 		crypto:   crypto,
 		encoding: encoding,
 		expiry:   expiry,
 		store:    store,
 	}
+
+	for _, option := range options {
+		option(&ba.options)
+	}
+
+	if len(ba.options.versions) == 0 {
+		ba.options.versions = []string{messages.DefaultProtocolVersion}
+	}
+
+	return ba
+}
+
+// WithCanonicalSigning signs responses and access tokens over the RFC 8785 (JCS) canonical form of
+// their payloads, for clients that verify by re-canonicalizing rather than over the received bytes.
+func WithCanonicalSigning() BetterAuthServerOption {
+	return func(o *betterAuthServerOptions) {
+		o.canonical = true
+	}
+}
+
+// ForTenant stamps issued tokens with tenant, and refuses to refresh tokens issued for another tenant.
+func ForTenant(tenant string) BetterAuthServerOption {
+	return func(o *betterAuthServerOptions) {
+		o.tenant = tenant
+	}
+}
+
+// WithProtocolVersions sets the protocol versions the server accepts. Responses are always sent in
+// the version of the request. Defaults to messages.DefaultProtocolVersion.
+func WithProtocolVersions(versions ...string) BetterAuthServerOption {
+	return func(o *betterAuthServerOptions) {
+		o.versions = versions
+	}
+}
+
+func (ba *BetterAuthServer[AttributesType]) acceptVersion(access *messages.ClientAccess) error {
+	version := access.ProtocolVersion()
+	if !slices.Contains(ba.options.versions, version) {
+		return errors.NewUnsupportedVersionError(version, ba.options.versions)
+	}
+
+	return nil
+}
+
+// rotateKey moves the device to publicKey, which must hash to the rotation hash committed to when the
+// current key was registered.
+func (ba *BetterAuthServer[AttributesType]) rotateKey(ctx context.Context, identity, device, publicKey, rotationHash string) error {
+	previousRotationHash := ba.crypto.Hasher.Sum([]byte(publicKey))
+
+	err := ba.store.Authentication.Key.Rotate(ctx, identity, device, previousRotationHash, publicKey, rotationHash)
+	if stderrors.Is(err, storageinterfaces.ErrRotationConflict) {
+		return errors.NewRotationConflictError(identity, device)
+	}
+
+	return err
+}
+
+type signable interface {
+	Sign(signer cryptointerfaces.SigningKey) error
+	SignCanonical(signer cryptointerfaces.SigningKey) error
+}
+
+func (ba *BetterAuthServer[AttributesType]) sign(message signable, signer cryptointerfaces.SigningKey) error {
+	return ba.signEncoded(message, messages.EncodedSigningKey(signer, ba.encoding.MessageCodec))
+}
+
+func (ba *BetterAuthServer[AttributesType]) signToken(token signable, signer cryptointerfaces.SigningKey) error {
+	return ba.signEncoded(token, messages.EncodedSigningKey(signer, ba.encoding.TokenEncoder))
+}
+
+func (ba *BetterAuthServer[AttributesType]) signEncoded(message signable, signer cryptointerfaces.SigningKey) error {
+	if ba.options.canonical || ba.encoding.MessageCodec != nil {
+		return message.SignCanonical(signer)
+	}
+
+	return message.Sign(signer)
+}
+
+// verifier verifies requests over their wire encoding, if it is signed.
+func (ba *BetterAuthServer[AttributesType]) verifier() cryptointerfaces.Verifier {
+	return messages.EncodedVerifier(ba.crypto.Verifier, ba.encoding.MessageCodec)
+}
+
+func (ba *BetterAuthServer[AttributesType]) decode(message string) (string, error) {
+	return decodeMessage(ba.encoding.MessageCodec, message)
+}
+
+func (ba *BetterAuthServer[AttributesType]) serialize(message messages.Serializable) (string, error) {
+	serialized, err := message.Serialize()
+	if err != nil {
+		return "", err
+	}
+
+	if ba.encoding.MessageCodec == nil {
+		return serialized, nil
+	}
+
+	return ba.encoding.MessageCodec.Encode(serialized)
+}
+
+func decodeMessage(codec encodinginterfaces.MessageCodec, message string) (string, error) {
+	if codec == nil {
+		return message, nil
+	}
+
+	decoded, err := codec.Decode(message)
+	if err != nil {
+		return "", errors.NewInvalidMessageError("message", err.Error())
+	}
+
+	return decoded, nil
+}
+
+func (ba *BetterAuthServer[AttributesType]) emit(ctx context.Context, event eventinterfaces.SecurityEvent) {
+	if ba.options.events != nil {
+		ba.options.events.Emit(ctx, event)
+	}
 }
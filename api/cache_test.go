@@ -0,0 +1,254 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+)
+
+// countingKeyStore counts key fetches, which happen on every request, and uses of the fetched key's
+// verifier, which only happen when a token is verified from scratch.
+type countingKeyStore struct {
+	*storage.VerificationKeyStore
+	gets          int
+	verifications int
+}
+
+func (s *countingKeyStore) Get(ctx context.Context, identity string) (cryptointerfaces.VerificationKey, error) {
+	s.gets++
+
+	key, err := s.VerificationKeyStore.Get(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingKey{VerificationKey: key, store: s}, nil
+}
+
+type countingKey struct {
+	cryptointerfaces.VerificationKey
+	store *countingKeyStore
+}
+
+func (k *countingKey) Verifier() cryptointerfaces.Verifier {
+	k.store.verifications++
+	return k.VerificationKey.Verifier()
+}
+
+func newCachingVerifier(t *testing.T, h *testHarness) (*api.AccessVerifier[MockAttributes], *countingKeyStore, *storage.InMemoryTokenCache) {
+	t.Helper()
+
+	accessIdentity, err := h.serverAccessKey.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive access identity: %v", err)
+	}
+
+	keyStore := &countingKeyStore{VerificationKeyStore: storage.NewVerificationKeyStore()}
+	keyStore.Add(accessIdentity, h.serverAccessKey)
+
+	cache := storage.NewInMemoryTokenCacheWithClock(16, h.clock)
+
+	av := api.NewAccessVerifier[MockAttributes](
+		&api.VerifierCryptoContainer{
+			Verifier: crypto.NewSecp256r1Verifier(),
+		},
+		&api.VerifierEncodingContainer{
+			TokenEncoder: h.tokenEncoder,
+			Timestamper:  h.timestamper,
+		},
+		&api.VerifierStoreContainer{
+			AccessNonce: storage.NewInMemoryTimeLockStoreWithClock(30*time.Second, h.clock),
+			AccessKey:   keyStore,
+		},
+		api.CacheTokens(cache),
+	)
+
+	return av, keyStore, cache
+}
+
+func TestTokenCacheSkipsTokenVerification(t *testing.T) {
+	h := newTestHarness(t)
+	av, keyStore, cache := newCachingVerifier(t, h)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	for range 3 {
+		if _, err := h.access(t, av, session); err != nil {
+			t.Fatalf("failed to access: %v", err)
+		}
+	}
+
+	if keyStore.verifications != 1 {
+		t.Errorf("expected the token to be verified once, got %d verifications", keyStore.verifications)
+	}
+
+	if keyStore.gets != 3 {
+		t.Errorf("expected the key to be checked on every request, got %d key fetches", keyStore.gets)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// the request signature is still checked on a hit
+	forged := &testSession{token: session.token, currentKey: session.nextKey}
+	if _, err := h.access(t, av, forged); err == nil {
+		t.Error("expected a request signed with the wrong key to be rejected")
+	}
+
+	// and so is the nonce
+	message := h.accessMessage(t, session)
+	if _, _, _, err := av.Verify(h.ctx, message, &MockAttributes{}); err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	if _, _, _, err := av.Verify(h.ctx, message, &MockAttributes{}); err == nil {
+		t.Error("expected a replayed request to be rejected")
+	}
+
+	// and the token's lifetime: a verifier tolerating less skew rejects a token the cache still holds
+	h.clock.Advance(15 * time.Minute)
+
+	_, err := h.access(t, av.With(api.AllowClockSkew(0, -time.Second)), session)
+	expectErrorCode(t, err, "BA401")
+
+	if keyStore.verifications != 1 {
+		t.Errorf("expected no further verifications, got %d", keyStore.verifications)
+	}
+
+	h.clock.Advance(time.Second)
+
+	_, err = h.access(t, av, session)
+	expectErrorCode(t, err, "BA401")
+
+	if stats := cache.Stats(); stats.Expirations != 1 {
+		t.Errorf("expected the expired token to be dropped, got %+v", stats)
+	}
+}
+
+func TestTokenCacheDropsRevokedSessions(t *testing.T) {
+	h := newTestHarness(t)
+	cache := storage.NewInMemoryTokenCacheWithClock(16, h.clock)
+	av := h.av.With(api.CacheTokens(cache))
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	if _, err := h.access(t, av, session); err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	message, _ := h.refreshRequest(t, session)
+	if _, err := h.ba.RefreshSession(h.ctx, message); err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+
+	_, err := h.ba.RefreshSession(h.ctx, message)
+	expectErrorReason(t, err, "BA401", "reused")
+
+	_, err = h.access(t, av, session)
+	expectErrorReason(t, err, "BA401", "revoked")
+
+	stats := cache.Stats()
+	if stats.Invalidations != 1 || stats.Size != 0 {
+		t.Errorf("expected the revoked session to be dropped, got %+v", stats)
+	}
+}
+
+func TestTokenCacheKeyRetirement(t *testing.T) {
+	h := newTestHarness(t)
+	av, keyStore, cache := newCachingVerifier(t, h)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	if _, err := h.access(t, av, session); err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	accessIdentity, err := h.serverAccessKey.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive access identity: %v", err)
+	}
+
+	keyStore.Remove(accessIdentity)
+	cache.InvalidateServerIdentity(accessIdentity)
+
+	if _, err := h.access(t, av, session); err == nil {
+		t.Error("expected a token signed by a retired key to be rejected")
+	}
+
+	if keyStore.gets != 2 {
+		t.Errorf("expected the token to be verified again, got %d key fetches", keyStore.gets)
+	}
+}
+
+func TestTokenCacheChecksKeyStore(t *testing.T) {
+	h := newTestHarness(t)
+	av, keyStore, cache := newCachingVerifier(t, h)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	if _, err := h.access(t, av, session); err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	accessIdentity, err := h.serverAccessKey.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive access identity: %v", err)
+	}
+
+	// the key is retired without telling the cache
+	keyStore.Remove(accessIdentity)
+
+	if _, err := h.access(t, av, session); err == nil {
+		t.Error("expected a cached token signed by a retired key to be rejected")
+	}
+
+	if stats := cache.Stats(); stats.Size != 0 {
+		t.Errorf("expected the retired key's tokens to be dropped, got %+v", stats)
+	}
+}
+
+func TestTokenCacheHandsOutCopies(t *testing.T) {
+	h := newTestHarness(t)
+	av, _, _ := newCachingVerifier(t, h)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{
+		PermissionsByRole: map[string][]string{"user": {"read"}},
+	})
+
+	// the first use caches the token, and later ones are hits
+	for range 2 {
+		token, err := h.access(t, av, session)
+		if err != nil {
+			t.Fatalf("failed to access: %v", err)
+		}
+
+		if permissions := token.Attributes.PermissionsByRole["user"]; len(permissions) != 1 || permissions[0] != "read" {
+			t.Fatalf("expected the issued attributes, got %v", token.Attributes.PermissionsByRole)
+		}
+
+		token.Attributes.PermissionsByRole["user"][0] = "write"
+		token.Attributes.PermissionsByRole["admin"] = []string{"write"}
+		token.Identity = "mallory"
+	}
+
+	token, err := h.access(t, av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	if token.Identity != account.identity {
+		t.Errorf("expected identity %s, got %s", account.identity, token.Identity)
+	}
+}
@@ -0,0 +1,127 @@
+package api_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+func TestUnknownFieldsPreservedForVerification(t *testing.T) {
+	h := newTestHarness(t)
+
+	currentKey, currentPublicKey := h.newKey(t)
+	_, nextPublicKey := h.newKey(t)
+	_, recoveryPublicKey := h.newKey(t)
+
+	rotationHash := h.hasher.Sum([]byte(nextPublicKey))
+	recoveryHash := h.hasher.Sum([]byte(recoveryPublicKey))
+	device := h.hasher.Sum([]byte(currentPublicKey + rotationHash))
+	identity := h.hasher.Sum([]byte(currentPublicKey + rotationHash + recoveryHash))
+
+	// a newer client adds fields this server doesn't know about, and doesn't escape html
+	payload := fmt.Sprintf(
+		`{"access":{"nonce":"%s","hint":"<new>"},"request":{"authentication":{"device":"%s","identity":"%s","publicKey":"%s","recoveryHash":"%s","rotationHash":"%s","futureField":{"b":1,"a":[true]}}}}`,
+		h.newNonce(t),
+		device,
+		identity,
+		currentPublicKey,
+		recoveryHash,
+		rotationHash,
+	)
+
+	signature, err := currentKey.Sign([]byte(payload))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	message := fmt.Sprintf(`{"payload":%s,"signature":"%s"}`, payload, signature)
+
+	request, err := messages.ParseCreateAccountRequest(message)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	serialized, err := request.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+
+	if serialized != message {
+		t.Errorf("expected the parsed message to serialize verbatim")
+	}
+
+	if _, err := h.ba.CreateAccount(h.ctx, message); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+}
+
+func TestCanonicalSigning(t *testing.T) {
+	h := newTestHarness(t, api.WithCanonicalSigning())
+
+	account := h.createAccount(t)
+
+	request := messages.NewRequestSessionRequest(
+		messages.RequestSessionRequestPayload{
+			Authentication: messages.RequestSessionRequestAuthentication{
+				Identity: account.identity,
+			},
+		},
+		h.newNonce(t),
+	)
+
+	message, err := request.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+
+	reply, err := h.ba.RequestSession(h.ctx, message)
+	if err != nil {
+		t.Fatalf("failed to request session: %v", err)
+	}
+
+	response, err := messages.ParseRequestSessionResponse(reply)
+	if err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	responsePublicKey := h.publicKey(t, h.serverResponseKey)
+	if err := response.Verify(h.serverResponseKey.Verifier(), responsePublicKey); err != nil {
+		t.Fatalf("failed to verify response: %v", err)
+	}
+
+	canonical, err := response.ComposeCanonicalPayload()
+	if err != nil {
+		t.Fatalf("failed to canonicalize: %v", err)
+	}
+
+	if err := h.serverResponseKey.Verifier().Verify(*response.Signature, responsePublicKey, []byte(canonical)); err != nil {
+		t.Fatalf("expected the signature to cover the canonical payload: %v", err)
+	}
+
+	session := h.createSession(t, account, MockAttributes{PermissionsByRole: map[string][]string{"admin": {"read"}}})
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	composed, err := token.ComposePayload()
+	if err != nil {
+		t.Fatalf("failed to compose token: %v", err)
+	}
+
+	canonical, err = token.ComposeCanonicalPayload()
+	if err != nil {
+		t.Fatalf("failed to canonicalize token: %v", err)
+	}
+
+	if composed == canonical {
+		t.Fatalf("expected struct order and canonical order to differ for this test to be meaningful")
+	}
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh canonically signed session: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+func TestCBOREncoding(t *testing.T) {
+	// RFC 8949 appendix A, restricted to the JSON data model
+	vectors := []struct {
+		json    string
+		encoded string
+	}{
+		{`0`, "00"},
+		{`23`, "17"},
+		{`24`, "1818"},
+		{`1000000`, "1a000f4240"},
+		{`-1000`, "3903e7"},
+		{`1.5`, "f93e00"},
+		{`-4.1`, "fbc010666666666666"},
+		{`1.1`, "fb3ff199999999999a"},
+		{`0.00006103515625`, "f90400"},
+		{`5.960464477539063e-8`, "f90001"},
+		{`3.4028234663852886e+38`, "fa7f7fffff"},
+		{`100000.0`, "1a000186a0"},
+		{`false`, "f4"},
+		{`null`, "f6"},
+		{`"ü"`, "62c3bc"},
+		{`[1,[2,3],[4,5]]`, "8301820203820405"},
+		{`{"b":[2,3],"a":1}`, "a26161016162820203"},
+		{`{"aa":1,"b":2}`, "a261620262616101"},
+	}
+
+	codec := encoding.NewCBOR()
+
+	for _, vector := range vectors {
+		encoded, err := codec.Encode(vector.json)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", vector.json, err)
+		}
+
+		if hex.EncodeToString([]byte(encoded)) != vector.encoded {
+			t.Errorf("expected %s to encode as %s, got %x", vector.json, vector.encoded, encoded)
+		}
+
+		decoded, err := codec.Decode(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", vector.encoded, err)
+		}
+
+		canonical, err := messages.Canonicalize([]byte(vector.json))
+		if err != nil {
+			t.Fatalf("failed to canonicalize %s: %v", vector.json, err)
+		}
+
+		if decoded != string(canonical) {
+			t.Errorf("expected %s to decode as %s, got %s", vector.encoded, canonical, decoded)
+		}
+	}
+}
+
+func TestCBORRejectsNonDeterministicEncoding(t *testing.T) {
+	codec := encoding.NewCBOR()
+
+	for name, encoded := range map[string]string{
+		"long head":         "1817",
+		"indefinite array":  "9f01ff",
+		"unsorted keys":     "a2616201616101",
+		"duplicate keys":    "a2616101616102",
+		"integral float":    "f93c00",
+		"wide float":        "fa3fc00000",
+		"byte string":       "4101",
+		"tag":               "c11a514b67b0",
+		"undefined":         "f7",
+		"non-text key":      "a10101",
+		"trailing data":     "0000",
+		"truncated":         "62c3",
+		"unsafe integer":    "1b0020000000000001",
+		"invalid utf-8":     "61ff",
+		"oversized array":   "9bffffffffffffffff",
+		"non-finite number": "f97c00",
+	} {
+		data, err := hex.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("bad test vector %s: %v", name, err)
+		}
+
+		if _, err := codec.Decode(string(data)); err == nil {
+			t.Errorf("expected %s (%s) to be rejected", name, encoded)
+		}
+	}
+}
+
+func TestCBORFlow(t *testing.T) {
+	h := newCBORTestHarness(t)
+	session := h.createSession(t, h.createAccount(t), MockAttributes{
+		PermissionsByRole: map[string][]string{"admin": {"read", "write"}},
+	})
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	request := NewFakeAccessRequest(
+		FakeAccessRequestPayload{Foo: "bar", Bar: "foo"},
+		h.timestamper,
+		session.token,
+		h.newNonce(t),
+	)
+
+	requestJson, token, _, err := h.av.Verify(h.ctx, h.encodeRequest(t, request, session.currentKey), &MockAttributes{})
+	if err != nil {
+		t.Fatalf("failed to verify access: %v", err)
+	}
+
+	if string(requestJson) != `{"bar":"foo","foo":"bar"}` {
+		t.Errorf("unexpected request: %s", requestJson)
+	}
+
+	if len(token.Attributes.PermissionsByRole["admin"]) != 2 {
+		t.Errorf("unexpected attributes: %+v", token.Attributes)
+	}
+}
+
+func TestCBORSignaturesCoverEncoding(t *testing.T) {
+	h := newCBORTestHarness(t)
+	session := h.createSession(t, h.createAccount(t), MockAttributes{})
+	codec := encoding.NewCBOR()
+
+	request := NewFakeAccessRequest(
+		FakeAccessRequestPayload{Foo: "bar", Bar: "foo"},
+		h.timestamper,
+		session.token,
+		h.newNonce(t),
+	)
+
+	message := h.encodeRequest(t, request, session.currentKey)
+
+	payload, err := request.ComposeCanonicalPayload()
+	if err != nil {
+		t.Fatalf("failed to compose payload: %v", err)
+	}
+
+	encodedPayload, err := codec.EncodePayload([]byte(payload))
+	if err != nil {
+		t.Fatalf("failed to encode payload: %v", err)
+	}
+
+	// the signed bytes are carried verbatim, so a client needs no json to check them
+	if !strings.Contains(message, string(encodedPayload)) {
+		t.Fatalf("expected the message to contain its encoded payload")
+	}
+
+	clientVerifier := session.currentKey.Verifier()
+	clientPublicKey := h.publicKey(t, session.currentKey)
+
+	if err := clientVerifier.Verify(*request.Signature, clientPublicKey, encodedPayload); err != nil {
+		t.Errorf("expected the signature to cover the cbor payload: %v", err)
+	}
+
+	if err := clientVerifier.Verify(*request.Signature, clientPublicKey, []byte(payload)); err == nil {
+		t.Errorf("expected the signature not to cover the json payload")
+	}
+
+	if _, _, _, err := h.av.Verify(h.ctx, message, &MockAttributes{}); err != nil {
+		t.Fatalf("failed to verify request: %v", err)
+	}
+
+	length, err := h.tokenEncoder.SignatureLength(session.token)
+	if err != nil {
+		t.Fatalf("failed to find token signature: %v", err)
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(session.token[length:])
+	if err != nil {
+		t.Fatalf("failed to decode token body: %v", err)
+	}
+
+	accessPublicKey := h.publicKey(t, h.serverAccessKey)
+	if err := h.serverAccessKey.Verifier().Verify(session.token[:length], accessPublicKey, body); err != nil {
+		t.Errorf("expected the token signature to cover the cbor body: %v", err)
+	}
+}
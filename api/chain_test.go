@@ -0,0 +1,150 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/pkg/eventinterfaces"
+)
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+	bystander := h.createSession(t, account, MockAttributes{})
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	// the attacker and the client both present the same link
+	stolen, _ := h.refreshRequest(t, session)
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	if _, err := h.access(t, h.av, session); err != nil {
+		t.Fatalf("failed to access with refreshed token: %v", err)
+	}
+
+	_, err = h.ba.RefreshSession(h.ctx, stolen)
+	expectErrorReason(t, err, "BA401", "reused")
+
+	recorded := h.events.recorded()
+	if len(recorded) != 1 {
+		t.Fatalf("expected 1 security event, got %d", len(recorded))
+	}
+
+	event := recorded[0]
+	if event.Kind != eventinterfaces.RefreshReuseDetected {
+		t.Errorf("expected %s event, got %s", eventinterfaces.RefreshReuseDetected, event.Kind)
+	}
+
+	if event.Session != token.Session || event.Identity != account.identity || event.Device != account.device {
+		t.Errorf("unexpected event subject %+v", event)
+	}
+
+	if event.Details["chain"] != token.Chain {
+		t.Errorf("expected chain %s in event details, got %v", token.Chain, event.Details["chain"])
+	}
+
+	// the winner's chain is dead too
+	err = h.refreshSession(t, session)
+	expectErrorReason(t, err, "BA401", "revoked")
+
+	_, err = h.access(t, h.av, session)
+	expectErrorReason(t, err, "BA401", "revoked")
+
+	sessions, err := h.ba.ListSessions(h.ctx, account.identity)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+
+	if len(sessions) != 1 || sessions[0].Id == token.Session {
+		t.Fatalf("expected only the bystander session to remain, got %+v", sessions)
+	}
+
+	if err := h.refreshSession(t, bystander); err != nil {
+		t.Fatalf("expected other sessions to be unaffected: %v", err)
+	}
+}
+
+func TestTerminatedSessionRejectedByVerifier(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	if err := h.ba.TerminateSession(h.ctx, account.identity, token.Session); err != nil {
+		t.Fatalf("failed to terminate session: %v", err)
+	}
+
+	_, err = h.access(t, h.av, session)
+	expectErrorReason(t, err, "BA401", "revoked")
+}
+
+func TestRefreshCarriesChain(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	if token.Chain == "" || token.Chain == token.Session {
+		t.Fatalf("expected a chain distinct from the session, got %q", token.Chain)
+	}
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	refreshed, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access with refreshed token: %v", err)
+	}
+
+	if refreshed.Chain != token.Chain || refreshed.Session != token.Session {
+		t.Errorf("expected chain %s and session %s, got %s and %s", token.Chain, token.Session, refreshed.Chain, refreshed.Session)
+	}
+}
+
+func TestSessionsWithoutRegistryOrRevocation(t *testing.T) {
+	h := newUntrackedTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	stolen, _ := h.refreshRequest(t, session)
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	if _, err := h.access(t, h.av, session); err != nil {
+		t.Fatalf("failed to access with refreshed token: %v", err)
+	}
+
+	_, err := h.ba.RefreshSession(h.ctx, stolen)
+	expectErrorReason(t, err, "BA401", "reused")
+
+	if _, err := h.ba.ListSessions(h.ctx, account.identity); !errors.Is(err, api.ErrNoSessionRegistry) {
+		t.Errorf("expected ErrNoSessionRegistry, got %v", err)
+	}
+
+	if err := h.ba.TerminateSession(h.ctx, account.identity, "session"); !errors.Is(err, api.ErrNoSessionRegistry) {
+		t.Errorf("expected ErrNoSessionRegistry, got %v", err)
+	}
+}
@@ -2,28 +2,36 @@ package api
 
 import (
 	"context"
+	stderrors "errors"
 
 	"github.com/jasoncolburne/better-auth-go/pkg/errors"
 	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
 )
 
 func (ba *BetterAuthServer[AttributesType]) LinkDevice(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseLinkDeviceRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Authentication.PublicKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
 		return "", err
 	}
 
-	linkContainer := messages.NewLinkContainer(
-		request.Payload.Request.Link.Payload,
-		request.Payload.Request.Link.Signature,
-	)
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Authentication.PublicKey); err != nil {
+		return "", err
+	}
+
+	linkContainer := &request.Payload.Request.Link
 
 	if err := linkContainer.Verify(
-		ba.crypto.Verifier,
+		ba.verifier(),
 		linkContainer.Payload.Authentication.PublicKey,
 	); err != nil {
 		return "", err
@@ -41,22 +49,27 @@ func (ba *BetterAuthServer[AttributesType]) LinkDevice(ctx context.Context, mess
 		return "", errors.NewInvalidDeviceError(linkContainer.Payload.Authentication.Device, device)
 	}
 
-	ba.store.Authentication.Key.Rotate(
+	// the new device is registered only together with the rotation that authorizes it
+	previousRotationHash := ba.crypto.Hasher.Sum([]byte(request.Payload.Request.Authentication.PublicKey))
+
+	err = ba.store.Authentication.Key.Link(
 		ctx,
 		request.Payload.Request.Authentication.Identity,
 		request.Payload.Request.Authentication.Device,
+		previousRotationHash,
 		request.Payload.Request.Authentication.PublicKey,
 		request.Payload.Request.Authentication.RotationHash,
-	)
-
-	if err := ba.store.Authentication.Key.Register(
-		ctx,
-		linkContainer.Payload.Authentication.Identity,
 		linkContainer.Payload.Authentication.Device,
 		linkContainer.Payload.Authentication.PublicKey,
 		linkContainer.Payload.Authentication.RotationHash,
-		true,
-	); err != nil {
+	)
+	if stderrors.Is(err, storageinterfaces.ErrRotationConflict) {
+		return "", errors.NewRotationConflictError(
+			request.Payload.Request.Authentication.Identity,
+			request.Payload.Request.Authentication.Device,
+		)
+	}
+	if err != nil {
 		return "", err
 	}
 
@@ -70,12 +83,13 @@ func (ba *BetterAuthServer[AttributesType]) LinkDevice(ctx context.Context, mess
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -84,16 +98,25 @@ func (ba *BetterAuthServer[AttributesType]) LinkDevice(ctx context.Context, mess
 }
 
 func (ba *BetterAuthServer[AttributesType]) UnlinkDevice(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseUnlinkDeviceRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Authentication.PublicKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Authentication.PublicKey); err != nil {
 		return "", err
 	}
 
-	if err := ba.store.Authentication.Key.Rotate(
+	if err := ba.rotateKey(
 		ctx,
 		request.Payload.Request.Authentication.Identity,
 		request.Payload.Request.Authentication.Device,
@@ -121,12 +144,13 @@ func (ba *BetterAuthServer[AttributesType]) UnlinkDevice(ctx context.Context, me
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -135,16 +159,25 @@ func (ba *BetterAuthServer[AttributesType]) UnlinkDevice(ctx context.Context, me
 }
 
 func (ba *BetterAuthServer[AttributesType]) RotateDevice(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseRotateDeviceRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Authentication.PublicKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Authentication.PublicKey); err != nil {
 		return "", err
 	}
 
-	if err := ba.store.Authentication.Key.Rotate(
+	if err := ba.rotateKey(
 		ctx,
 		request.Payload.Request.Authentication.Identity,
 		request.Payload.Request.Authentication.Device,
@@ -164,12 +197,13 @@ func (ba *BetterAuthServer[AttributesType]) RotateDevice(ctx context.Context, me
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
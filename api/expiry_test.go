@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+func (h *testHarness) parseTimes(t *testing.T, token *messages.AccessToken[MockAttributes]) (issuedAt, expiry, refreshExpiry, sessionExpiry time.Time) {
+	t.Helper()
+
+	parse := func(when string) time.Time {
+		parsed, err := h.timestamper.Parse(when)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", when, err)
+		}
+
+		return parsed
+	}
+
+	return parse(token.IssuedAt), parse(token.Expiry), parse(token.RefreshExpiry), parse(token.SessionExpiry)
+}
+
+func TestSlidingRefreshWindow(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{}, api.WithRefreshWindow(time.Hour), api.WithSessionLifetime(3*time.Hour))
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	issuedAt, expiry, refreshExpiry, sessionExpiry := h.parseTimes(t, token)
+	if refreshExpiry.Sub(issuedAt) != time.Hour {
+		t.Errorf("expected a one hour refresh window, got %v", refreshExpiry.Sub(issuedAt))
+	}
+
+	if sessionExpiry.Sub(issuedAt) != 3*time.Hour {
+		t.Errorf("expected a three hour session, got %v", sessionExpiry.Sub(issuedAt))
+	}
+
+	if expiry.Sub(issuedAt) != 15*time.Minute {
+		t.Errorf("expected a fifteen minute access token, got %v", expiry.Sub(issuedAt))
+	}
+
+	h.clock.Advance(10 * time.Minute)
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	refreshed, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	refreshedIssuedAt, _, refreshedRefreshExpiry, refreshedSessionExpiry := h.parseTimes(t, refreshed)
+	if !refreshedIssuedAt.After(issuedAt) {
+		t.Fatalf("expected refreshed token to be issued later")
+	}
+
+	if refreshedRefreshExpiry.Sub(refreshedIssuedAt) != time.Hour {
+		t.Errorf("expected the refresh window to slide, got %v", refreshedRefreshExpiry.Sub(refreshedIssuedAt))
+	}
+
+	if !refreshedSessionExpiry.Equal(sessionExpiry) {
+		t.Errorf("expected the session expiry to be preserved")
+	}
+}
+
+func TestRefreshWindowClampedBySessionLifetime(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{}, api.WithSessionLifetime(time.Minute))
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	_, expiry, refreshExpiry, sessionExpiry := h.parseTimes(t, token)
+	if !expiry.Equal(sessionExpiry) || !refreshExpiry.Equal(sessionExpiry) {
+		t.Errorf("expected access and refresh expiries to be clamped to %s, got %s and %s", token.SessionExpiry, token.Expiry, token.RefreshExpiry)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{}, api.WithRefreshWindow(time.Hour))
+
+	h.clock.Advance(2 * time.Hour)
+
+	err := h.refreshSession(t, session)
+	expectErrorCode(t, err, "BA401")
+}
+
+func TestAbsoluteSessionLifetime(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{}, api.WithRefreshWindow(time.Hour), api.WithSessionLifetime(3*time.Hour))
+
+	for range 3 {
+		h.clock.Advance(50 * time.Minute)
+
+		if err := h.refreshSession(t, session); err != nil {
+			t.Fatalf("failed to refresh session: %v", err)
+		}
+	}
+
+	h.clock.Advance(50 * time.Minute)
+
+	err := h.refreshSession(t, session)
+	expectErrorCode(t, err, "BA401")
+}
+
+func TestStaleAccessRequest(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+	message := h.accessMessage(t, session)
+
+	h.clock.Advance(time.Minute)
+
+	_, _, _, err := h.av.Verify(h.ctx, message, &MockAttributes{})
+	expectErrorCode(t, err, "BA501")
+}
@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	baerrors "github.com/jasoncolburne/better-auth-go/pkg/errors"
+)
+
+func TestFreshAuthentication(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	if token.AuthenticatedAt == "" {
+		t.Fatalf("expected authenticatedAt to be set")
+	}
+
+	if token.AuthenticatedAt != token.IssuedAt {
+		t.Errorf("expected authenticatedAt %s to equal issuedAt %s", token.AuthenticatedAt, token.IssuedAt)
+	}
+
+	authenticatedAt := token.AuthenticatedAt
+
+	h.clock.Advance(time.Second)
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	token, err = h.access(t, h.av.With(api.RequireFreshAuthentication(time.Hour)), session)
+	if err != nil {
+		t.Fatalf("expected fresh access to succeed: %v", err)
+	}
+
+	if token.AuthenticatedAt != authenticatedAt {
+		t.Errorf("expected refresh to preserve authenticatedAt %s, got %s", authenticatedAt, token.AuthenticatedAt)
+	}
+
+	if token.IssuedAt == authenticatedAt {
+		t.Errorf("expected refreshed token to have a new issuedAt")
+	}
+
+	_, err = h.access(t, h.av.With(api.RequireFreshAuthentication(500*time.Millisecond)), session)
+	expectErrorCode(t, err, "BA401")
+
+	// sub-second ages round up rather than being reported as 0
+	var betterAuthError *baerrors.BetterAuthError
+	if !errors.As(err, &betterAuthError) || betterAuthError.Context["maximumAge"] != int64(1) {
+		t.Errorf("expected maximumAge 1, got %v", err)
+	}
+
+	session = h.createSession(t, account, MockAttributes{})
+	if _, err := h.access(t, h.av.With(api.RequireFreshAuthentication(time.Minute)), session); err != nil {
+		t.Fatalf("expected access with a new session to succeed: %v", err)
+	}
+}
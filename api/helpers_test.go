@@ -0,0 +1,591 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	baerrors "github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/eventinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+type testHarness struct {
+	ctx context.Context
+
+	// clock drives every time-sensitive component, and only moves when advanced.
+	clock *clock.ManualClock
+
+	// entropy seeds every key and nonce of the test.
+	entropy *crypto.SeededEntropy
+
+	hasher       *crypto.Blake3
+	noncer       *crypto.Noncer
+	timestamper  *encoding.Rfc3339
+	tokenEncoder encodinginterfaces.TokenEncoder
+
+	// codec is the wire format of client flows, JSON when nil.
+	codec encodinginterfaces.MessageCodec
+
+	serverResponseKey *crypto.Secp256r1
+	serverAccessKey   *crypto.Secp256r1
+
+	events   *recordingEventEmitter
+	sessions *storage.InMemorySessionStore
+
+	ba *api.BetterAuthServer[MockAttributes]
+	av *api.AccessVerifier[MockAttributes]
+
+	// server handles client flows, and defaults to ba. tenant is placed in request envelopes when set.
+	server authServer
+	tenant string
+}
+
+type authServer interface {
+	CreateAccount(ctx context.Context, message string) (string, error)
+	RequestSession(ctx context.Context, message string) (string, error)
+	CreateSession(ctx context.Context, message string, attributes MockAttributes, options ...api.SessionOption) (string, error)
+	RefreshSession(ctx context.Context, message string) (string, error)
+}
+
+func newTestHarness(t *testing.T, options ...api.BetterAuthServerOption) *testHarness {
+	t.Helper()
+
+	return newEncodedTestHarness(t, nil, encoding.NewTokenEncoder[MockAttributes](), options...)
+}
+
+func newCBORTestHarness(t *testing.T, options ...api.BetterAuthServerOption) *testHarness {
+	t.Helper()
+
+	return newEncodedTestHarness(t, encoding.NewCBOR(), encoding.NewCBORTokenEncoder[MockAttributes](), options...)
+}
+
+func newEncodedTestHarness(
+	t *testing.T,
+	codec encodinginterfaces.MessageCodec,
+	tokenEncoder encodinginterfaces.TokenEncoder,
+	options ...api.BetterAuthServerOption,
+) *testHarness {
+	t.Helper()
+
+	return buildTestHarness(t, codec, tokenEncoder, true, options...)
+}
+
+// newUntrackedTestHarness builds a server with neither a session registry nor a revocation store.
+func newUntrackedTestHarness(t *testing.T, options ...api.BetterAuthServerOption) *testHarness {
+	t.Helper()
+
+	return buildTestHarness(t, nil, encoding.NewTokenEncoder[MockAttributes](), false, options...)
+}
+
+func buildTestHarness(
+	t *testing.T,
+	codec encodinginterfaces.MessageCodec,
+	tokenEncoder encodinginterfaces.TokenEncoder,
+	tracked bool,
+	options ...api.BetterAuthServerOption,
+) *testHarness {
+	t.Helper()
+
+	hasher := crypto.NewBlake3()
+	verifier := crypto.NewSecp256r1Verifier()
+	// every test draws the same keys and nonces on each run
+	entropy := crypto.NewSeededEntropy([]byte(t.Name()))
+	noncer := crypto.NewNoncerWithEntropy(entropy)
+	clock := clock.NewManualClock(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	timestamper := encoding.NewRfc3339WithClock(clock)
+
+	serverResponseKey, err := crypto.NewSecp256r1WithEntropy(entropy)
+	if err != nil {
+		t.Fatalf("failed to generate response key: %v", err)
+	}
+
+	serverAccessKey, err := crypto.NewSecp256r1WithEntropy(entropy)
+	if err != nil {
+		t.Fatalf("failed to generate access key: %v", err)
+	}
+
+	accessIdentity, err := serverAccessKey.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive access identity: %v", err)
+	}
+
+	accessKeyStore := storage.NewVerificationKeyStore()
+	accessKeyStore.Add(accessIdentity, serverAccessKey)
+
+	events := &recordingEventEmitter{}
+
+	stores := &api.StoresContainer{
+		Access: &api.AccessStoreContainer{
+			KeyHash:         storage.NewInMemoryTimeLockStoreWithClock(12*time.Hour, clock),
+			VerificationKey: accessKeyStore,
+		},
+		Authentication: &api.AuthenticationStoreContainer{
+			Key:   storage.NewInMemoryAuthenticationKeyStore(),
+			Nonce: storage.NewInMemoryAuthenticationNonceStoreWithNoncer(1*time.Minute, clock, noncer),
+		},
+		Recovery: &api.RecoveryStoreContainer{
+			Hash: storage.NewInMemoryRecoveryHashStore(),
+		},
+	}
+
+	var sessionStore *storage.InMemorySessionStore
+	var verifierOptions []api.AccessVerifierOption
+	if tracked {
+		revocationStore := storage.NewInMemoryRevocationStoreWithClock(12*time.Hour, clock)
+		sessionStore = storage.NewInMemorySessionStoreWithClock(clock)
+
+		stores.Access.Revocation = revocationStore
+		stores.Session = &api.SessionStoreContainer{
+			Registry: sessionStore,
+		}
+		verifierOptions = append(verifierOptions, api.CheckRevocation(revocationStore))
+	}
+
+	ba := api.NewBetterAuthServer[MockAttributes](
+		&api.CryptoContainer{
+			Hasher: hasher,
+			KeyPair: &api.KeyPairContainer{
+				Access:   serverAccessKey,
+				Response: serverResponseKey,
+			},
+			Noncer:   noncer,
+			Verifier: verifier,
+		},
+		&api.EncodingContainer{
+			IdentityVerifier: encoding.NewMockIdentityVerifier(hasher),
+			Timestamper:      timestamper,
+			TokenEncoder:     tokenEncoder,
+			MessageCodec:     codec,
+		},
+		&api.ExpiryContainer{
+			Access:  15 * time.Minute,
+			Refresh: 12 * time.Hour,
+		},
+		stores,
+		append([]api.BetterAuthServerOption{api.WithSecurityEventEmitter(events)}, options...)...,
+	)
+
+	av := api.NewAccessVerifier[MockAttributes](
+		&api.VerifierCryptoContainer{
+			Verifier: verifier,
+		},
+		&api.VerifierEncodingContainer{
+			TokenEncoder: tokenEncoder,
+			Timestamper:  timestamper,
+			MessageCodec: codec,
+		},
+		&api.VerifierStoreContainer{
+			AccessNonce: storage.NewInMemoryTimeLockStoreWithClock(30*time.Second, clock),
+			AccessKey:   accessKeyStore,
+		},
+		verifierOptions...,
+	)
+
+	return &testHarness{
+		ctx:               context.Background(),
+		clock:             clock,
+		entropy:           entropy,
+		hasher:            hasher,
+		noncer:            noncer,
+		timestamper:       timestamper,
+		tokenEncoder:      tokenEncoder,
+		codec:             codec,
+		serverResponseKey: serverResponseKey,
+		serverAccessKey:   serverAccessKey,
+		events:            events,
+		sessions:          sessionStore,
+		ba:                ba,
+		av:                av,
+		server:            ba,
+	}
+}
+
+// newVerifier builds a resource server verifier trusting the harness access key, with its own clock
+// and nonce store.
+func (h *testHarness) newVerifier(
+	t *testing.T,
+	timestamper encodinginterfaces.Timestamper,
+	nonceStore storageinterfaces.TimeLockStore,
+	options ...api.AccessVerifierOption,
+) *api.AccessVerifier[MockAttributes] {
+	t.Helper()
+
+	accessIdentity, err := h.serverAccessKey.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive access identity: %v", err)
+	}
+
+	accessKeyStore := storage.NewVerificationKeyStore()
+	accessKeyStore.Add(accessIdentity, h.serverAccessKey)
+
+	return api.NewAccessVerifier[MockAttributes](
+		&api.VerifierCryptoContainer{
+			Verifier: crypto.NewSecp256r1Verifier(),
+		},
+		&api.VerifierEncodingContainer{
+			TokenEncoder: h.tokenEncoder,
+			Timestamper:  timestamper,
+			MessageCodec: h.codec,
+		},
+		&api.VerifierStoreContainer{
+			AccessNonce: nonceStore,
+			AccessKey:   accessKeyStore,
+		},
+		options...,
+	)
+}
+
+// testAccount is a single-device account whose authentication key has not yet been rotated.
+type testAccount struct {
+	identity string
+	device   string
+
+	currentKey *crypto.Secp256r1
+	nextKey    *crypto.Secp256r1
+}
+
+// testSession tracks the client side of an access key chain.
+type testSession struct {
+	token string
+
+	currentKey *crypto.Secp256r1
+	nextKey    *crypto.Secp256r1
+}
+
+func (h *testHarness) newKey(t *testing.T) (*crypto.Secp256r1, string) {
+	t.Helper()
+
+	key, err := crypto.NewSecp256r1WithEntropy(h.entropy)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	publicKey, err := key.Public()
+	if err != nil {
+		t.Fatalf("failed to derive public key: %v", err)
+	}
+
+	return key, publicKey
+}
+
+func (h *testHarness) newNonce(t *testing.T) string {
+	t.Helper()
+
+	nonce, err := h.noncer.Generate128()
+	if err != nil {
+		t.Fatalf("failed to generate nonce: %v", err)
+	}
+
+	return nonce
+}
+
+func (h *testHarness) publicKey(t *testing.T, key *crypto.Secp256r1) string {
+	t.Helper()
+
+	publicKey, err := key.Public()
+	if err != nil {
+		t.Fatalf("failed to derive public key: %v", err)
+	}
+
+	return publicKey
+}
+
+type clientRequest interface {
+	Sign(signer cryptointerfaces.SigningKey) error
+	Serialize() (string, error)
+}
+
+// encodeRequest signs request with key, if given, over the harness' wire format and serializes it in
+// that format.
+func (h *testHarness) encodeRequest(t *testing.T, request clientRequest, key *crypto.Secp256r1) string {
+	t.Helper()
+
+	if key != nil {
+		if err := request.Sign(messages.EncodedSigningKey(key, h.codec)); err != nil {
+			t.Fatalf("failed to sign request: %v", err)
+		}
+	}
+
+	message, err := request.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize request: %v", err)
+	}
+
+	if h.codec == nil {
+		return message
+	}
+
+	encoded, err := h.codec.Encode(message)
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	return encoded
+}
+
+func (h *testHarness) decodeReply(t *testing.T, reply string) string {
+	t.Helper()
+
+	if h.codec == nil {
+		return reply
+	}
+
+	decoded, err := h.codec.Decode(reply)
+	if err != nil {
+		t.Fatalf("failed to decode reply: %v", err)
+	}
+
+	return decoded
+}
+
+func (h *testHarness) createAccount(t *testing.T) *testAccount {
+	t.Helper()
+
+	currentKey, currentPublicKey := h.newKey(t)
+	nextKey, nextPublicKey := h.newKey(t)
+	_, recoveryPublicKey := h.newKey(t)
+
+	rotationHash := h.hasher.Sum([]byte(nextPublicKey))
+	recoveryHash := h.hasher.Sum([]byte(recoveryPublicKey))
+	device := h.hasher.Sum([]byte(currentPublicKey + rotationHash))
+	identity := h.hasher.Sum([]byte(currentPublicKey + rotationHash + recoveryHash))
+
+	request := messages.NewCreateAccountRequest(
+		messages.CreateAccountRequestPayload{
+			Authentication: messages.CreateAccountRequestAuthentication{
+				Device:       device,
+				Identity:     identity,
+				PublicKey:    currentPublicKey,
+				RecoveryHash: recoveryHash,
+				RotationHash: rotationHash,
+			},
+		},
+		h.newNonce(t),
+	)
+
+	request.Payload.Access.Tenant = h.tenant
+
+	message := h.encodeRequest(t, request, currentKey)
+
+	if _, err := h.server.CreateAccount(h.ctx, message); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	return &testAccount{
+		identity:   identity,
+		device:     device,
+		currentKey: currentKey,
+		nextKey:    nextKey,
+	}
+}
+
+func (h *testHarness) createSession(t *testing.T, account *testAccount, attributes MockAttributes, options ...api.SessionOption) *testSession {
+	t.Helper()
+
+	requestSessionRequest := messages.NewRequestSessionRequest(
+		messages.RequestSessionRequestPayload{
+			Authentication: messages.RequestSessionRequestAuthentication{
+				Identity: account.identity,
+			},
+		},
+		h.newNonce(t),
+	)
+
+	requestSessionRequest.Payload.Access.Tenant = h.tenant
+
+	message := h.encodeRequest(t, requestSessionRequest, nil)
+
+	reply, err := h.server.RequestSession(h.ctx, message)
+	if err != nil {
+		t.Fatalf("failed to request session: %v", err)
+	}
+
+	requestSessionResponse, err := messages.ParseRequestSessionResponse(h.decodeReply(t, reply))
+	if err != nil {
+		t.Fatalf("failed to parse request session response: %v", err)
+	}
+
+	accessKey, accessPublicKey := h.newKey(t)
+	nextAccessKey, nextAccessPublicKey := h.newKey(t)
+
+	request := messages.NewCreateSessionRequest(
+		messages.CreateSessionRequestPayload{
+			Access: messages.CreateSessionRequestAccess{
+				PublicKey:    accessPublicKey,
+				RotationHash: h.hasher.Sum([]byte(nextAccessPublicKey)),
+			},
+			Authentication: messages.CreateSessionRequestAuthentication{
+				Device: account.device,
+				Nonce:  requestSessionResponse.Payload.Response.Authentication.Nonce,
+			},
+		},
+		h.newNonce(t),
+	)
+
+	request.Payload.Access.Tenant = h.tenant
+
+	message = h.encodeRequest(t, request, account.currentKey)
+
+	reply, err = h.server.CreateSession(h.ctx, message, attributes, options...)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	response, err := messages.ParseCreateSessionResponse(h.decodeReply(t, reply))
+	if err != nil {
+		t.Fatalf("failed to parse create session response: %v", err)
+	}
+
+	return &testSession{
+		token:      response.Payload.Response.Access.Token,
+		currentKey: accessKey,
+		nextKey:    nextAccessKey,
+	}
+}
+
+// refreshRequest builds a refresh request for the session's current link without advancing the session.
+func (h *testHarness) refreshRequest(t *testing.T, session *testSession) (string, *crypto.Secp256r1) {
+	t.Helper()
+
+	nextNextKey, nextNextPublicKey := h.newKey(t)
+
+	request := messages.NewRefreshSessionRequest(
+		messages.RefreshSessionRequestPayload{
+			Access: messages.RefreshSessionRequestAccess{
+				PublicKey:    h.publicKey(t, session.nextKey),
+				RotationHash: h.hasher.Sum([]byte(nextNextPublicKey)),
+				Token:        session.token,
+			},
+		},
+		h.newNonce(t),
+	)
+
+	request.Payload.Access.Tenant = h.tenant
+
+	message := h.encodeRequest(t, request, session.nextKey)
+
+	return message, nextNextKey
+}
+
+// refreshSession refreshes the session, advancing it on success.
+func (h *testHarness) refreshSession(t *testing.T, session *testSession) error {
+	t.Helper()
+
+	message, nextNextKey := h.refreshRequest(t, session)
+
+	reply, err := h.server.RefreshSession(h.ctx, message)
+	if err != nil {
+		return err
+	}
+
+	response, err := messages.ParseRefreshSessionResponse(h.decodeReply(t, reply))
+	if err != nil {
+		return err
+	}
+
+	session.token = response.Payload.Response.Access.Token
+	session.currentKey = session.nextKey
+	session.nextKey = nextNextKey
+
+	return nil
+}
+
+func (h *testHarness) accessMessage(t *testing.T, session *testSession) string {
+	t.Helper()
+
+	request := NewFakeAccessRequest(
+		FakeAccessRequestPayload{
+			Foo: "bar",
+			Bar: "foo",
+		},
+		h.timestamper,
+		session.token,
+		h.newNonce(t),
+	)
+
+	message := h.encodeRequest(t, request, session.currentKey)
+
+	return message
+}
+
+func (h *testHarness) access(t *testing.T, av *api.AccessVerifier[MockAttributes], session *testSession) (*messages.AccessToken[MockAttributes], error) {
+	t.Helper()
+
+	_, token, _, err := av.Verify(h.ctx, h.accessMessage(t, session), &MockAttributes{})
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil {
+		return nil, fmt.Errorf("null token")
+	}
+
+	return token, nil
+}
+
+type recordingEventEmitter struct {
+	mu     sync.Mutex
+	events []eventinterfaces.SecurityEvent
+}
+
+func (e *recordingEventEmitter) Emit(ctx context.Context, event eventinterfaces.SecurityEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.events = append(e.events, event)
+}
+
+func (e *recordingEventEmitter) recorded() []eventinterfaces.SecurityEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]eventinterfaces.SecurityEvent{}, e.events...)
+}
+
+func errorCode(err error) string {
+	var betterAuthError *baerrors.BetterAuthError
+	if errors.As(err, &betterAuthError) {
+		return betterAuthError.Code
+	}
+
+	return ""
+}
+
+func expectErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("expected error %s, got success", code)
+	}
+
+	if actual := errorCode(err); actual != code {
+		t.Fatalf("expected error %s, got %q (%v)", code, actual, err)
+	}
+}
+
+// expectErrorReason expects an error whose code is shared with other cases, told apart by the reason
+// in its context.
+func expectErrorReason(t *testing.T, err error, code, reason string) {
+	t.Helper()
+
+	expectErrorCode(t, err, code)
+
+	var betterAuthError *baerrors.BetterAuthError
+	errors.As(err, &betterAuthError)
+
+	if actual := betterAuthError.Context["reason"]; actual != reason {
+		t.Fatalf("expected error reason %s, got %v (%v)", reason, actual, err)
+	}
+}
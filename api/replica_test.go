@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/resp"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+)
+
+func TestReplayAcrossReplicas(t *testing.T) {
+	h := newTestHarness(t)
+
+	server := resp.NewServerWithClock(h.clock)
+	address, err := server.Start()
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Close()
+
+	replica := func() *resp.Client {
+		client := resp.NewClient(address)
+		t.Cleanup(func() { client.Close() })
+
+		return client
+	}
+
+	podA := h.newVerifier(t, h.timestamper, storage.NewRedisTimeLockStore(replica(), "access:", 30*time.Second))
+	podB := h.newVerifier(t, h.timestamper, storage.NewRedisTimeLockStore(replica(), "access:", 30*time.Second))
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	message := h.accessMessage(t, session)
+
+	if _, _, _, err := podA.Verify(h.ctx, message, &MockAttributes{}); err != nil {
+		t.Fatalf("failed to verify on pod A: %v", err)
+	}
+
+	if _, _, _, err := podB.Verify(h.ctx, message, &MockAttributes{}); err == nil {
+		t.Fatalf("expected replay on pod B to be refused")
+	}
+
+	if _, err := h.access(t, podB, session); err != nil {
+		t.Fatalf("expected a fresh request on pod B to succeed: %v", err)
+	}
+}
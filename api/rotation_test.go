@@ -0,0 +1,129 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+// rotateDevice presents key as the account's next key, committing to a fresh one.
+func (h *testHarness) rotateDevice(t *testing.T, account *testAccount, key *crypto.Secp256r1) error {
+	t.Helper()
+
+	_, nextNextPublicKey := h.newKey(t)
+
+	request := messages.NewRotateDeviceRequest(
+		messages.RotateDeviceRequestPayload{
+			Authentication: messages.RotateDeviceRequestAuthentication{
+				Device:       account.device,
+				Identity:     account.identity,
+				PublicKey:    h.publicKey(t, key),
+				RotationHash: h.hasher.Sum([]byte(nextNextPublicKey)),
+			},
+		},
+		h.newNonce(t),
+	)
+
+	_, err := h.ba.RotateDevice(h.ctx, h.encodeRequest(t, request, key))
+	return err
+}
+
+func TestRotationConflict(t *testing.T) {
+	h := newTestHarness(t)
+	account := h.createAccount(t)
+
+	if err := h.rotateDevice(t, account, account.nextKey); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	// a second holder of the same next key loses the race
+	expectErrorCode(t, h.rotateDevice(t, account, account.nextKey), "BA104")
+
+	// as does a key that was never committed to
+	expectErrorCode(t, h.rotateDevice(t, account, account.currentKey), "BA104")
+}
+
+// newLinkContainer signs a link container for a fresh device of account.
+func (h *testHarness) newLinkContainer(t *testing.T, account *testAccount) *messages.LinkContainer {
+	t.Helper()
+
+	linkedKey, linkedPublicKey := h.newKey(t)
+	_, linkedNextPublicKey := h.newKey(t)
+	linkedRotationHash := h.hasher.Sum([]byte(linkedNextPublicKey))
+
+	link := messages.NewLinkContainer(
+		messages.LinkContainerPayload{
+			Authentication: messages.LinkContainerAuthentication{
+				Device:       h.hasher.Sum([]byte(linkedPublicKey + linkedRotationHash)),
+				Identity:     account.identity,
+				PublicKey:    linkedPublicKey,
+				RotationHash: linkedRotationHash,
+			},
+		},
+		nil,
+	)
+
+	if err := link.Sign(linkedKey); err != nil {
+		t.Fatalf("failed to sign link container: %v", err)
+	}
+
+	return link
+}
+
+// linkDevice presents key as the account's next key, committing to the returned one.
+func (h *testHarness) linkDevice(t *testing.T, account *testAccount, key *crypto.Secp256r1, link *messages.LinkContainer) (*crypto.Secp256r1, error) {
+	t.Helper()
+
+	nextNextKey, nextNextPublicKey := h.newKey(t)
+
+	request := messages.NewLinkDeviceRequest(
+		messages.LinkDeviceRequestPayload{
+			Authentication: messages.LinkDeviceRequestAuthentication{
+				Device:       account.device,
+				Identity:     account.identity,
+				PublicKey:    h.publicKey(t, key),
+				RotationHash: h.hasher.Sum([]byte(nextNextPublicKey)),
+			},
+			Link: *link,
+		},
+		h.newNonce(t),
+	)
+
+	_, err := h.ba.LinkDevice(h.ctx, h.encodeRequest(t, request, key))
+	return nextNextKey, err
+}
+
+func TestLinkDeviceRotationConflict(t *testing.T) {
+	h := newTestHarness(t)
+	account := h.createAccount(t)
+
+	// signed with the current key rather than the committed next key
+	_, err := h.linkDevice(t, account, account.currentKey, h.newLinkContainer(t, account))
+	expectErrorCode(t, err, "BA104")
+
+	// the link was refused, so the account's commitment is intact
+	if err := h.rotateDevice(t, account, account.nextKey); err != nil {
+		t.Fatalf("failed to rotate after the refused link: %v", err)
+	}
+}
+
+func TestLinkDeviceRegistrationFailureKeepsRotation(t *testing.T) {
+	h := newTestHarness(t)
+	account := h.createAccount(t)
+	link := h.newLinkContainer(t, account)
+
+	nextKey, err := h.linkDevice(t, account, account.nextKey, link)
+	if err != nil {
+		t.Fatalf("failed to link device: %v", err)
+	}
+
+	// the device is already linked, so the rotation authorizing it again must not be spent
+	if _, err := h.linkDevice(t, account, nextKey, link); err == nil {
+		t.Fatalf("expected linking a registered device to fail")
+	}
+
+	if err := h.rotateDevice(t, account, nextKey); err != nil {
+		t.Fatalf("failed to rotate after the refused link: %v", err)
+	}
+}
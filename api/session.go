@@ -2,18 +2,31 @@ package api
 
 import (
 	"context"
+	stderrors "errors"
 	"strings"
+	"time"
 
 	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/eventinterfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
 )
 
 func (ba *BetterAuthServer[AttributesType]) RequestSession(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseRequestSessionRequest(message)
 	if err != nil {
 		return "", err
 	}
 
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
 	nonce, err := ba.store.Authentication.Nonce.Generate(
 		ctx,
 		request.Payload.Request.Authentication.Identity,
@@ -36,12 +49,13 @@ func (ba *BetterAuthServer[AttributesType]) RequestSession(ctx context.Context,
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -49,12 +63,52 @@ func (ba *BetterAuthServer[AttributesType]) RequestSession(ctx context.Context,
 	return reply, err
 }
 
-func (ba *BetterAuthServer[AttributesType]) CreateSession(ctx context.Context, message string, attributes AttributesType) (string, error) {
+type sessionOptions struct {
+	refresh time.Duration
+	session time.Duration
+}
+
+type SessionOption func(*sessionOptions)
+
+// WithRefreshWindow overrides ExpiryContainer.Refresh for a single session, e.g. for "remember me".
+// The access key hash store's lifetime must cover the longest refresh window in use, or consumed
+// tokens could be refreshed again.
+func WithRefreshWindow(window time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.refresh = window
+	}
+}
+
+// WithSessionLifetime overrides ExpiryContainer.Session for a single session.
+func WithSessionLifetime(lifetime time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.session = lifetime
+	}
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
+
+func (ba *BetterAuthServer[AttributesType]) CreateSession(ctx context.Context, message string, attributes AttributesType, options ...SessionOption) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseCreateSessionRequest(message)
 	if err != nil {
 		return "", err
 	}
 
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
 	identity, err := ba.store.Authentication.Nonce.Verify(
 		ctx,
 		request.Payload.Request.Authentication.Nonce,
@@ -72,23 +126,60 @@ func (ba *BetterAuthServer[AttributesType]) CreateSession(ctx context.Context, m
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, authenticationPublicKey); err != nil {
+	if err := request.Verify(ba.verifier(), authenticationPublicKey); err != nil {
 		return "", err
 	}
 
+	lifetimes := sessionOptions{
+		refresh: ba.expiry.Refresh,
+		session: ba.expiry.Session,
+	}
+
+	for _, option := range options {
+		option(&lifetimes)
+	}
+
+	if lifetimes.session == 0 {
+		lifetimes.session = lifetimes.refresh
+	}
+
 	now := ba.encoding.Timestamper.Now()
-	expiryTime := now.Add(ba.expiry.Access)
-	refreshExpiryTime := now.Add(ba.expiry.Refresh)
+	sessionExpiryTime := now.Add(lifetimes.session)
+	expiryTime := earliest(now.Add(ba.expiry.Access), sessionExpiryTime)
+	refreshExpiryTime := earliest(now.Add(lifetimes.refresh), sessionExpiryTime)
 
 	issuedAt := ba.encoding.Timestamper.Format(now)
 	expiry := ba.encoding.Timestamper.Format(expiryTime)
 	refreshExpiry := ba.encoding.Timestamper.Format(refreshExpiryTime)
+	sessionExpiry := ba.encoding.Timestamper.Format(sessionExpiryTime)
 
 	accessServerIdentity, err := ba.crypto.KeyPair.Access.Identity()
 	if err != nil {
 		return "", err
 	}
 
+	session, err := ba.crypto.Noncer.Generate128()
+	if err != nil {
+		return "", err
+	}
+
+	chain, err := ba.crypto.Noncer.Generate128()
+	if err != nil {
+		return "", err
+	}
+
+	if registry := ba.registry(); registry != nil {
+		if err := registry.Create(
+			ctx,
+			session,
+			identity,
+			request.Payload.Request.Authentication.Device,
+			refreshExpiryTime.Add(ba.options.skew),
+		); err != nil {
+			return "", err
+		}
+	}
+
 	accessToken := messages.NewAccessToken(
 		accessServerIdentity,
 		request.Payload.Request.Authentication.Device,
@@ -99,9 +190,14 @@ func (ba *BetterAuthServer[AttributesType]) CreateSession(ctx context.Context, m
 		expiry,
 		refreshExpiry,
 		attributes,
+		messages.WithSessionExpiry(sessionExpiry),
+		messages.WithAuthenticatedAt(issuedAt),
+		messages.WithSession(session),
+		messages.WithChain(chain),
+		messages.WithTenant(ba.options.tenant),
 	)
 
-	if err := accessToken.Sign(ba.crypto.KeyPair.Access); err != nil {
+	if err := ba.signToken(accessToken, ba.crypto.KeyPair.Access); err != nil {
 		return "", err
 	}
 
@@ -124,12 +220,13 @@ func (ba *BetterAuthServer[AttributesType]) CreateSession(ctx context.Context, m
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
@@ -138,12 +235,21 @@ func (ba *BetterAuthServer[AttributesType]) CreateSession(ctx context.Context, m
 }
 
 func (ba *BetterAuthServer[AttributesType]) RefreshSession(ctx context.Context, message string) (string, error) {
+	message, err := ba.decode(message)
+	if err != nil {
+		return "", err
+	}
+
 	request, err := messages.ParseRefreshSessionRequest(message)
 	if err != nil {
 		return "", err
 	}
 
-	if err := request.Verify(ba.crypto.Verifier, request.Payload.Request.Access.PublicKey); err != nil {
+	if err := ba.acceptVersion(&request.Payload.Access); err != nil {
+		return "", err
+	}
+
+	if err := request.Verify(ba.verifier(), request.Payload.Request.Access.PublicKey); err != nil {
 		return "", err
 	}
 
@@ -166,10 +272,26 @@ func (ba *BetterAuthServer[AttributesType]) RefreshSession(ctx context.Context,
 		return "", err
 	}
 
-	if err := token.VerifySignature(ba.crypto.KeyPair.Access.Verifier(), accessPublicKey); err != nil {
+	tokenVerifier := messages.EncodedVerifier(ba.crypto.KeyPair.Access.Verifier(), ba.encoding.TokenEncoder)
+	if err := token.VerifySignature(tokenVerifier, accessPublicKey); err != nil {
 		return "", err
 	}
 
+	if token.Tenant != ba.options.tenant {
+		return "", errors.NewMismatchedTenantError(ba.options.tenant, token.Tenant)
+	}
+
+	if revocation := ba.store.Access.Revocation; revocation != nil {
+		revoked, err := isRevoked(ctx, revocation, token)
+		if err != nil {
+			return "", err
+		}
+
+		if revoked {
+			return "", errors.NewRevokedSessionError(token.Session)
+		}
+	}
+
 	hash := ba.crypto.Hasher.Sum([]byte(request.Payload.Request.Access.PublicKey))
 	if !strings.EqualFold(hash, token.RotationHash) {
 		return "", errors.NewInvalidHashError(token.RotationHash, hash, "rotation")
@@ -185,19 +307,60 @@ func (ba *BetterAuthServer[AttributesType]) RefreshSession(ctx context.Context,
 		return "", err
 	}
 
-	if now.After(refreshExpiry) {
+	// tokens issued without an absolute lifetime had a fixed refresh deadline
+	sessionExpiry := refreshExpiry
+	sessionExpiryStr := token.RefreshExpiry
+	if token.SessionExpiry != "" {
+		sessionExpiryStr = token.SessionExpiry
+		sessionExpiry, err = ba.encoding.Timestamper.Parse(token.SessionExpiry)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if now.After(sessionExpiry.Add(ba.options.skew)) {
 		nowStr := ba.encoding.Timestamper.Format(now)
-		return "", errors.NewExpiredTokenError(token.RefreshExpiry, nowStr, "refresh")
+		return "", errors.NewExpiredTokenErrorWithSkew(sessionExpiryStr, nowStr, "session", ba.options.skew.Seconds())
 	}
 
-	if err := ba.store.Access.KeyHash.Reserve(ctx, hash); err != nil {
+	if now.After(refreshExpiry.Add(ba.options.skew)) {
+		nowStr := ba.encoding.Timestamper.Format(now)
+		return "", errors.NewExpiredTokenErrorWithSkew(token.RefreshExpiry, nowStr, "refresh", ba.options.skew.Seconds())
+	}
+
+	previouslyIssuedAt, err := ba.encoding.Timestamper.Parse(token.IssuedAt)
+	if err != nil {
 		return "", err
 	}
 
-	later := now.Add(ba.expiry.Access)
+	// the idle window (which may have been overridden at creation) is carried forward implicitly. once
+	// the window is clamped by the session expiry it remains clamped, so this never extends a session.
+	refreshWindow := refreshExpiry.Sub(previouslyIssuedAt)
+
+	later := earliest(now.Add(ba.expiry.Access), sessionExpiry)
+	nextRefreshExpiry := earliest(now.Add(refreshWindow), sessionExpiry)
+
+	// checked before the key hash is consumed, so a refresh the registry refuses leaves the token
+	// usable. tokens issued before sessions were registered carry no session and are not tracked.
+	if registry := ba.registry(); registry != nil && token.Session != "" {
+		if err := registry.Refresh(ctx, token.Session, nextRefreshExpiry.Add(ba.options.skew)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := ba.store.Access.KeyHash.Reserve(ctx, hash); err != nil {
+		if !stderrors.Is(err, storageinterfaces.ErrReserved) {
+			return "", err
+		}
+
+		// a consumed link of the chain was presented again, so either the client or an attacker holds a
+		// copy of it and nothing descending from it can be trusted
+		return "", ba.revokeReusedChain(ctx, token)
+	}
 
 	issuedAt := ba.encoding.Timestamper.Format(now)
 	expiry := ba.encoding.Timestamper.Format(later)
+	refreshExpiryStr := ba.encoding.Timestamper.Format(nextRefreshExpiry)
 
 	accessServerIdentity, err := ba.crypto.KeyPair.Access.Identity()
 	if err != nil {
@@ -212,11 +375,16 @@ func (ba *BetterAuthServer[AttributesType]) RefreshSession(ctx context.Context,
 		request.Payload.Request.Access.RotationHash,
 		issuedAt,
 		expiry,
-		token.RefreshExpiry,
+		refreshExpiryStr,
 		token.Attributes,
+		messages.WithSessionExpiry(sessionExpiryStr),
+		messages.WithAuthenticatedAt(token.AuthenticatedAt),
+		messages.WithSession(token.Session),
+		messages.WithChain(token.Chain),
+		messages.WithTenant(token.Tenant),
 	)
 
-	if err := accessToken.Sign(ba.crypto.KeyPair.Access); err != nil {
+	if err := ba.signToken(accessToken, ba.crypto.KeyPair.Access); err != nil {
 		return "", err
 	}
 
@@ -239,15 +407,110 @@ func (ba *BetterAuthServer[AttributesType]) RefreshSession(ctx context.Context,
 		serverIdentity,
 		request.Payload.Access.Nonce,
 	)
+	response.Payload.Access.Version = request.Payload.Access.Version
 
-	if err := response.Sign(ba.crypto.KeyPair.Response); err != nil {
+	if err := ba.sign(response, ba.crypto.KeyPair.Response); err != nil {
 		return "", err
 	}
 
-	reply, err := response.Serialize()
+	reply, err := ba.serialize(response)
 	if err != nil {
 		return "", err
 	}
 
 	return reply, nil
 }
+
+// ErrNoSessionRegistry is returned by ListSessions and TerminateSession when the server's stores have
+// no session registry. Without one, sessions are neither registered nor checked on refresh.
+var ErrNoSessionRegistry = stderrors.New("no session registry is configured")
+
+// ListSessions returns the unexpired sessions for identity. Callers are expected to have authenticated
+// the identity, typically with an AccessVerifier.
+func (ba *BetterAuthServer[AttributesType]) ListSessions(ctx context.Context, identity string) ([]storageinterfaces.Session, error) {
+	registry := ba.registry()
+	if registry == nil {
+		return nil, ErrNoSessionRegistry
+	}
+
+	return registry.List(ctx, identity)
+}
+
+// TerminateSession ends a session belonging to identity. Subsequent attempts to refresh it will fail,
+// and with a revocation store so will access with tokens already issued for it.
+func (ba *BetterAuthServer[AttributesType]) TerminateSession(ctx context.Context, identity, session string) error {
+	registry := ba.registry()
+	if registry == nil {
+		return ErrNoSessionRegistry
+	}
+
+	if err := registry.Terminate(ctx, identity, session); err != nil {
+		return err
+	}
+
+	if ba.store.Access.Revocation == nil {
+		return nil
+	}
+
+	return ba.store.Access.Revocation.Revoke(ctx, session)
+}
+
+// registry returns the session registry, which is optional.
+func (ba *BetterAuthServer[AttributesType]) registry() storageinterfaces.SessionStore {
+	if ba.store.Session == nil {
+		return nil
+	}
+
+	return ba.store.Session.Registry
+}
+
+func (ba *BetterAuthServer[AttributesType]) revokeReusedChain(ctx context.Context, token *messages.AccessToken[AttributesType]) error {
+	// a token without a chain has nothing to revoke, and revoking the empty chain would lock out every
+	// other such token
+	if token.Chain == "" {
+		return errors.NewRefreshReuseError(token.Session)
+	}
+
+	if revocation := ba.store.Access.Revocation; revocation != nil {
+		if err := revocation.Revoke(ctx, token.Chain); err != nil {
+			return err
+		}
+	}
+
+	ba.emit(ctx, eventinterfaces.SecurityEvent{
+		Kind:     eventinterfaces.RefreshReuseDetected,
+		Identity: token.Identity,
+		Device:   token.Device,
+		Session:  token.Session,
+		Details: map[string]any{
+			"chain": token.Chain,
+		},
+	})
+
+	// the session may already have been terminated, and the revocation above is what matters
+	if registry := ba.registry(); registry != nil && token.Session != "" {
+		_ = registry.Terminate(ctx, token.Identity, token.Session)
+	}
+
+	return errors.NewRefreshReuseError(token.Session)
+}
+
+// isRevoked reports whether the token's refresh chain or its session has been revoked.
+func isRevoked[AttributesType any](ctx context.Context, revocation storageinterfaces.RevocationStore, token *messages.AccessToken[AttributesType]) (bool, error) {
+	for _, value := range []string{token.Chain, token.Session} {
+		if value == "" {
+			continue
+		}
+
+		revoked, err := revocation.IsRevoked(ctx, value)
+		if err != nil {
+			return false, err
+		}
+
+		if revoked {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
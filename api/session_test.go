@@ -0,0 +1,189 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+func TestSessionRegistry(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	other := h.createAccount(t)
+
+	first := h.createSession(t, account, MockAttributes{})
+	second := h.createSession(t, account, MockAttributes{})
+	h.createSession(t, other, MockAttributes{})
+
+	sessions, err := h.ba.ListSessions(h.ctx, account.identity)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+
+	firstToken, err := h.access(t, h.av, first)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	found := false
+	for _, session := range sessions {
+		if session.Identity != account.identity || session.Device != account.device {
+			t.Errorf("unexpected session owner %s/%s", session.Identity, session.Device)
+		}
+
+		if session.Id == firstToken.Session {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("session %s not listed", firstToken.Session)
+	}
+
+	if err := h.ba.TerminateSession(h.ctx, other.identity, firstToken.Session); err == nil {
+		t.Fatalf("expected terminating another identity's session to fail")
+	}
+
+	if err := h.refreshSession(t, first); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	if err := h.ba.TerminateSession(h.ctx, account.identity, firstToken.Session); err != nil {
+		t.Fatalf("failed to terminate session: %v", err)
+	}
+
+	if err := h.refreshSession(t, first); err == nil {
+		t.Fatalf("expected refresh of terminated session to fail")
+	}
+
+	if err := h.refreshSession(t, second); err != nil {
+		t.Fatalf("failed to refresh remaining session: %v", err)
+	}
+
+	sessions, err = h.ba.ListSessions(h.ctx, account.identity)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(sessions))
+	}
+
+	if sessions[0].RefreshedAt.Before(sessions[0].CreatedAt) {
+		t.Errorf("expected refreshedAt to follow createdAt")
+	}
+}
+
+func TestSessionRegistryExpiry(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	h.createSession(t, account, MockAttributes{})
+	kept := h.createSession(t, account, MockAttributes{}, api.WithSessionLifetime(48*time.Hour))
+
+	h.clock.Advance(11 * time.Hour)
+
+	if err := h.refreshSession(t, kept); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	h.clock.Advance(2 * time.Hour)
+
+	sessions, err := h.ba.ListSessions(h.ctx, account.identity)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+
+	if len(sessions) != 1 || !sessions[0].RefreshedAt.Equal(sessions[0].CreatedAt.Add(11*time.Hour)) {
+		t.Fatalf("expected only the refreshed session to be listed, got %+v", sessions)
+	}
+}
+
+func TestRefreshChecksRegistryBeforeConsumingToken(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	token, err := h.access(t, h.av, session)
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	// removed from the registry behind the server's back, so the session is not revoked
+	if err := h.sessions.Terminate(h.ctx, account.identity, token.Session); err != nil {
+		t.Fatalf("failed to terminate: %v", err)
+	}
+
+	expectErrorReason(t, h.refreshSession(t, session), "BA401", "unknown")
+
+	if err := h.sessions.Create(h.ctx, token.Session, account.identity, account.device, h.clock.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to re-register: %v", err)
+	}
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("expected the refused refresh to leave the token usable: %v", err)
+	}
+}
+
+func TestRefreshTokenWithoutSession(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+	other := h.createSession(t, account, MockAttributes{})
+
+	// tokens issued before sessions were registered carry no session
+	issued, err := messages.ParseAccessToken[MockAttributes](session.token, h.tokenEncoder)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+
+	legacy := messages.NewAccessToken(
+		issued.ServerIdentity,
+		issued.Device,
+		issued.Identity,
+		issued.PublicKey,
+		issued.RotationHash,
+		issued.IssuedAt,
+		issued.Expiry,
+		issued.RefreshExpiry,
+		issued.Attributes,
+		messages.WithSessionExpiry(issued.SessionExpiry),
+		messages.WithAuthenticatedAt(issued.AuthenticatedAt),
+		messages.WithTenant(issued.Tenant),
+	)
+
+	if err := legacy.Sign(h.serverAccessKey); err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	session.token, err = legacy.SerializeToken(h.tokenEncoder)
+	if err != nil {
+		t.Fatalf("failed to serialize token: %v", err)
+	}
+
+	replayed := *session
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("failed to refresh a token without a session: %v", err)
+	}
+
+	// reuse is still refused, but there is no session to revoke
+	expectErrorReason(t, h.refreshSession(t, &replayed), "BA401", "reused")
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("expected other tokens without a session to remain refreshable: %v", err)
+	}
+
+	if err := h.refreshSession(t, other); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	baerrors "github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// skewedVerifier builds a verifier on a clock offset from the harness clock, as a resource server
+// whose clock has drifted would be.
+func (h *testHarness) skewedVerifier(t *testing.T, offset time.Duration, options ...api.AccessVerifierOption) *api.AccessVerifier[MockAttributes] {
+	t.Helper()
+
+	skewed := clock.NewManualClock(h.clock.Now().Add(offset))
+
+	return h.newVerifier(
+		t,
+		encoding.NewRfc3339WithClock(skewed),
+		storage.NewInMemoryTimeLockStoreWithClock(30*time.Second, skewed),
+		options...,
+	)
+}
+
+func expectAllowedSkew(t *testing.T, err error, allowed float64) {
+	t.Helper()
+
+	var betterAuthError *baerrors.BetterAuthError
+	if !errors.As(err, &betterAuthError) {
+		t.Fatalf("expected a better auth error, got %v", err)
+	}
+
+	actual, ok := betterAuthError.Context["allowedSkew"]
+	if allowed == 0 {
+		if ok {
+			t.Errorf("expected no allowedSkew in context, got %v", actual)
+		}
+
+		return
+	}
+
+	if actual != allowed {
+		t.Errorf("expected allowedSkew %v, got %v", allowed, actual)
+	}
+}
+
+func TestFutureTokenSkew(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	_, err := h.access(t, h.skewedVerifier(t, -5*time.Second), session)
+	expectErrorCode(t, err, "BA403")
+	expectAllowedSkew(t, err, 0)
+
+	_, err = h.access(t, h.skewedVerifier(t, -5*time.Second, api.AllowClockSkew(2*time.Second, 0)), session)
+	expectErrorCode(t, err, "BA403")
+	expectAllowedSkew(t, err, 2)
+
+	if _, err := h.access(t, h.skewedVerifier(t, -5*time.Second, api.AllowClockSkew(10*time.Second, 0)), session); err != nil {
+		t.Fatalf("expected access within the allowed skew to succeed: %v", err)
+	}
+}
+
+func TestFutureRequestSkew(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	// the token is well in the past for the lagging verifier, only the request is ahead
+	h.clock.Advance(time.Minute)
+
+	_, err := h.access(t, h.skewedVerifier(t, -5*time.Second), session)
+	expectErrorCode(t, err, "BA502")
+	expectAllowedSkew(t, err, 0)
+
+	_, err = h.access(t, h.skewedVerifier(t, -5*time.Second, api.AllowClockSkew(time.Second, 0)), session)
+	expectErrorCode(t, err, "BA502")
+	expectAllowedSkew(t, err, 1)
+
+	if _, err := h.access(t, h.skewedVerifier(t, -5*time.Second, api.AllowClockSkew(10*time.Second, 0)), session); err != nil {
+		t.Fatalf("expected access within the allowed skew to succeed: %v", err)
+	}
+}
+
+func TestExpiredTokenSkew(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	h.clock.Advance(15*time.Minute + 5*time.Second)
+
+	_, err := h.access(t, h.skewedVerifier(t, 0), session)
+	expectErrorCode(t, err, "BA401")
+	expectAllowedSkew(t, err, 0)
+
+	if _, err := h.access(t, h.skewedVerifier(t, 0, api.AllowClockSkew(0, 10*time.Second)), session); err != nil {
+		t.Fatalf("expected access within the allowed skew to succeed: %v", err)
+	}
+
+	// past skew applies to token expiry only, requests are still bounded by the nonce lifetime
+	_, err = h.access(t, h.skewedVerifier(t, 31*time.Second, api.AllowClockSkew(0, time.Hour)), session)
+	expectErrorCode(t, err, "BA501")
+}
+
+func TestRefreshExpirySkew(t *testing.T) {
+	h := newTestHarness(t, api.WithClockSkew(time.Minute))
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{}, api.WithRefreshWindow(time.Hour))
+
+	h.clock.Advance(time.Hour + 30*time.Second)
+
+	if err := h.refreshSession(t, session); err != nil {
+		t.Fatalf("expected refresh within the allowed skew to succeed: %v", err)
+	}
+
+	h.clock.Advance(time.Hour + 2*time.Minute)
+
+	err := h.refreshSession(t, session)
+	expectErrorCode(t, err, "BA401")
+	expectAllowedSkew(t, err, 60)
+}
+
+func TestFutureRequestSkewReplay(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	h.clock.Advance(time.Minute)
+
+	// the verifier lags by the full allowed skew, so the request is accepted for twice the nonce lifetime
+	lagging := clock.NewManualClock(h.clock.Now().Add(-30 * time.Second))
+	av := h.newVerifier(
+		t,
+		encoding.NewRfc3339WithClock(lagging),
+		storage.NewInMemoryTimeLockStoreWithClock(30*time.Second, lagging),
+		api.AllowClockSkew(30*time.Second, 0),
+	)
+
+	message := h.accessMessage(t, session)
+
+	if _, _, _, err := av.Verify(h.ctx, message, &MockAttributes{}); err != nil {
+		t.Fatalf("expected access within the allowed skew to succeed: %v", err)
+	}
+
+	lagging.Advance(31 * time.Second)
+
+	if _, _, _, err := av.Verify(h.ctx, message, &MockAttributes{}); !errors.Is(err, storageinterfaces.ErrReserved) {
+		t.Fatalf("expected the replayed request to be refused by its nonce, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// TenantContainer holds the stateful, per-tenant parts of a BetterAuthServer. Stateless components
+// (hashers, verifiers, noncers) may be shared between tenants' containers.
+type TenantContainer struct {
+	Crypto *CryptoContainer
+	Expiry *ExpiryContainer
+	Store  *StoresContainer
+}
+
+type TenantResolver interface {
+	Resolve(ctx context.Context, tenant string) (*TenantContainer, error)
+}
+
+type tenantContextKey struct{}
+
+// ContextWithTenant scopes ctx to tenant, e.g. when the tenant is derived from the request host.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantBetterAuthServer serves many tenants, each with its own keys, stores and expiries. The
+// tenant is taken from the context (see ContextWithTenant) or from payload.access.tenant in the
+// request envelope, which is covered by the client's signature. If both are present they must agree.
+type TenantBetterAuthServer[AttributesType any] struct {
+	encoding *EncodingContainer
+	resolver TenantResolver
+	options  []BetterAuthServerOption
+}
+
+func NewTenantBetterAuthServer[AttributesType any](
+	encoding *EncodingContainer,
+	resolver TenantResolver,
+	options ...BetterAuthServerOption,
+) *TenantBetterAuthServer[AttributesType] {
+	return &TenantBetterAuthServer[AttributesType]{
+		encoding: encoding,
+		resolver: resolver,
+		options:  options,
+	}
+}
+
+type tenantScanner struct {
+	Payload struct {
+		Access struct {
+			Tenant string `json:"tenant"`
+		} `json:"access"`
+	} `json:"payload"`
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) tenant(ctx context.Context, message string) (string, error) {
+	message, err := decodeMessage(t.encoding.MessageCodec, message)
+	if err != nil {
+		return "", err
+	}
+
+	scanner := tenantScanner{}
+	if err := json.Unmarshal([]byte(message), &scanner); err != nil {
+		return "", errors.NewInvalidMessageError("message", "malformed json")
+	}
+
+	envelopeTenant := scanner.Payload.Access.Tenant
+	contextTenant, ok := TenantFromContext(ctx)
+
+	if !ok {
+		if envelopeTenant == "" {
+			return "", errors.NewInvalidMessageError("payload.access.tenant", "tenant is required")
+		}
+
+		return envelopeTenant, nil
+	}
+
+	if envelopeTenant != "" && envelopeTenant != contextTenant {
+		return "", errors.NewMismatchedTenantError(contextTenant, envelopeTenant)
+	}
+
+	return contextTenant, nil
+}
+
+// Server returns a BetterAuthServer for tenant.
+func (t *TenantBetterAuthServer[AttributesType]) Server(ctx context.Context, tenant string) (*BetterAuthServer[AttributesType], error) {
+	container, err := t.resolver.Resolve(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	options := append([]BetterAuthServerOption{}, t.options...)
+	options = append(options, ForTenant(tenant))
+
+	return NewBetterAuthServer[AttributesType](
+		container.Crypto,
+		t.encoding,
+		container.Expiry,
+		container.Store,
+		options...,
+	), nil
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) server(ctx context.Context, message string) (*BetterAuthServer[AttributesType], error) {
+	tenant, err := t.tenant(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Server(ctx, tenant)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) contextServer(ctx context.Context) (*BetterAuthServer[AttributesType], error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, errors.NewInvalidMessageError("tenant", "tenant is required in context")
+	}
+
+	return t.Server(ctx, tenant)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) CreateAccount(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.CreateAccount(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) RecoverAccount(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.RecoverAccount(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) DeleteAccount(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.DeleteAccount(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) ChangeRecoveryKey(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.ChangeRecoveryKey(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) LinkDevice(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.LinkDevice(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) UnlinkDevice(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.UnlinkDevice(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) RotateDevice(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.RotateDevice(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) RequestSession(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.RequestSession(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) CreateSession(ctx context.Context, message string, attributes AttributesType, options ...SessionOption) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.CreateSession(ctx, message, attributes, options...)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) RefreshSession(ctx context.Context, message string) (string, error) {
+	ba, err := t.server(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return ba.RefreshSession(ctx, message)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) ListSessions(ctx context.Context, identity string) ([]storageinterfaces.Session, error) {
+	ba, err := t.contextServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ba.ListSessions(ctx, identity)
+}
+
+func (t *TenantBetterAuthServer[AttributesType]) TerminateSession(ctx context.Context, identity, session string) error {
+	ba, err := t.contextServer(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ba.TerminateSession(ctx, identity, session)
+}
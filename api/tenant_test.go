@@ -0,0 +1,232 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+type mapTenantResolver map[string]*api.TenantContainer
+
+func (r mapTenantResolver) Resolve(ctx context.Context, tenant string) (*api.TenantContainer, error) {
+	container, ok := r[tenant]
+	if !ok {
+		return nil, fmt.Errorf("unknown tenant: %s", tenant)
+	}
+
+	return container, nil
+}
+
+func newTenantContainer(t *testing.T, h *testHarness, accessKeyStore *storage.VerificationKeyStore) *api.TenantContainer {
+	t.Helper()
+
+	accessKey, err := crypto.NewSecp256r1()
+	if err != nil {
+		t.Fatalf("failed to generate access key: %v", err)
+	}
+
+	accessIdentity, err := accessKey.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive access identity: %v", err)
+	}
+
+	accessKeyStore.Add(accessIdentity, accessKey)
+
+	return &api.TenantContainer{
+		Crypto: &api.CryptoContainer{
+			Hasher: h.hasher,
+			KeyPair: &api.KeyPairContainer{
+				Access:   accessKey,
+				Response: h.serverResponseKey,
+			},
+			Noncer:   h.noncer,
+			Verifier: crypto.NewSecp256r1Verifier(),
+		},
+		Expiry: &api.ExpiryContainer{
+			Access:  15 * time.Minute,
+			Refresh: 12 * time.Hour,
+		},
+		Store: &api.StoresContainer{
+			Access: &api.AccessStoreContainer{
+				KeyHash:         storage.NewInMemoryTimeLockStore(12 * time.Hour),
+				Revocation:      storage.NewInMemoryRevocationStore(12 * time.Hour),
+				VerificationKey: accessKeyStore,
+			},
+			Authentication: &api.AuthenticationStoreContainer{
+				Key:   storage.NewInMemoryAuthenticationKeyStore(),
+				Nonce: storage.NewInMemoryAuthenticationNonceStore(time.Minute),
+			},
+			Recovery: &api.RecoveryStoreContainer{
+				Hash: storage.NewInMemoryRecoveryHashStore(),
+			},
+			Session: &api.SessionStoreContainer{
+				Registry: storage.NewInMemorySessionStoreWithClock(h.clock),
+			},
+		},
+	}
+}
+
+func newTenantVerifier(h *testHarness, tenant string, accessKeyStore *storage.VerificationKeyStore, accessNonceStore storageinterfaces.TimeLockStore) *api.AccessVerifier[MockAttributes] {
+	return api.NewAccessVerifier[MockAttributes](
+		&api.VerifierCryptoContainer{
+			Verifier: crypto.NewSecp256r1Verifier(),
+		},
+		&api.VerifierEncodingContainer{
+			TokenEncoder: h.tokenEncoder,
+			Timestamper:  h.timestamper,
+		},
+		&api.VerifierStoreContainer{
+			AccessNonce: accessNonceStore,
+			AccessKey:   accessKeyStore,
+		},
+		api.RequireTenant(tenant),
+	)
+}
+
+func TestTenantIsolation(t *testing.T) {
+	h := newTestHarness(t)
+
+	// a resource server trusting both tenants' access keys must still keep their tokens apart
+	accessKeyStore := storage.NewVerificationKeyStore()
+
+	tenants := api.NewTenantBetterAuthServer[MockAttributes](
+		&api.EncodingContainer{
+			IdentityVerifier: encoding.NewMockIdentityVerifier(h.hasher),
+			Timestamper:      h.timestamper,
+			TokenEncoder:     h.tokenEncoder,
+		},
+		mapTenantResolver{
+			"a": newTenantContainer(t, h, accessKeyStore),
+			"b": newTenantContainer(t, h, accessKeyStore),
+		},
+	)
+
+	// the resource servers share a nonce store, as replicas behind one cache would
+	accessNonceStore := storage.NewInMemoryTimeLockStoreWithClock(30*time.Second, h.clock)
+	avA := newTenantVerifier(h, "a", accessKeyStore, accessNonceStore)
+	avB := newTenantVerifier(h, "b", accessKeyStore, accessNonceStore)
+
+	// tenant a is selected by the signed request envelope, tenant b by context
+	hA := *h
+	hA.server = tenants
+	hA.tenant = "a"
+
+	hB := *h
+	hB.server = tenants
+	hB.ctx = api.ContextWithTenant(context.Background(), "b")
+
+	accountA := hA.createAccount(t)
+	sessionA := hA.createSession(t, accountA, MockAttributes{})
+
+	accountB := hB.createAccount(t)
+	sessionB := hB.createSession(t, accountB, MockAttributes{})
+
+	token, err := hA.access(t, avA, sessionA)
+	if err != nil {
+		t.Fatalf("failed to access tenant a: %v", err)
+	}
+
+	if token.Tenant != "a" {
+		t.Errorf("expected tenant a, got %q", token.Tenant)
+	}
+
+	if _, err := hB.access(t, avB, sessionB); err != nil {
+		t.Fatalf("failed to access tenant b: %v", err)
+	}
+
+	// a token rejected for its tenant must not consume the request's nonce
+	message := hA.accessMessage(t, sessionA)
+	_, _, _, err = avB.Verify(h.ctx, message, &MockAttributes{})
+	expectErrorCode(t, err, "BA302")
+
+	if _, _, _, err := avA.Verify(h.ctx, message, &MockAttributes{}); err != nil {
+		t.Fatalf("expected the request to remain usable with tenant a: %v", err)
+	}
+
+	if _, err := hA.access(t, h.av, sessionA); err == nil {
+		t.Fatalf("expected an unrelated verifier to reject tenant a's token")
+	}
+
+	// tenant a's token presented to tenant b's refresh endpoint
+	message, _ = hA.refreshRequest(t, sessionA)
+	_, err = tenants.RefreshSession(api.ContextWithTenant(context.Background(), "b"), message)
+	expectErrorCode(t, err, "BA302")
+
+	if err := hA.refreshSession(t, sessionA); err != nil {
+		t.Fatalf("failed to refresh tenant a session: %v", err)
+	}
+
+	sessions, err := tenants.ListSessions(api.ContextWithTenant(context.Background(), "b"), accountA.identity)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+
+	if len(sessions) != 0 {
+		t.Errorf("expected tenant b to have no sessions for tenant a's identity")
+	}
+}
+
+func TestTenantResolution(t *testing.T) {
+	h := newTestHarness(t)
+
+	accessKeyStore := storage.NewVerificationKeyStore()
+	tenants := api.NewTenantBetterAuthServer[MockAttributes](
+		&api.EncodingContainer{
+			IdentityVerifier: encoding.NewMockIdentityVerifier(h.hasher),
+			Timestamper:      h.timestamper,
+			TokenEncoder:     h.tokenEncoder,
+		},
+		mapTenantResolver{
+			"a": newTenantContainer(t, h, accessKeyStore),
+		},
+	)
+
+	identity := h.hasher.Sum([]byte("identity"))
+
+	_, err := tenants.RequestSession(context.Background(), "{")
+	expectErrorCode(t, err, "BA101")
+
+	_, err = tenants.RequestSession(context.Background(), requestSessionMessage(t, h, identity, ""))
+	expectErrorCode(t, err, "BA101")
+
+	_, err = tenants.RequestSession(api.ContextWithTenant(context.Background(), "b"), requestSessionMessage(t, h, identity, "a"))
+	expectErrorCode(t, err, "BA302")
+
+	if _, err := tenants.RequestSession(context.Background(), requestSessionMessage(t, h, identity, "c")); err == nil {
+		t.Fatalf("expected unknown tenant to fail")
+	}
+
+	if _, err := tenants.RequestSession(api.ContextWithTenant(context.Background(), "a"), requestSessionMessage(t, h, identity, "a")); err != nil {
+		t.Fatalf("failed to request session: %v", err)
+	}
+}
+
+func requestSessionMessage(t *testing.T, h *testHarness, identity, tenant string) string {
+	t.Helper()
+
+	request := messages.NewRequestSessionRequest(
+		messages.RequestSessionRequestPayload{
+			Authentication: messages.RequestSessionRequestAuthentication{
+				Identity: identity,
+			},
+		},
+		h.newNonce(t),
+	)
+
+	request.Payload.Access.Tenant = tenant
+
+	message, err := request.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize request session request: %v", err)
+	}
+
+	return message
+}
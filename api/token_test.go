@@ -33,6 +33,10 @@ func TestTokenParsing(t *testing.T) {
 		tempToken.Expiry,
 		tempToken.RefreshExpiry,
 		tempToken.Attributes,
+		messages.WithSessionExpiry(tempToken.SessionExpiry),
+		messages.WithAuthenticatedAt(tempToken.AuthenticatedAt),
+		messages.WithSession(tempToken.Session),
+		messages.WithTenant(tempToken.Tenant),
 	)
 
 	if err := newToken.Sign(tempKey); err != nil {
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+// Protocol handles the messages of one protocol version. BetterAuthServer and TenantBetterAuthServer
+// both implement it.
+type Protocol[AttributesType any] interface {
+	CreateAccount(ctx context.Context, message string) (string, error)
+	RecoverAccount(ctx context.Context, message string) (string, error)
+	DeleteAccount(ctx context.Context, message string) (string, error)
+	ChangeRecoveryKey(ctx context.Context, message string) (string, error)
+	LinkDevice(ctx context.Context, message string) (string, error)
+	UnlinkDevice(ctx context.Context, message string) (string, error)
+	RotateDevice(ctx context.Context, message string) (string, error)
+	RequestSession(ctx context.Context, message string) (string, error)
+	CreateSession(ctx context.Context, message string, attributes AttributesType, options ...SessionOption) (string, error)
+	RefreshSession(ctx context.Context, message string) (string, error)
+}
+
+// VersionRouter dispatches requests on payload.access.version, so that several protocol versions
+// can be served side by side while clients migrate. Requests without a version are routed to
+// messages.DefaultProtocolVersion. Register all versions before serving requests. Messages are
+// scanned as JSON, so decode other wire formats before routing.
+type VersionRouter[AttributesType any] struct {
+	protocols map[string]Protocol[AttributesType]
+	versions  []string
+}
+
+func NewVersionRouter[AttributesType any]() *VersionRouter[AttributesType] {
+	return &VersionRouter[AttributesType]{
+		protocols: map[string]Protocol[AttributesType]{},
+	}
+}
+
+func (r *VersionRouter[AttributesType]) Register(version string, protocol Protocol[AttributesType]) {
+	if _, ok := r.protocols[version]; !ok {
+		r.versions = append(r.versions, version)
+	}
+
+	r.protocols[version] = protocol
+}
+
+func (r *VersionRouter[AttributesType]) Versions() []string {
+	return append([]string{}, r.versions...)
+}
+
+type versionScanner struct {
+	Payload struct {
+		Access messages.ClientAccess `json:"access"`
+	} `json:"payload"`
+}
+
+func (r *VersionRouter[AttributesType]) protocol(message string) (Protocol[AttributesType], error) {
+	scanner := versionScanner{}
+	if err := json.Unmarshal([]byte(message), &scanner); err != nil {
+		return nil, errors.NewInvalidMessageError("message", "malformed json")
+	}
+
+	version := scanner.Payload.Access.ProtocolVersion()
+
+	protocol, ok := r.protocols[version]
+	if !ok {
+		return nil, errors.NewUnsupportedVersionError(version, r.Versions())
+	}
+
+	return protocol, nil
+}
+
+func (r *VersionRouter[AttributesType]) CreateAccount(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.CreateAccount(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) RecoverAccount(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.RecoverAccount(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) DeleteAccount(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.DeleteAccount(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) ChangeRecoveryKey(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.ChangeRecoveryKey(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) LinkDevice(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.LinkDevice(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) UnlinkDevice(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.UnlinkDevice(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) RotateDevice(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.RotateDevice(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) RequestSession(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.RequestSession(ctx, message)
+}
+
+func (r *VersionRouter[AttributesType]) CreateSession(ctx context.Context, message string, attributes AttributesType, options ...SessionOption) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.CreateSession(ctx, message, attributes, options...)
+}
+
+func (r *VersionRouter[AttributesType]) RefreshSession(ctx context.Context, message string) (string, error) {
+	protocol, err := r.protocol(message)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.RefreshSession(ctx, message)
+}
@@ -0,0 +1,136 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+func versionedRequestSessionMessage(t *testing.T, h *testHarness, version string) string {
+	t.Helper()
+
+	request := messages.NewRequestSessionRequest(
+		messages.RequestSessionRequestPayload{
+			Authentication: messages.RequestSessionRequestAuthentication{
+				Identity: h.hasher.Sum([]byte("identity")),
+			},
+		},
+		h.newNonce(t),
+	)
+
+	request.Payload.Access.Version = version
+
+	message, err := request.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize request session request: %v", err)
+	}
+
+	return message
+}
+
+func TestProtocolVersionIsEchoed(t *testing.T) {
+	h := newTestHarness(t, api.WithProtocolVersions("1", "2"))
+
+	for _, version := range []string{"", "1", "2"} {
+		reply, err := h.ba.RequestSession(h.ctx, versionedRequestSessionMessage(t, h, version))
+		if err != nil {
+			t.Fatalf("failed to request session with version %q: %v", version, err)
+		}
+
+		response, err := messages.ParseRequestSessionResponse(reply)
+		if err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+
+		if response.Payload.Access.Version != version {
+			t.Errorf("expected response version %q, got %q", version, response.Payload.Access.Version)
+		}
+	}
+}
+
+func TestUnsupportedProtocolVersion(t *testing.T) {
+	h := newTestHarness(t)
+
+	if _, err := h.ba.RequestSession(h.ctx, versionedRequestSessionMessage(t, h, messages.DefaultProtocolVersion)); err != nil {
+		t.Fatalf("failed to request session with default version: %v", err)
+	}
+
+	_, err := h.ba.RequestSession(h.ctx, versionedRequestSessionMessage(t, h, "2"))
+	expectErrorCode(t, err, "BA101")
+}
+
+type recordingProtocol struct {
+	api.Protocol[MockAttributes]
+	name  string
+	calls *[]string
+}
+
+func (p recordingProtocol) RequestSession(ctx context.Context, message string) (string, error) {
+	*p.calls = append(*p.calls, p.name)
+	return "", nil
+}
+
+func TestVersionRouter(t *testing.T) {
+	h := newTestHarness(t)
+	calls := []string{}
+
+	router := api.NewVersionRouter[MockAttributes]()
+	router.Register("1", recordingProtocol{name: "v1", calls: &calls})
+	router.Register("2", recordingProtocol{name: "v2", calls: &calls})
+
+	for _, version := range []string{"2", "", "1"} {
+		if _, err := router.RequestSession(h.ctx, versionedRequestSessionMessage(t, h, version)); err != nil {
+			t.Fatalf("failed to route version %q: %v", version, err)
+		}
+	}
+
+	if len(calls) != 3 || calls[0] != "v2" || calls[1] != "v1" || calls[2] != "v1" {
+		t.Fatalf("unexpected routing: %v", calls)
+	}
+
+	_, err := router.RequestSession(h.ctx, versionedRequestSessionMessage(t, h, "3"))
+	expectErrorCode(t, err, "BA101")
+
+	router.Register("3", h.ba)
+	if _, err := router.RequestSession(h.ctx, versionedRequestSessionMessage(t, h, "3")); err == nil {
+		t.Fatalf("expected server without version 3 support to reject the request")
+	}
+}
+
+func TestAccessVerifierProtocolVersion(t *testing.T) {
+	h := newTestHarness(t)
+
+	account := h.createAccount(t)
+	session := h.createSession(t, account, MockAttributes{})
+
+	accessMessage := func(version string) string {
+		request := NewFakeAccessRequest(
+			FakeAccessRequestPayload{
+				Foo: "bar",
+				Bar: "foo",
+			},
+			h.timestamper,
+			session.token,
+			h.newNonce(t),
+		)
+
+		request.Payload.Access.Version = version
+
+		return h.encodeRequest(t, request, session.currentKey)
+	}
+
+	for _, version := range []string{"", messages.DefaultProtocolVersion} {
+		if _, _, _, err := h.av.Verify(h.ctx, accessMessage(version), &MockAttributes{}); err != nil {
+			t.Fatalf("failed to verify access with version %q: %v", version, err)
+		}
+	}
+
+	_, _, _, err := h.av.Verify(h.ctx, accessMessage("2"), &MockAttributes{})
+	expectErrorCode(t, err, "BA101")
+
+	if _, _, _, err := h.av.With(api.AcceptProtocolVersions("1", "2")).Verify(h.ctx, accessMessage("2"), &MockAttributes{}); err != nil {
+		t.Fatalf("failed to verify access with an accepted version: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/bench"
+)
+
+func newNode(tb testing.TB) *bench.Node {
+	tb.Helper()
+
+	node, err := bench.NewNode()
+	if err != nil {
+		tb.Fatalf("failed to build node: %v", err)
+	}
+
+	return node
+}
+
+func newClient(tb testing.TB, target bench.Target) *bench.Client {
+	tb.Helper()
+
+	client, err := bench.NewClient(context.Background(), target)
+	if err != nil {
+		tb.Fatalf("failed to build client: %v", err)
+	}
+
+	return client
+}
+
+func TestRun(t *testing.T) {
+	node := newNode(t)
+	server := httptest.NewServer(node.Handler())
+	defer server.Close()
+
+	targets := map[string]bench.Target{
+		"inprocess": bench.InProcess(node),
+		"http":      bench.HTTP(server.Client(), server.URL),
+	}
+
+	mix, err := bench.ParseMix("createAccount=1,createSession=1,refreshSession=1,access=1")
+	if err != nil {
+		t.Fatalf("failed to parse mix: %v", err)
+	}
+
+	for name, target := range targets {
+		t.Run(name, func(t *testing.T) {
+			report, err := bench.Run(context.Background(), bench.Config{
+				Target:         target,
+				Workers:        2,
+				Duration:       500 * time.Millisecond,
+				Mix:            mix,
+				AllocationRuns: 2,
+			})
+			if err != nil {
+				t.Fatalf("failed to run: %v", err)
+			}
+
+			if len(report.Results) != len(mix) {
+				t.Fatalf("expected %d results, got %d", len(mix), len(report.Results))
+			}
+
+			for _, result := range report.Results {
+				if result.Errors != 0 {
+					t.Errorf("%s: %d errors, first %v", result.Operation, result.Errors, result.FirstError)
+				}
+
+				if result.Count > 0 && (result.P50 > result.P99 || result.P99 > result.Max) {
+					t.Errorf("%s: percentiles out of order: %+v", result.Operation, result)
+				}
+
+				if result.AllocsPerOp == 0 {
+					t.Errorf("%s: expected allocations to be counted", result.Operation)
+				}
+			}
+
+			var out bytes.Buffer
+			if err := report.Write(&out); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+
+			if !strings.Contains(out.String(), "refreshSession") {
+				t.Errorf("report is missing operations:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestParseMix(t *testing.T) {
+	mix, err := bench.ParseMix(bench.DefaultMix.String())
+	if err != nil {
+		t.Fatalf("failed to parse default mix: %v", err)
+	}
+
+	if mix.String() != bench.DefaultMix.String() {
+		t.Errorf("expected %s, got %s", bench.DefaultMix, mix)
+	}
+
+	for _, invalid := range []string{"", "access", "access=-1", "access=0", "requestSession=1", "login=1"} {
+		if _, err := bench.ParseMix(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestHTTPRejection(t *testing.T) {
+	server := httptest.NewServer(newNode(t).Handler())
+	defer server.Close()
+
+	target := bench.HTTP(server.Client(), server.URL)
+	if _, err := target.Call(context.Background(), bench.RefreshSession, "{}"); !errors.Is(err, bench.ErrRejected) {
+		t.Errorf("expected a failed request to be rejected, got %v", err)
+	}
+
+	target = bench.HTTP(server.Client(), server.URL+"/missing")
+	if _, err := target.Call(context.Background(), bench.CreateAccount, "{}"); !errors.Is(err, bench.ErrRejected) {
+		t.Errorf("expected an unknown route to be rejected, got %v", err)
+	}
+}
+
+// capture records messages instead of delivering them, once armed, so that benchmarks can time the
+// server alone.
+type capture struct {
+	bench.Target
+
+	mu       sync.Mutex
+	armed    bool
+	messages []string
+}
+
+func (c *capture) Call(ctx context.Context, operation bench.Operation, message string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.armed {
+		return c.Target.Call(ctx, operation, message)
+	}
+
+	c.messages = append(c.messages, message)
+	return "", nil
+}
+
+// prepare builds n messages for operation from one client.
+func prepare(b *testing.B, node *bench.Node, operation bench.Operation, n int) []string {
+	b.Helper()
+
+	target := &capture{Target: bench.InProcess(node)}
+	client := newClient(b, target)
+	target.armed = true
+
+	for range n {
+		if err := client.Do(context.Background(), operation); err != nil {
+			b.Fatalf("failed to prepare %s: %v", operation, err)
+		}
+	}
+
+	return target.messages
+}
+
+func BenchmarkCreateAccount(b *testing.B) {
+	ctx := context.Background()
+	node := newNode(b)
+	messages := prepare(b, node, bench.CreateAccount, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := range b.N {
+		if _, err := node.Server.CreateAccount(ctx, messages[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	ctx := context.Background()
+	node := newNode(b)
+	messages := prepare(b, node, bench.Access, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := range b.N {
+		if _, err := node.Access(ctx, messages[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Sessions chain, so each request depends on the reply to the last and they can't be prepared in
+// advance. These include the client's key generation and signing.
+
+func BenchmarkCreateSession(b *testing.B) {
+	benchmarkFlow(b, bench.CreateSession)
+}
+
+func BenchmarkRefreshSession(b *testing.B) {
+	benchmarkFlow(b, bench.RefreshSession)
+}
+
+func BenchmarkAccessParallel(b *testing.B) {
+	benchmarkFlow(b, bench.Access)
+}
+
+func BenchmarkAccessHTTP(b *testing.B) {
+	node := newNode(b)
+	server := httptest.NewServer(node.Handler())
+	defer server.Close()
+
+	client := newClient(b, bench.HTTP(server.Client(), server.URL))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if err := client.Do(ctx, bench.Access); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkFlow(b *testing.B, operation bench.Operation) {
+	ctx := context.Background()
+	target := bench.InProcess(newNode(b))
+
+	b.ReportAllocs()
+
+	b.RunParallel(func(pb *testing.PB) {
+		client, err := bench.NewClient(ctx, target)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+
+		for pb.Next() {
+			if err := client.Do(ctx, operation); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/harness"
+)
+
+// Client is one virtual user with an account and an open session. It is not safe for concurrent
+// use; each worker has its own.
+type Client struct {
+	*timed
+
+	client  *harness.Client
+	account *harness.Account
+	session *harness.Session
+}
+
+// timed delivers requests to the target, accumulating the time spent waiting on it.
+type timed struct {
+	target Target
+
+	// waited is how long the last operation spent waiting on the target
+	waited time.Duration
+}
+
+func (t *timed) Call(ctx context.Context, operation Operation, message string) (string, error) {
+	began := time.Now()
+	reply, err := t.target.Call(ctx, operation, message)
+	t.waited += time.Since(began)
+
+	return reply, err
+}
+
+// NewClient creates an account and opens a session on it.
+func NewClient(ctx context.Context, target Target) (*Client, error) {
+	transport := &timed{target: target}
+
+	c := &Client{
+		timed:  transport,
+		client: harness.NewClient(transport, harness.NewKeyring(nil), encoding.NewRfc3339()),
+	}
+
+	account, err := c.client.CreateAccount(ctx, "account", "device")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account: %w", err)
+	}
+	c.account = account
+
+	if err := c.createSession(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return c, nil
+}
+
+// Do performs one operation. Creating an account creates a new one, leaving the client's in place,
+// and creating a session replaces the client's session.
+func (c *Client) Do(ctx context.Context, operation Operation) error {
+	c.waited = 0
+
+	switch operation {
+	case CreateAccount:
+		_, err := c.client.CreateAccount(ctx, "account", "device")
+		return err
+	case CreateSession:
+		return c.createSession(ctx)
+	case RefreshSession:
+		return c.client.RefreshSession(ctx, c.session)
+	case Access:
+		_, err := c.client.Access(ctx, c.session, AccessPayload{Foo: "bar", Bar: "foo"})
+		return err
+	}
+
+	return fmt.Errorf("cannot perform %s on its own", operation)
+}
+
+// createSession requests a challenge and answers it, as one operation.
+func (c *Client) createSession(ctx context.Context) error {
+	session, err := c.client.CreateSession(ctx, c.account.Device, "session")
+	if err != nil {
+		return err
+	}
+
+	c.session = session
+
+	return nil
+}
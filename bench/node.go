@@ -0,0 +1,114 @@
+// Package bench drives mixes of the authentication flows against a server, in process or over
+// HTTP, and reports throughput, latency percentiles and allocations per operation.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/harness"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+// Attributes, SessionAttributes and the payloads match examples/server.go, so that the HTTP target
+// can also drive that server.
+type Attributes = harness.Attributes
+
+var SessionAttributes = harness.DefaultSessionAttributes
+
+type AccessPayload struct {
+	Foo string `json:"foo"`
+	Bar string `json:"bar"`
+}
+
+type AccessResponsePayload struct {
+	WasFoo string `json:"wasFoo"`
+	WasBar string `json:"wasBar"`
+}
+
+// Node is a harness node that answers access requests like examples/server.go.
+type Node struct {
+	*harness.Node
+}
+
+func NewNode(options ...api.BetterAuthServerOption) (*Node, error) {
+	node, err := harness.New(
+		harness.WithSessionAttributes(SessionAttributes),
+		harness.WithServerOptions(options...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Node{Node: node}, nil
+}
+
+// Call hands the request to the node, answering access requests with Access.
+func (n *Node) Call(ctx context.Context, operation Operation, message string) (string, error) {
+	if operation == Access {
+		return n.Access(ctx, message)
+	}
+
+	return n.Node.Call(ctx, operation, message)
+}
+
+// Access verifies an access request and answers it the way the example server's /foo/bar does.
+func (n *Node) Access(ctx context.Context, message string) (string, error) {
+	requestJson, _, nonce, err := n.Verifier.Verify(ctx, message, &Attributes{})
+	if err != nil {
+		return "", err
+	}
+
+	request := &AccessPayload{}
+	if err := json.Unmarshal(requestJson, request); err != nil {
+		return "", err
+	}
+
+	serverIdentity, err := n.ResponseKey.Identity()
+	if err != nil {
+		return "", err
+	}
+
+	response := messages.NewServerResponse(
+		AccessResponsePayload{
+			WasFoo: request.Foo,
+			WasBar: request.Bar,
+		},
+		serverIdentity,
+		nonce,
+	)
+
+	if err := response.Sign(n.ResponseKey); err != nil {
+		return "", err
+	}
+
+	return response.Serialize()
+}
+
+// Handler serves the benchmarked operations on the example server's routes.
+func (n *Node) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	for operation, route := range routes {
+		mux.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+			var reply string
+
+			message, err := io.ReadAll(r.Body)
+			if err == nil {
+				reply, err = n.Call(r.Context(), operation, string(message))
+			}
+
+			if err != nil {
+				reply = errorReply
+			}
+
+			fmt.Fprintf(w, "%s", reply)
+		})
+	}
+
+	return mux
+}
@@ -0,0 +1,331 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Weight is an operation's share of a mix.
+type Weight struct {
+	Operation Operation
+	Weight    int
+}
+
+type Mix []Weight
+
+// DefaultMix is read heavy, as most traffic is access requests.
+var DefaultMix = Mix{
+	{CreateAccount, 1},
+	{CreateSession, 2},
+	{RefreshSession, 5},
+	{Access, 92},
+}
+
+// ParseMix parses a mix like "createSession=1,access=9".
+func ParseMix(value string) (Mix, error) {
+	mix := Mix{}
+	for _, term := range strings.Split(value, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(term), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected operation=weight, got %q", term)
+		}
+
+		n, err := strconv.Atoi(weight)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, weight)
+		}
+
+		mix = append(mix, Weight{Operation: Operation(name), Weight: n})
+	}
+
+	return mix, mix.validate()
+}
+
+func (m Mix) String() string {
+	terms := make([]string, len(m))
+	for i, weight := range m {
+		terms[i] = fmt.Sprintf("%s=%d", weight.Operation, weight.Weight)
+	}
+
+	return strings.Join(terms, ",")
+}
+
+func (m Mix) validate() error {
+	total := 0
+	for _, weight := range m {
+		switch weight.Operation {
+		case CreateAccount, CreateSession, RefreshSession, Access:
+		default:
+			return fmt.Errorf("unknown operation %s", weight.Operation)
+		}
+
+		total += weight.Weight
+	}
+
+	if total == 0 {
+		return fmt.Errorf("mix has no weight")
+	}
+
+	return nil
+}
+
+func (m Mix) pick(random *rand.Rand) Operation {
+	total := 0
+	for _, weight := range m {
+		total += weight.Weight
+	}
+
+	pick := random.IntN(total)
+	for _, weight := range m {
+		if pick < weight.Weight {
+			return weight.Operation
+		}
+		pick -= weight.Weight
+	}
+
+	return m[len(m)-1].Operation
+}
+
+type Config struct {
+	Target   Target
+	Workers  int
+	Duration time.Duration
+	Mix      Mix
+
+	// AllocationRuns is how many times each operation is repeated on its own to count its
+	// allocations. Zero skips the count.
+	AllocationRuns int
+}
+
+type Result struct {
+	Operation  Operation
+	Count      int
+	Errors     int
+	Throughput float64
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+
+	AllocsPerOp uint64
+	BytesPerOp  uint64
+
+	// FirstError is the first failure seen, if any.
+	FirstError error
+}
+
+type Report struct {
+	Workers int
+	Elapsed time.Duration
+	Results []Result
+}
+
+type samples struct {
+	latencies  []time.Duration
+	errors     int
+	firstError error
+}
+
+// Run has each worker perform operations drawn from the mix until the duration passes, with one
+// client, and so one account and session, per worker. Latencies only count time spent waiting on
+// the target, but an in-process target shares the CPU with the clients' key generation and signing.
+func Run(ctx context.Context, config Config) (*Report, error) {
+	if err := config.Mix.validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Workers < 1 {
+		return nil, fmt.Errorf("need at least one worker")
+	}
+
+	clients := make([]*Client, config.Workers)
+	for i := range clients {
+		client, err := NewClient(ctx, config.Target)
+		if err != nil {
+			return nil, err
+		}
+
+		clients[i] = client
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, config.Duration)
+	defer cancel()
+
+	recorded := make([]map[Operation]*samples, config.Workers)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i, client := range clients {
+		recorded[i] = map[Operation]*samples{}
+		random := rand.New(rand.NewPCG(uint64(i), uint64(start.UnixNano())))
+
+		wg.Go(func() {
+			for runCtx.Err() == nil {
+				operation := config.Mix.pick(random)
+
+				err := client.Do(runCtx, operation)
+
+				// an operation cut short by the deadline says nothing about the server
+				if err != nil && runCtx.Err() != nil {
+					break
+				}
+
+				s, ok := recorded[i][operation]
+				if !ok {
+					s = &samples{}
+					recorded[i][operation] = s
+				}
+
+				s.latencies = append(s.latencies, client.waited)
+				if err != nil {
+					s.errors++
+					if s.firstError == nil {
+						s.firstError = err
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	report := &Report{
+		Workers: config.Workers,
+		Elapsed: time.Since(start),
+	}
+
+	for _, weight := range config.Mix {
+		if weight.Weight == 0 {
+			continue
+		}
+
+		merged := &samples{}
+		for _, worker := range recorded {
+			s, ok := worker[weight.Operation]
+			if !ok {
+				continue
+			}
+
+			merged.latencies = append(merged.latencies, s.latencies...)
+			merged.errors += s.errors
+			if merged.firstError == nil {
+				merged.firstError = s.firstError
+			}
+		}
+
+		result := summarize(weight.Operation, merged, report.Elapsed)
+
+		if config.AllocationRuns > 0 {
+			allocs, bytes, err := allocations(ctx, config.Target, weight.Operation, config.AllocationRuns)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count allocations for %s: %w", weight.Operation, err)
+			}
+
+			result.AllocsPerOp = allocs
+			result.BytesPerOp = bytes
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+func summarize(operation Operation, s *samples, elapsed time.Duration) Result {
+	result := Result{
+		Operation:  operation,
+		Count:      len(s.latencies),
+		Errors:     s.errors,
+		FirstError: s.firstError,
+	}
+
+	if result.Count == 0 {
+		return result
+	}
+
+	slices.Sort(s.latencies)
+	result.Throughput = float64(result.Count) / elapsed.Seconds()
+	result.P50 = percentile(s.latencies, 0.50)
+	result.P90 = percentile(s.latencies, 0.90)
+	result.P99 = percentile(s.latencies, 0.99)
+	result.Max = s.latencies[len(s.latencies)-1]
+
+	return result
+}
+
+// percentile takes the nearest rank in sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// allocations counts what one operation allocates, running it alone so that other workers don't
+// contribute. Over HTTP the count includes the client and the server if it runs in this process.
+func allocations(ctx context.Context, target Target, operation Operation, runs int) (uint64, uint64, error) {
+	client, err := NewClient(ctx, target)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	for range runs {
+		if err := client.Do(ctx, operation); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	runtime.ReadMemStats(&after)
+
+	return (after.Mallocs - before.Mallocs) / uint64(runs), (after.TotalAlloc - before.TotalAlloc) / uint64(runs), nil
+}
+
+// Write prints the report as a table, followed by the first error of each failing operation.
+func (r *Report) Write(w io.Writer) error {
+	fmt.Fprintf(w, "%d workers for %s\n\n", r.Workers, r.Elapsed.Round(time.Millisecond))
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "operation\tcount\terrors\tops/s\tp50\tp90\tp99\tmax\tallocs/op\tB/op\t")
+
+	for _, result := range r.Results {
+		fmt.Fprintf(
+			table,
+			"%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%d\t%d\t\n",
+			result.Operation,
+			result.Count,
+			result.Errors,
+			result.Throughput,
+			result.P50.Round(time.Microsecond),
+			result.P90.Round(time.Microsecond),
+			result.P99.Round(time.Microsecond),
+			result.Max.Round(time.Microsecond),
+			result.AllocsPerOp,
+			result.BytesPerOp,
+		)
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, result := range r.Results {
+		if result.FirstError != nil {
+			fmt.Fprintf(w, "\n%s: %v\n", result.Operation, result.FirstError)
+		}
+	}
+
+	return nil
+}
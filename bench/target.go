@@ -0,0 +1,92 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jasoncolburne/better-auth-go/harness"
+)
+
+type Operation = harness.Operation
+
+const (
+	CreateAccount  = harness.CreateAccount
+	RequestSession = harness.RequestSession
+	CreateSession  = harness.CreateSession
+	RefreshSession = harness.RefreshSession
+	Access         = harness.Access
+)
+
+// routes are those of examples/server.go.
+var routes = map[Operation]string{
+	CreateAccount:  "/account/create",
+	RequestSession: "/session/request",
+	CreateSession:  "/session/create",
+	RefreshSession: "/session/refresh",
+	Access:         "/foo/bar",
+}
+
+// errorReply is what the example server answers in place of any failed request.
+const errorReply = `{"error":"an error occured"}`
+
+var ErrRejected = errors.New("request rejected")
+
+// Target delivers a serialized request and returns the serialized reply.
+type Target = harness.Transport
+
+// InProcess calls the node's server and verifier directly.
+func InProcess(node *Node) Target {
+	return node
+}
+
+type overHTTP struct {
+	client  *http.Client
+	baseURL string
+}
+
+// HTTP posts requests to the example server's routes under baseURL. The server reports failures in
+// the body rather than the status, and they are returned as ErrRejected.
+func HTTP(client *http.Client, baseURL string) Target {
+	return &overHTTP{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (t *overHTTP) Call(ctx context.Context, operation Operation, message string) (string, error) {
+	route, ok := routes[operation]
+	if !ok {
+		return "", fmt.Errorf("unknown operation %s", operation)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+route, strings.NewReader(message))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := t.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	reply, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s %s", ErrRejected, route, response.Status)
+	}
+
+	if strings.HasPrefix(string(reply), `{"error"`) {
+		return "", fmt.Errorf("%w: %s %s", ErrRejected, route, reply)
+	}
+
+	return string(reply), nil
+}
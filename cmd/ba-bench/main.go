@@ -0,0 +1,87 @@
+// Command ba-bench drives a mix of account creation, session creation, refresh and access
+// verification against a server and reports throughput, latency percentiles and allocations.
+//
+// By default the server runs in this process and is called directly. With -transport http it is
+// called through its HTTP handlers, and with -url the requests go to a running examples/server.go.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/bench"
+)
+
+func main() {
+	duration := flag.Duration("duration", 10*time.Second, "how long to drive load for")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "concurrent clients")
+	mixFlag := flag.String("mix", bench.DefaultMix.String(), "operation weights")
+	transport := flag.String("transport", "inprocess", "inprocess or http")
+	url := flag.String("url", "", "base URL of a running server, instead of one in this process")
+	allocationRuns := flag.Int("allocations", 200, "runs per operation when counting allocations, 0 to skip")
+	flag.Parse()
+
+	if err := run(*duration, *workers, *mixFlag, *transport, *url, *allocationRuns); err != nil {
+		fmt.Fprintf(os.Stderr, "ba-bench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(duration time.Duration, workers int, mixFlag, transport, url string, allocationRuns int) error {
+	mix, err := bench.ParseMix(mixFlag)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: workers,
+		},
+	}
+
+	var target bench.Target
+	switch {
+	case url != "":
+		target = bench.HTTP(client, url)
+		// allocations on this side alone would mislead
+		allocationRuns = 0
+	case transport == "http":
+		node, err := bench.NewNode()
+		if err != nil {
+			return err
+		}
+
+		server := httptest.NewServer(node.Handler())
+		defer server.Close()
+
+		target = bench.HTTP(client, server.URL)
+	case transport == "inprocess":
+		node, err := bench.NewNode()
+		if err != nil {
+			return err
+		}
+
+		target = bench.InProcess(node)
+	default:
+		return fmt.Errorf("unknown transport %s", transport)
+	}
+
+	report, err := bench.Run(context.Background(), bench.Config{
+		Target:         target,
+		Workers:        workers,
+		Duration:       duration,
+		Mix:            mix,
+		AllocationRuns: allocationRuns,
+	})
+	if err != nil {
+		return err
+	}
+
+	return report.Write(os.Stdout)
+}
@@ -0,0 +1,31 @@
+// Command testvectors regenerates the protocol test vectors in testvectors/testdata.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jasoncolburne/better-auth-go/testvectors"
+)
+
+func main() {
+	out := flag.String("out", "testvectors/testdata", "directory to write the fixtures to")
+	flag.Parse()
+
+	corpus, err := testvectors.Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate test vectors: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+
+	if err := corpus.Write(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write test vectors: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,13 @@
+package clock
+
+import "time"
+
+type SystemClock struct{}
+
+func NewSystemClock() *SystemClock {
+	return &SystemClock{}
+}
+
+func (*SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,32 @@
+package crypto
+
+import (
+	"io"
+	"sync"
+
+	"github.com/zeebo/blake3"
+)
+
+// SeededEntropy is a reproducible stream of bytes expanded from a seed, for keys and nonces in tests
+// and fixtures. It is safe for concurrent use, though concurrent readers see an unpredictable split
+// of the stream. Never use it in production.
+type SeededEntropy struct {
+	mu     sync.Mutex
+	stream io.Reader
+}
+
+func NewSeededEntropy(seed []byte) *SeededEntropy {
+	hasher := blake3.New()
+	_, _ = hasher.Write(seed)
+
+	return &SeededEntropy{
+		stream: hasher.Digest(),
+	}
+}
+
+func (e *SeededEntropy) Read(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.stream.Read(p)
+}
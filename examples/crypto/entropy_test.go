@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"testing"
+)
+
+func TestSeededEntropy(t *testing.T) {
+	generate := func(seed string) (string, string, string) {
+		entropy := NewSeededEntropy([]byte(seed))
+
+		key, err := NewSecp256r1WithEntropy(entropy)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+
+		publicKey, err := key.Public()
+		if err != nil {
+			t.Fatalf("failed to derive public key: %v", err)
+		}
+
+		signature, err := key.Sign([]byte("message"))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+
+		nonce, err := NewNoncerWithEntropy(entropy).Generate128()
+		if err != nil {
+			t.Fatalf("failed to generate nonce: %v", err)
+		}
+
+		return publicKey, signature, nonce
+	}
+
+	publicKey, signature, nonce := generate("seed")
+	replayedPublicKey, replayedSignature, replayedNonce := generate("seed")
+
+	if publicKey != replayedPublicKey || signature != replayedSignature || nonce != replayedNonce {
+		t.Fatalf("expected the same seed to reproduce its key, signature and nonce")
+	}
+
+	otherPublicKey, _, otherNonce := generate("other seed")
+	if otherPublicKey == publicKey || otherNonce == nonce {
+		t.Fatalf("expected different seeds to diverge")
+	}
+
+	if err := NewSecp256r1Verifier().Verify(signature, publicKey, []byte("message")); err != nil {
+		t.Fatalf("failed to verify signature: %v", err)
+	}
+}
+
+func TestEntropyExhaustion(t *testing.T) {
+	// all zeros never yields a valid scalar
+	if _, err := NewSecp256r1WithEntropy(zeros{}); err == nil {
+		t.Fatalf("expected a degenerate entropy source to be refused")
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -3,18 +3,27 @@ package crypto
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"io"
 )
 
-type Noncer struct{}
+type Noncer struct {
+	entropy io.Reader
+}
 
 func NewNoncer() *Noncer {
-	return &Noncer{}
+	return NewNoncerWithEntropy(rand.Reader)
+}
+
+func NewNoncerWithEntropy(entropy io.Reader) *Noncer {
+	return &Noncer{
+		entropy: entropy,
+	}
 }
 
-func (*Noncer) Generate128() (string, error) {
+func (n *Noncer) Generate128() (string, error) {
 	entropy := [18]byte{}
 
-	_, err := rand.Read(entropy[2:])
+	_, err := io.ReadFull(n.entropy, entropy[2:])
 	if err != nil {
 		return "", err
 	}
@@ -9,11 +9,17 @@ import (
 	"encoding/asn1"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"math/big"
+	"strings"
 
+	"github.com/jasoncolburne/better-auth-go/pkg/cesr"
 	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
 )
 
+// Secp256r1 signs deterministically: without a random source the standard library derives each
+// nonce from the key and message as RFC 6979 describes, in constant time, so a message always signs
+// to the same bytes.
 type Secp256r1 struct {
 	private *ecdsa.PrivateKey
 }
@@ -24,9 +30,47 @@ func NewSecp256r1() (*Secp256r1, error) {
 		return nil, err
 	}
 
+	return newSecp256r1(keyPair), nil
+}
+
+// NewSecp256r1WithEntropy draws the private scalar from entropy, so that a seeded source generates
+// the same keys every run. The standard library ignores caller supplied randomness when generating
+// keys, hence the rejection sampling here.
+func NewSecp256r1WithEntropy(entropy io.Reader) (*Secp256r1, error) {
+	scalar := make([]byte, 32)
+
+	// a uniform source is rejected with probability 2^-32, so repeated rejection means it isn't one
+	for range 8 {
+		if _, err := io.ReadFull(entropy, scalar); err != nil {
+			return nil, err
+		}
+
+		// zero, or not less than the group order
+		keyPair, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), scalar)
+		if err != nil {
+			continue
+		}
+
+		return newSecp256r1(keyPair), nil
+	}
+
+	return nil, fmt.Errorf("entropy source produced no valid scalar")
+}
+
+// NewSecp256r1FromScalar builds a key from its 32 byte big-endian private scalar.
+func NewSecp256r1FromScalar(scalar []byte) (*Secp256r1, error) {
+	keyPair, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), scalar)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecp256r1(keyPair), nil
+}
+
+func newSecp256r1(private *ecdsa.PrivateKey) *Secp256r1 {
 	return &Secp256r1{
-		private: keyPair,
-	}, nil
+		private: private,
+	}
 }
 
 func (k *Secp256r1) Verifier() cryptointerfaces.Verifier {
@@ -89,13 +133,7 @@ type Secp256r1Signature struct {
 func (k *Secp256r1) Sign(message []byte) (string, error) {
 	hash := sha256.Sum256(message)
 
-	asn1Signature, err := k.private.Sign(nil, hash[:], crypto.SHA256)
-	if err != nil {
-		return "", err
-	}
-
-	signature := Secp256r1Signature{}
-	_, err = asn1.Unmarshal(asn1Signature, &signature)
+	signature, err := k.sign(hash[:])
 	if err != nil {
 		return "", err
 	}
@@ -112,6 +150,20 @@ func (k *Secp256r1) Sign(message []byte) (string, error) {
 	return string(runes), nil
 }
 
+func (k *Secp256r1) sign(hash []byte) (Secp256r1Signature, error) {
+	asn1Signature, err := k.private.Sign(nil, hash, crypto.SHA256)
+	if err != nil {
+		return Secp256r1Signature{}, err
+	}
+
+	signature := Secp256r1Signature{}
+	if _, err := asn1.Unmarshal(asn1Signature, &signature); err != nil {
+		return Secp256r1Signature{}, err
+	}
+
+	return signature, nil
+}
+
 type Secp256r1Verifier struct {
 }
 
@@ -120,37 +172,69 @@ func NewSecp256r1Verifier() *Secp256r1Verifier {
 }
 
 func (v *Secp256r1Verifier) Verify(signature, publicKey string, message []byte) error {
-	publicKeyBytes, err := base64.URLEncoding.DecodeString(publicKey[4:])
+	cryptoKey, err := parseSecp256r1PublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	r, s, err := parseSecp256r1Signature(signature)
 	if err != nil {
 		return err
 	}
 
+	hash := sha256.Sum256(message)
+	if !ecdsa.Verify(cryptoKey, hash[:], r, s) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// parseSecp256r1PublicKey decodes a CESR compressed secp256r1 public key, which must be on the curve.
+func parseSecp256r1PublicKey(publicKey string) (*ecdsa.PublicKey, error) {
+	if err := cesr.Validate(publicKey, cesr.PublicKey); err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	if code := publicKey[:4]; code != "1AAI" && code != "1AAJ" {
+		return nil, fmt.Errorf("invalid public key: not secp256r1")
+	}
+
+	publicKeyBytes, err := base64.URLEncoding.DecodeString(publicKey[4:])
+	if err != nil {
+		return nil, err
+	}
+
 	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), publicKeyBytes)
+	if x == nil {
+		return nil, fmt.Errorf("invalid public key: not a point on the curve")
+	}
+
 	uncompressedKey := [65]byte{}
 	uncompressedKey[0] = 0x04
 	x.FillBytes(uncompressedKey[1:33])
 	y.FillBytes(uncompressedKey[33:65])
 
-	cryptoKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), uncompressedKey[:])
-	if err != nil {
-		return err
+	return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), uncompressedKey[:])
+}
+
+// parseSecp256r1Signature decodes a CESR secp256r1 signature into its scalars.
+func parseSecp256r1Signature(signature string) (*big.Int, *big.Int, error) {
+	if err := cesr.Validate(signature, cesr.Signature); err != nil {
+		return nil, nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	if !strings.HasPrefix(signature, "0I") {
+		return nil, nil, fmt.Errorf("invalid signature: not secp256r1")
 	}
 
 	signatureBytes, err := base64.URLEncoding.DecodeString(signature)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	r := big.Int{}
-	s := big.Int{}
-
-	r.SetBytes(signatureBytes[2:34])
-	s.SetBytes(signatureBytes[34:66])
+	r := new(big.Int).SetBytes(signatureBytes[2:34])
+	s := new(big.Int).SetBytes(signatureBytes[34:66])
 
-	hash := sha256.Sum256(message)
-	if !ecdsa.Verify(cryptoKey, hash[:], &r, &s) {
-		return fmt.Errorf("invalid signature")
-	}
-
-	return nil
+	return r, s, nil
 }
@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRejectsMalformedInput(t *testing.T) {
+	key, err := NewSecp256r1WithEntropy(NewSeededEntropy([]byte(t.Name())))
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	publicKey, err := key.Public()
+	if err != nil {
+		t.Fatalf("failed to derive public key: %v", err)
+	}
+
+	signature, err := key.Sign([]byte("message"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		publicKey string
+	}{
+		{"empty public key", signature, ""},
+		{"short public key", signature, "1AAI"},
+		{"truncated public key", signature, publicKey[:40]},
+		{"ed25519 public key", signature, "D" + strings.Repeat("A", 43)},
+		// x = 1 has no square root on P-256
+		{"point off the curve", signature, "1AAIAgAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAB"},
+		{"bad point prefix", signature, "1AAIBQ" + publicKey[6:]},
+		{"empty signature", "", publicKey},
+		{"short signature", "0I", publicKey},
+		{"truncated signature", signature[:60], publicKey},
+		{"ed25519 signature", "0B" + signature[2:], publicKey},
+		{"invalid characters", "0I" + strings.Repeat("!", 86), publicKey},
+	}
+
+	verifier := NewSecp256r1Verifier()
+	for _, test := range tests {
+		if err := verifier.Verify(test.signature, test.publicKey, []byte("message")); err == nil {
+			t.Errorf("%s: expected verification to fail", test.name)
+		}
+	}
+
+	if err := verifier.Verify(signature, publicKey, []byte("message")); err != nil {
+		t.Fatalf("failed to verify valid signature: %v", err)
+	}
+}
+
+// TestDeterministicSignatures checks signatures against the P-256/SHA-256 vectors of RFC 6979,
+// appendix A.2.5.
+func TestDeterministicSignatures(t *testing.T) {
+	scalar, _ := hex.DecodeString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")
+
+	key, err := NewSecp256r1FromScalar(scalar)
+	if err != nil {
+		t.Fatalf("failed to build key: %v", err)
+	}
+
+	publicKey, err := key.Public()
+	if err != nil {
+		t.Fatalf("failed to derive public key: %v", err)
+	}
+
+	compressed, err := base64.URLEncoding.DecodeString(publicKey[4:])
+	if err != nil {
+		t.Fatalf("failed to decode public key: %v", err)
+	}
+
+	if x := hex.EncodeToString(compressed[1:]); x != "60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6" {
+		t.Fatalf("unexpected public key x %s", x)
+	}
+
+	for _, vector := range []struct {
+		message string
+		r       string
+		s       string
+	}{
+		{
+			"sample",
+			"efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716",
+			"f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8",
+		},
+		{
+			"test",
+			"f1abb023518351cd71d881567b1ea663ed3efcf6c5132b354f28d3b0b7d38367",
+			"019f4113742a2b14bd25926b49c649155f267e60d3814b4c0cc84250e46f0083",
+		},
+	} {
+		signature, err := key.Sign([]byte(vector.message))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+
+		if signature[:2] != "0I" {
+			t.Fatalf("unexpected signature code %s", signature[:2])
+		}
+
+		raw, err := base64.URLEncoding.DecodeString("AA" + signature[2:])
+		if err != nil {
+			t.Fatalf("failed to decode signature: %v", err)
+		}
+
+		if r := hex.EncodeToString(raw[2:34]); r != vector.r {
+			t.Errorf("%s: expected r %s, got %s", vector.message, vector.r, r)
+		}
+
+		if s := hex.EncodeToString(raw[34:66]); s != vector.s {
+			t.Errorf("%s: expected s %s, got %s", vector.message, vector.s, s)
+		}
+
+		if err := key.Verifier().Verify(signature, publicKey, []byte(vector.message)); err != nil {
+			t.Errorf("%s: failed to verify: %v", vector.message, err)
+		}
+	}
+}
+
+func FuzzVerify(f *testing.F) {
+	key, err := NewSecp256r1WithEntropy(NewSeededEntropy([]byte("FuzzVerify")))
+	if err != nil {
+		f.Fatalf("failed to generate key: %v", err)
+	}
+
+	publicKey, err := key.Public()
+	if err != nil {
+		f.Fatalf("failed to derive public key: %v", err)
+	}
+
+	signature, err := key.Sign([]byte("message"))
+	if err != nil {
+		f.Fatalf("failed to sign: %v", err)
+	}
+
+	f.Add(signature, publicKey, []byte("message"))
+	f.Add(signature, "1AAI", []byte("message"))
+	f.Add("0I", publicKey, []byte{})
+	f.Add("", "", []byte(nil))
+
+	verifier := NewSecp256r1Verifier()
+
+	// verification may fail, but must not panic on hostile input
+	f.Fuzz(func(t *testing.T, signature, publicKey string, message []byte) {
+		_ = verifier.Verify(signature, publicKey, message)
+	})
+}
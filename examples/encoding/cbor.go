@@ -0,0 +1,491 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+// CBOR is a MessageCodec for RFC 8949 CBOR, using core deterministic encoding (shortest heads,
+// preferred float serialization, bytewise sorted map keys). Numbers follow the JSON data model:
+// integral values within ±2^53 are integers, everything else is a float.
+//
+// Decoding is strict and only accepts deterministically encoded input, so every message has a
+// single encoding. Indefinite lengths, byte strings, tags and undefined are rejected.
+//
+// CBOR is a SignedEncoding: signatures cover the CBOR encoding of a payload, exactly as it appears
+// in the message, rather than its JSON.
+type CBOR struct{}
+
+func NewCBOR() *CBOR {
+	return &CBOR{}
+}
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+
+	cborFalse   = 20
+	cborTrue    = 21
+	cborNull    = 22
+	cborFloat16 = 25
+	cborFloat32 = 26
+	cborFloat64 = 27
+
+	cborMaximumDepth = 64
+	cborMaximumSafe  = 1 << 53
+)
+
+func (*CBOR) Encode(message string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(message)))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+
+	if decoder.More() {
+		return "", fmt.Errorf("unexpected data after json value")
+	}
+
+	buffer := bytes.Buffer{}
+	if err := encodeCBOR(&buffer, value); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+func (c *CBOR) EncodePayload(payload []byte) ([]byte, error) {
+	encoded, err := c.Encode(string(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(encoded), nil
+}
+
+func (*CBOR) Decode(message string) (string, error) {
+	decoder := &cborDecoder{data: []byte(message)}
+
+	buffer := bytes.Buffer{}
+	if err := decoder.decode(&buffer, 0); err != nil {
+		return "", err
+	}
+
+	if decoder.offset != len(decoder.data) {
+		return "", fmt.Errorf("unexpected data after cbor item")
+	}
+
+	canonical, err := messages.Canonicalize(buffer.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	return string(canonical), nil
+}
+
+func writeCBORHead(buffer *bytes.Buffer, major byte, argument uint64) {
+	switch {
+	case argument < 24:
+		buffer.WriteByte(major<<5 | byte(argument))
+	case argument <= math.MaxUint8:
+		buffer.WriteByte(major<<5 | 24)
+		buffer.WriteByte(byte(argument))
+	case argument <= math.MaxUint16:
+		buffer.WriteByte(major<<5 | 25)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(argument)))
+	case argument <= math.MaxUint32:
+		buffer.WriteByte(major<<5 | 26)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(argument)))
+	default:
+		buffer.WriteByte(major<<5 | 27)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, argument))
+	}
+}
+
+func encodeCBOR(buffer *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteByte(cborSimple<<5 | cborNull)
+	case bool:
+		if v {
+			buffer.WriteByte(cborSimple<<5 | cborTrue)
+		} else {
+			buffer.WriteByte(cborSimple<<5 | cborFalse)
+		}
+	case string:
+		writeCBORHead(buffer, cborText, uint64(len(v)))
+		buffer.WriteString(v)
+	case json.Number:
+		number, err := strconv.ParseFloat(string(v), 64)
+		if err != nil || math.IsInf(number, 0) {
+			return fmt.Errorf("number out of range: %s", v)
+		}
+
+		encodeCBORNumber(buffer, number)
+	case []any:
+		writeCBORHead(buffer, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCBOR(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		type entry struct {
+			key   []byte
+			value any
+		}
+
+		entries := make([]entry, 0, len(v))
+		for key, item := range v {
+			encodedKey := bytes.Buffer{}
+			writeCBORHead(&encodedKey, cborText, uint64(len(key)))
+			encodedKey.WriteString(key)
+			entries = append(entries, entry{key: encodedKey.Bytes(), value: item})
+		}
+
+		slices.SortFunc(entries, func(a, b entry) int {
+			return bytes.Compare(a.key, b.key)
+		})
+
+		writeCBORHead(buffer, cborMap, uint64(len(v)))
+		for _, entry := range entries {
+			buffer.Write(entry.key)
+			if err := encodeCBOR(buffer, entry.value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value %T", value)
+	}
+
+	return nil
+}
+
+func encodeCBORNumber(buffer *bytes.Buffer, number float64) {
+	if number == math.Trunc(number) && math.Abs(number) <= cborMaximumSafe {
+		if number >= 0 {
+			writeCBORHead(buffer, cborUnsigned, uint64(number))
+		} else {
+			writeCBORHead(buffer, cborNegative, uint64(-number)-1)
+		}
+
+		return
+	}
+
+	if float64(float32(number)) == number {
+		if half, ok := float16Bits(float32(number)); ok {
+			buffer.WriteByte(cborSimple<<5 | cborFloat16)
+			buffer.Write(binary.BigEndian.AppendUint16(nil, half))
+			return
+		}
+
+		buffer.WriteByte(cborSimple<<5 | cborFloat32)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(number))))
+		return
+	}
+
+	buffer.WriteByte(cborSimple<<5 | cborFloat64)
+	buffer.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(number)))
+}
+
+// float16Bits returns the IEEE 754 half precision encoding of a finite value, if it is exact.
+func float16Bits(value float32) (uint16, bool) {
+	bits := math.Float32bits(value)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int(bits>>23&0xff) - 127
+	mantissa := bits & 0x7fffff
+
+	if bits&0x7fffffff == 0 {
+		return sign, true
+	}
+
+	if exponent >= -14 && exponent <= 15 {
+		if mantissa&0x1fff != 0 {
+			return 0, false
+		}
+
+		return sign | uint16(exponent+15)<<10 | uint16(mantissa>>13), true
+	}
+
+	if exponent >= -24 && exponent < -14 {
+		// subnormal: value = m * 2^-24
+		full := mantissa | 0x800000
+		shift := uint(-(exponent + 1))
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+
+		return sign | uint16(full>>shift), true
+	}
+
+	return 0, false
+}
+
+func float16Value(half uint16) float64 {
+	sign := 1.0
+	if half&0x8000 != 0 {
+		sign = -1.0
+	}
+
+	exponent := int(half >> 10 & 0x1f)
+	mantissa := float64(half & 0x3ff)
+
+	switch exponent {
+	case 0:
+		return sign * math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			return math.Inf(int(sign))
+		}
+
+		return math.NaN()
+	}
+
+	return sign * math.Ldexp(mantissa+1024, exponent-25)
+}
+
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *cborDecoder) read(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)-d.offset) {
+		return nil, fmt.Errorf("unexpected end of cbor data")
+	}
+
+	data := d.data[d.offset : d.offset+int(length)]
+	d.offset += int(length)
+
+	return data, nil
+}
+
+// head reads an initial byte and argument, rejecting arguments that aren't encoded in the shortest form.
+func (d *cborDecoder) head() (byte, byte, uint64, error) {
+	initial, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	major := initial[0] >> 5
+	additional := initial[0] & 0x1f
+
+	if additional < 24 {
+		return major, additional, uint64(additional), nil
+	}
+
+	var (
+		argument uint64
+		minimum  uint64
+	)
+
+	switch additional {
+	case 24:
+		data, err := d.read(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument, minimum = uint64(data[0]), 24
+	case 25:
+		data, err := d.read(2)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument, minimum = uint64(binary.BigEndian.Uint16(data)), math.MaxUint8+1
+	case 26:
+		data, err := d.read(4)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument, minimum = uint64(binary.BigEndian.Uint32(data)), math.MaxUint16+1
+	case 27:
+		data, err := d.read(8)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument, minimum = binary.BigEndian.Uint64(data), math.MaxUint32+1
+	case 31:
+		return 0, 0, 0, fmt.Errorf("indefinite length items are not supported")
+	default:
+		return 0, 0, 0, fmt.Errorf("reserved additional information %d", additional)
+	}
+
+	// floats carry their value rather than an argument, and have their own shortest form rules
+	if major != cborSimple && argument < minimum {
+		return 0, 0, 0, fmt.Errorf("non-deterministic encoding of argument %d", argument)
+	}
+
+	return major, additional, argument, nil
+}
+
+func (d *cborDecoder) decode(buffer *bytes.Buffer, depth int) error {
+	if depth > cborMaximumDepth {
+		return fmt.Errorf("cbor nesting too deep")
+	}
+
+	start := d.offset
+	major, additional, argument, err := d.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if argument > cborMaximumSafe {
+			return fmt.Errorf("integer out of range")
+		}
+
+		buffer.WriteString(strconv.FormatUint(argument, 10))
+	case cborNegative:
+		if argument >= cborMaximumSafe {
+			return fmt.Errorf("integer out of range")
+		}
+
+		buffer.WriteString("-" + strconv.FormatUint(argument+1, 10))
+	case cborText:
+		text, err := d.text(argument)
+		if err != nil {
+			return err
+		}
+
+		writeJSONString(buffer, text)
+	case cborArray:
+		if argument > uint64(len(d.data)-d.offset) {
+			return fmt.Errorf("unexpected end of cbor data")
+		}
+
+		buffer.WriteByte('[')
+		for i := uint64(0); i < argument; i++ {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			if err := d.decode(buffer, depth+1); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case cborMap:
+		if argument > uint64(len(d.data)-d.offset) {
+			return fmt.Errorf("unexpected end of cbor data")
+		}
+
+		var previous []byte
+
+		buffer.WriteByte('{')
+		for i := uint64(0); i < argument; i++ {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			keyStart := d.offset
+			keyMajor, _, length, err := d.head()
+			if err != nil {
+				return err
+			}
+
+			if keyMajor != cborText {
+				return fmt.Errorf("map keys must be text strings")
+			}
+
+			key, err := d.text(length)
+			if err != nil {
+				return err
+			}
+
+			encodedKey := d.data[keyStart:d.offset]
+			if previous != nil && bytes.Compare(previous, encodedKey) >= 0 {
+				return fmt.Errorf("map keys must be unique and sorted")
+			}
+			previous = encodedKey
+
+			writeJSONString(buffer, key)
+			buffer.WriteByte(':')
+
+			if err := d.decode(buffer, depth+1); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case cborSimple:
+		return d.simple(buffer, d.data[start:d.offset], additional, argument)
+	case cborBytes:
+		return fmt.Errorf("byte strings are not supported")
+	case cborTag:
+		return fmt.Errorf("tags are not supported")
+	}
+
+	return nil
+}
+
+func (d *cborDecoder) text(length uint64) (string, error) {
+	data, err := d.read(length)
+	if err != nil {
+		return "", err
+	}
+
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("invalid utf-8 in text string")
+	}
+
+	return string(data), nil
+}
+
+func (d *cborDecoder) simple(buffer *bytes.Buffer, encoded []byte, additional byte, argument uint64) error {
+	var number float64
+
+	switch additional {
+	case cborFalse:
+		buffer.WriteString("false")
+		return nil
+	case cborTrue:
+		buffer.WriteString("true")
+		return nil
+	case cborNull:
+		buffer.WriteString("null")
+		return nil
+	case cborFloat16:
+		number = float16Value(uint16(argument))
+	case cborFloat32:
+		number = float64(math.Float32frombits(uint32(argument)))
+	case cborFloat64:
+		number = math.Float64frombits(argument)
+	default:
+		return fmt.Errorf("unsupported simple value %d", argument)
+	}
+
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return fmt.Errorf("non-finite numbers are not supported")
+	}
+
+	expected := bytes.Buffer{}
+	encodeCBORNumber(&expected, number)
+	if !bytes.Equal(expected.Bytes(), encoded) {
+		return fmt.Errorf("non-deterministic encoding of number")
+	}
+
+	buffer.WriteString(strconv.FormatFloat(number, 'g', -1, 64))
+
+	return nil
+}
+
+func writeJSONString(buffer *bytes.Buffer, value string) {
+	encoded, _ := json.Marshal(value)
+	buffer.Write(encoded)
+}
@@ -0,0 +1,51 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// CBORTokenEncoder encodes token bodies as base64url deterministic CBOR. CBOR is already compact,
+// so unlike TokenEncoder the body isn't compressed. The signature covers the CBOR body itself rather
+// than its JSON.
+type CBORTokenEncoder[AttributesType any] struct {
+	codec       *CBOR
+	maximumSize int
+}
+
+func NewCBORTokenEncoder[AttributesType any]() *CBORTokenEncoder[AttributesType] {
+	return &CBORTokenEncoder[AttributesType]{
+		codec:       NewCBOR(),
+		maximumSize: DefaultMaximumTokenSize,
+	}
+}
+
+func (e *CBORTokenEncoder[AttributesType]) Encode(object string) (string, error) {
+	encoded, err := e.codec.Encode(object)
+	if err != nil {
+		return "", err
+	}
+
+	if len(encoded) > e.maximumSize {
+		return "", fmt.Errorf("token exceeds %d bytes", e.maximumSize)
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(encoded)), nil
+}
+
+func (e *CBORTokenEncoder[AttributesType]) EncodePayload(payload []byte) ([]byte, error) {
+	return e.codec.EncodePayload(payload)
+}
+
+func (e *CBORTokenEncoder[AttributesType]) Decode(token string) (string, error) {
+	encoded, err := decodeBase64(token, e.maximumSize)
+	if err != nil {
+		return "", err
+	}
+
+	return e.codec.Decode(string(encoded))
+}
+
+func (*CBORTokenEncoder[AttributesType]) SignatureLength(token string) (int, error) {
+	return signatureLength(token)
+}
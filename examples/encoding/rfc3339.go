@@ -1,24 +1,122 @@
 package encoding
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+)
 
 // RFC3339 format with millisecond precision (3 digits)
 const ConsistentMilli = `2006-01-02T15:04:05.000Z`
 
-type Rfc3339 struct{}
+// RFC3339 format with nanosecond precision (9 digits)
+const ConsistentNano = `2006-01-02T15:04:05.000000000Z`
+
+type Rfc3339 struct {
+	clock clockinterfaces.Clock
+}
 
 func NewRfc3339() *Rfc3339 {
-	return &Rfc3339{}
+	return NewRfc3339WithClock(clock.NewSystemClock())
+}
+
+func NewRfc3339WithClock(clock clockinterfaces.Clock) *Rfc3339 {
+	return &Rfc3339{
+		clock: clock,
+	}
 }
 
 func (*Rfc3339) Format(when time.Time) string {
-	return when.Format(ConsistentMilli)
+	return when.UTC().Format(ConsistentMilli)
 }
 
 func (*Rfc3339) Parse(when string) (time.Time, error) {
-	return time.Parse(ConsistentMilli, when)
+	return parseTimestamp(when)
+}
+
+func (r *Rfc3339) Now() time.Time {
+	return r.clock.Now().UTC()
+}
+
+type Rfc3339Nano struct {
+	clock clockinterfaces.Clock
 }
 
-func (*Rfc3339) Now() time.Time {
-	return time.Now().UTC()
+func NewRfc3339Nano() *Rfc3339Nano {
+	return NewRfc3339NanoWithClock(clock.NewSystemClock())
+}
+
+func NewRfc3339NanoWithClock(clock clockinterfaces.Clock) *Rfc3339Nano {
+	return &Rfc3339Nano{
+		clock: clock,
+	}
+}
+
+func (*Rfc3339Nano) Format(when time.Time) string {
+	return when.UTC().Format(ConsistentNano)
+}
+
+func (*Rfc3339Nano) Parse(when string) (time.Time, error) {
+	return parseTimestamp(when)
+}
+
+func (r *Rfc3339Nano) Now() time.Time {
+	return r.clock.Now().UTC()
+}
+
+// UnixMillis formats timestamps as decimal milliseconds since the Unix epoch.
+type UnixMillis struct {
+	clock clockinterfaces.Clock
+}
+
+func NewUnixMillis() *UnixMillis {
+	return NewUnixMillisWithClock(clock.NewSystemClock())
+}
+
+func NewUnixMillisWithClock(clock clockinterfaces.Clock) *UnixMillis {
+	return &UnixMillis{
+		clock: clock,
+	}
+}
+
+func (*UnixMillis) Format(when time.Time) string {
+	return strconv.FormatInt(when.UnixMilli(), 10)
+}
+
+func (*UnixMillis) Parse(when string) (time.Time, error) {
+	return parseTimestamp(when)
+}
+
+func (u *UnixMillis) Now() time.Time {
+	return u.clock.Now().UTC().Truncate(time.Millisecond)
+}
+
+// parseTimestamp accepts the variants produced by other implementations: RFC 3339 with any
+// fractional precision, numeric offsets, lowercase designators or a space separator, and decimal
+// Unix epoch milliseconds.
+func parseTimestamp(when string) (time.Time, error) {
+	if when != "" && strings.Trim(when, "-0123456789") == "" {
+		millis, err := strconv.ParseInt(when, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return time.UnixMilli(millis).UTC(), nil
+	}
+
+	normalized := strings.ToUpper(when)
+	if len(normalized) > 10 && normalized[10] == ' ' {
+		normalized = normalized[:10] + "T" + normalized[11:]
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, normalized)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", when, err)
+	}
+
+	return parsed.UTC(), nil
 }
@@ -0,0 +1,79 @@
+package encoding_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+)
+
+func TestTimestampers(t *testing.T) {
+	when := time.Date(2025, 10, 19, 12, 34, 56, 123456789, time.UTC)
+	manual := clock.NewManualClock(when)
+
+	tests := []struct {
+		timestamper encodinginterfaces.Timestamper
+		formatted   string
+		precision   time.Duration
+	}{
+		{encoding.NewRfc3339WithClock(manual), "2025-10-19T12:34:56.123Z", time.Millisecond},
+		{encoding.NewRfc3339NanoWithClock(manual), "2025-10-19T12:34:56.123456789Z", time.Nanosecond},
+		{encoding.NewUnixMillisWithClock(manual), "1760877296123", time.Millisecond},
+	}
+
+	for _, test := range tests {
+		formatted := test.timestamper.Format(test.timestamper.Now())
+		if formatted != test.formatted {
+			t.Errorf("expected %s, got %s", test.formatted, formatted)
+		}
+
+		parsed, err := test.timestamper.Parse(formatted)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", formatted, err)
+		}
+
+		if !parsed.Equal(when.Truncate(test.precision)) {
+			t.Errorf("expected %s to parse as %s, got %s", formatted, when.Truncate(test.precision), parsed)
+		}
+	}
+
+	manual.Advance(time.Hour)
+	if !tests[0].timestamper.Now().Equal(when.Add(time.Hour)) {
+		t.Errorf("expected timestamper to follow the clock")
+	}
+}
+
+func TestLenientTimestampParsing(t *testing.T) {
+	expected := time.Date(2025, 10, 19, 12, 34, 56, 120000000, time.UTC)
+	timestamper := encoding.NewRfc3339()
+
+	for _, variant := range []string{
+		"2025-10-19T12:34:56.12Z",
+		"2025-10-19T12:34:56.120Z",
+		"2025-10-19T12:34:56.120000Z",
+		"2025-10-19T12:34:56.120000000Z",
+		"2025-10-19t12:34:56.12z",
+		"2025-10-19 12:34:56.12Z",
+		"2025-10-19T14:34:56.12+02:00",
+		"2025-10-19T12:34:56.12+00:00",
+		"1760877296120",
+	} {
+		parsed, err := timestamper.Parse(variant)
+		if err != nil {
+			t.Errorf("failed to parse %s: %v", variant, err)
+			continue
+		}
+
+		if !parsed.Equal(expected) || parsed.Location() != time.UTC {
+			t.Errorf("expected %s to parse as %s, got %s", variant, expected, parsed)
+		}
+	}
+
+	for _, invalid := range []string{"", "yesterday", "2025-10-19", "12:34:56", "-"} {
+		if _, err := timestamper.Parse(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -6,65 +6,209 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
-	"strings"
+	"sync"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/cesr"
 )
 
-type TokenEncoder[AttributesType any] struct{}
+// DefaultMaximumTokenSize bounds the decoded size of token bodies, since tokens are untrusted input.
+const DefaultMaximumTokenSize = 64 * 1024
+
+// TokenEncoder encodes token bodies as base64url gzip. Decompression stops at the maximum size, so
+// a small malicious token can't expand into an unbounded allocation.
+type TokenEncoder[AttributesType any] struct {
+	maximumSize int
+}
 
 func NewTokenEncoder[AttributesType any]() *TokenEncoder[AttributesType] {
-	return &TokenEncoder[AttributesType]{}
+	return NewTokenEncoderWithLimit[AttributesType](DefaultMaximumTokenSize)
 }
 
-func (*TokenEncoder[AttributesType]) Encode(object string) (string, error) {
-	var compressedBuffer bytes.Buffer
-	writer, err := gzip.NewWriterLevel(&compressedBuffer, 9)
-	if err != nil {
-		return "", err
+func NewTokenEncoderWithLimit[AttributesType any](maximumSize int) *TokenEncoder[AttributesType] {
+	return &TokenEncoder[AttributesType]{
+		maximumSize: maximumSize,
 	}
+}
+
+func (e *TokenEncoder[AttributesType]) Encode(object string) (string, error) {
+	if len(object) > e.maximumSize {
+		return "", fmt.Errorf("token exceeds %d bytes", e.maximumSize)
+	}
+
+	encoder := gzipEncoders.Get().(*gzipEncoder)
+	defer gzipEncoders.Put(encoder)
 
-	if _, err := writer.Write([]byte(object)); err != nil {
+	encoder.compressed.Reset()
+	encoder.writer.Reset(&encoder.compressed)
+
+	if _, err := io.WriteString(encoder.writer, object); err != nil {
 		return "", err
 	}
 
-	if err := writer.Close(); err != nil {
+	if err := encoder.writer.Close(); err != nil {
 		return "", err
 	}
 
-	return base64.RawURLEncoding.EncodeToString(compressedBuffer.Bytes()), nil
+	return base64.RawURLEncoding.EncodeToString(encoder.compressed.Bytes()), nil
 }
 
-func (*TokenEncoder[AttributesType]) Decode(token string) (string, error) {
-	gzippedToken, err := base64.RawURLEncoding.DecodeString(token) // TODO remove magic
+func (e *TokenEncoder[AttributesType]) Decode(token string) (string, error) {
+	decoder := gzipDecoders.Get().(*gzipDecoder)
+	defer gzipDecoders.Put(decoder)
+
+	decompressed, err := decoder.decode(token, e.maximumSize)
 	if err != nil {
 		return "", err
 	}
 
-	compressedBuffer := bytes.NewBuffer(gzippedToken)
-	reader, err := gzip.NewReader(compressedBuffer)
+	return string(decompressed), nil
+}
+
+// DecodeBytes decodes token to a slice the caller owns, saving the copy Decode makes to a string.
+func (e *TokenEncoder[AttributesType]) DecodeBytes(token string) ([]byte, error) {
+	decoder := gzipDecoders.Get().(*gzipDecoder)
+	defer gzipDecoders.Put(decoder)
+
+	decompressed, err := decoder.decode(token, e.maximumSize)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	bytes, err := io.ReadAll(reader)
+	return bytes.Clone(decompressed), nil
+}
+
+func (*TokenEncoder[AttributesType]) SignatureLength(token string) (int, error) {
+	return signatureLength(token)
+}
+
+// gzip state dwarfs a token, so encoders and decoders are pooled along with their buffers rather
+// than allocated per token.
+var (
+	gzipEncoders = sync.Pool{
+		New: func() any {
+			writer, _ := gzip.NewWriterLevel(nil, gzip.BestCompression)
+			return &gzipEncoder{writer: writer}
+		},
+	}
+	gzipDecoders = sync.Pool{
+		New: func() any {
+			return &gzipDecoder{}
+		},
+	}
+)
+
+type gzipEncoder struct {
+	compressed bytes.Buffer
+	writer     *gzip.Writer
+}
+
+type gzipDecoder struct {
+	compressed   []byte
+	source       bytes.Reader
+	reader       gzip.Reader
+	limit        io.LimitedReader
+	decompressed bytes.Buffer
+}
+
+// decode returns the decompressed token, which is only valid until the decoder is reused.
+func (d *gzipDecoder) decode(token string, maximumSize int) ([]byte, error) {
+	if base64.RawURLEncoding.DecodedLen(len(token)) > maximumSize {
+		return nil, fmt.Errorf("token exceeds %d bytes", maximumSize)
+	}
+
+	compressed, err := base64.RawURLEncoding.AppendDecode(d.compressed[:0], []byte(token))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	d.compressed = compressed
+	d.source.Reset(compressed)
+
+	if err := d.reader.Reset(&d.source); err != nil {
+		return nil, err
+	}
+
+	d.limit = io.LimitedReader{R: &d.reader, N: int64(maximumSize) + 1}
+	d.decompressed.Reset()
+
+	if _, err := d.decompressed.ReadFrom(&d.limit); err != nil {
+		return nil, err
+	}
+
+	if d.decompressed.Len() > maximumSize {
+		return nil, fmt.Errorf("token exceeds %d bytes", maximumSize)
+	}
+
+	if err := d.reader.Close(); err != nil {
+		return nil, err
+	}
+
+	return d.decompressed.Bytes(), nil
+}
+
+// Base64TokenEncoder encodes token bodies as plain base64url, trading size for decoding cost.
+type Base64TokenEncoder[AttributesType any] struct {
+	maximumSize int
+}
+
+func NewBase64TokenEncoder[AttributesType any]() *Base64TokenEncoder[AttributesType] {
+	return NewBase64TokenEncoderWithLimit[AttributesType](DefaultMaximumTokenSize)
+}
+
+func NewBase64TokenEncoderWithLimit[AttributesType any](maximumSize int) *Base64TokenEncoder[AttributesType] {
+	return &Base64TokenEncoder[AttributesType]{
+		maximumSize: maximumSize,
+	}
+}
+
+func (e *Base64TokenEncoder[AttributesType]) Encode(object string) (string, error) {
+	if len(object) > e.maximumSize {
+		return "", fmt.Errorf("token exceeds %d bytes", e.maximumSize)
 	}
 
-	if err := reader.Close(); err != nil {
+	return base64.RawURLEncoding.EncodeToString([]byte(object)), nil
+}
+
+func (e *Base64TokenEncoder[AttributesType]) Decode(token string) (string, error) {
+	decoded, err := decodeBase64(token, e.maximumSize)
+	if err != nil {
 		return "", err
 	}
 
-	return string(bytes), nil
+	return string(decoded), nil
 }
 
-func (*TokenEncoder[AttributesType]) SignatureLength(token string) (int, error) {
-	if len(token) < 88 {
-		return 0, fmt.Errorf("token too short")
+func (e *Base64TokenEncoder[AttributesType]) DecodeBytes(token string) ([]byte, error) {
+	return decodeBase64(token, e.maximumSize)
+}
+
+func (*Base64TokenEncoder[AttributesType]) SignatureLength(token string) (int, error) {
+	return signatureLength(token)
+}
+
+// decodeBase64 checks the encoded length before decoding, so oversized tokens are never allocated.
+func decodeBase64(token string, maximumSize int) ([]byte, error) {
+	if base64.RawURLEncoding.DecodedLen(len(token)) > maximumSize {
+		return nil, fmt.Errorf("token exceeds %d bytes", maximumSize)
 	}
 
-	if !strings.HasPrefix(token, "0I") {
-		return 0, fmt.Errorf("invalid signature prefix")
+	return base64.RawURLEncoding.DecodeString(token)
+}
+
+// signatureLength returns the length of the CESR signature prefixing token.
+func signatureLength(token string) (int, error) {
+	primitive, err := cesr.Lookup(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid signature prefix: %w", err)
+	}
+
+	if primitive.Kind != cesr.Signature {
+		return 0, fmt.Errorf("invalid signature prefix: %s is not a signature", primitive.Description)
 	}
-	// CESR signature format is always 88 characters for secp256r1
-	return 88, nil
+
+	if len(token) < primitive.Length {
+		return 0, fmt.Errorf("token too short")
+	}
+
+	return primitive.Length, nil
 }
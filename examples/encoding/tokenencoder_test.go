@@ -0,0 +1,229 @@
+package encoding_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+)
+
+const sampleToken = `{"serverIdentity":"1AAIA","device":"E","identity":"E","attributes":{"permissionsByRole":{"admin":["read"]}}}`
+
+func tokenEncoders() map[string]encodinginterfaces.TokenEncoder {
+	return map[string]encodinginterfaces.TokenEncoder{
+		"gzip":   encoding.NewTokenEncoderWithLimit[any](1024),
+		"base64": encoding.NewBase64TokenEncoderWithLimit[any](1024),
+		"cbor":   encoding.NewCBORTokenEncoder[any](),
+	}
+}
+
+func TestTokenEncoderRejectsDecompressionBomb(t *testing.T) {
+	buffer := bytes.Buffer{}
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(bytes.Repeat([]byte{'a'}, 10*1024*1024)); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+
+	bomb := base64.RawURLEncoding.EncodeToString(buffer.Bytes())
+	if _, err := encoding.NewTokenEncoder[any]().Decode(bomb); err == nil {
+		t.Fatalf("expected decompression bomb to be rejected")
+	}
+}
+
+func TestTokenEncoderLimits(t *testing.T) {
+	oversized := `"` + strings.Repeat("a", 2048) + `"`
+
+	for name, encoder := range tokenEncoders() {
+		if name == "cbor" {
+			continue
+		}
+
+		if _, err := encoder.Encode(oversized); err == nil {
+			t.Errorf("%s: expected oversized token to be rejected", name)
+		}
+	}
+
+	if _, err := encoding.NewBase64TokenEncoderWithLimit[any](16).Decode(strings.Repeat("a", 64)); err == nil {
+		t.Errorf("expected oversized encoded token to be rejected")
+	}
+}
+
+func TestTokenEncoderDecodeBytes(t *testing.T) {
+	for name, encoder := range tokenEncoders() {
+		decoder, ok := encoder.(encodinginterfaces.TokenBytesDecoder)
+		if !ok {
+			continue
+		}
+
+		first, err := encoder.Encode(sampleToken)
+		if err != nil {
+			t.Fatalf("%s: failed to encode: %v", name, err)
+		}
+
+		second, err := encoder.Encode(`{"other":"token"}`)
+		if err != nil {
+			t.Fatalf("%s: failed to encode: %v", name, err)
+		}
+
+		decoded, err := decoder.DecodeBytes(first)
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", name, err)
+		}
+
+		// decoders are reused, so the first result must not share their buffers
+		if _, err := decoder.DecodeBytes(second); err != nil {
+			t.Fatalf("%s: failed to decode: %v", name, err)
+		}
+
+		if string(decoded) != sampleToken {
+			t.Errorf("%s: expected %s, got %s", name, sampleToken, decoded)
+		}
+	}
+}
+
+func TestSignatureLength(t *testing.T) {
+	encoder := encoding.NewBase64TokenEncoder[any]()
+
+	for _, prefix := range []string{"0B", "0C", "0I"} {
+		length, err := encoder.SignatureLength(prefix + strings.Repeat("A", 100))
+		if err != nil || length != 88 {
+			t.Errorf("expected %s signature length 88, got %d (%v)", prefix, length, err)
+		}
+	}
+
+	for _, token := range []string{"", "0I" + strings.Repeat("A", 80), "E" + strings.Repeat("A", 100), "0ZAAAA", "1AAI" + strings.Repeat("A", 100)} {
+		if _, err := encoder.SignatureLength(token); err == nil {
+			t.Errorf("expected %q to be rejected", token)
+		}
+	}
+}
+
+func FuzzTokenEncoderRoundTrip(f *testing.F) {
+	f.Add(sampleToken)
+	f.Add(`{}`)
+	f.Add(`{"a":[1.5,-2,null,true,"ü"]}`)
+
+	f.Fuzz(func(t *testing.T, object string) {
+		for name, encoder := range tokenEncoders() {
+			encoded, err := encoder.Encode(object)
+			if err != nil {
+				continue
+			}
+
+			decoded, err := encoder.Decode(encoded)
+			if err != nil {
+				t.Fatalf("%s: failed to decode encoded token: %v", name, err)
+			}
+
+			// cbor decodes to the canonical form of the token
+			if name != "cbor" && decoded != object {
+				t.Fatalf("%s: round trip mismatch: %q != %q", name, decoded, object)
+			}
+		}
+	})
+}
+
+func FuzzTokenEncoderDecode(f *testing.F) {
+	for _, encoder := range tokenEncoders() {
+		encoded, err := encoder.Encode(sampleToken)
+		if err != nil {
+			f.Fatalf("failed to encode seed: %v", err)
+		}
+
+		f.Add(encoded)
+	}
+
+	f.Add("")
+	f.Add("H4sIAAAAAAAA")
+
+	f.Fuzz(func(t *testing.T, token string) {
+		for name, encoder := range tokenEncoders() {
+			decoded, err := encoder.Decode(token)
+			if err == nil && len(decoded) > 2*encoding.DefaultMaximumTokenSize {
+				t.Fatalf("%s: decoded %d bytes", name, len(decoded))
+			}
+		}
+	})
+}
+
+func FuzzSignatureLength(f *testing.F) {
+	f.Add("0I" + strings.Repeat("A", 86) + "rest")
+	f.Add("1AAI")
+	f.Add("")
+
+	encoder := encoding.NewTokenEncoder[any]()
+
+	f.Fuzz(func(t *testing.T, token string) {
+		length, err := encoder.SignatureLength(token)
+		if err == nil && (length <= 0 || length > len(token)) {
+			t.Fatalf("invalid signature length %d for %d byte token", length, len(token))
+		}
+	})
+}
+
+func FuzzCBORDecode(f *testing.F) {
+	codec := encoding.NewCBOR()
+
+	for _, seed := range []string{sampleToken, `[1,2.5,"x",{"a":null}]`, `-9007199254740992`} {
+		encoded, err := codec.Encode(seed)
+		if err != nil {
+			f.Fatalf("failed to encode seed: %v", err)
+		}
+
+		f.Add([]byte(encoded))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded, err := codec.Decode(string(data))
+		if err != nil {
+			return
+		}
+
+		// decoding is strict, so anything accepted must be the unique encoding of its value
+		encoded, err := codec.Encode(decoded)
+		if err != nil {
+			t.Fatalf("failed to re-encode %s: %v", decoded, err)
+		}
+
+		if encoded != string(data) {
+			t.Fatalf("accepted non-deterministic encoding %x of %s (expected %x)", data, decoded, encoded)
+		}
+	})
+}
+
+func BenchmarkTokenEncoder(b *testing.B) {
+	for name, encoder := range tokenEncoders() {
+		encoded, err := encoder.Encode(sampleToken)
+		if err != nil {
+			b.Fatalf("%s: failed to encode: %v", name, err)
+		}
+
+		b.Run(name+"/encode", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if _, err := encoder.Encode(sampleToken); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(name+"/decode", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if _, err := encoder.Decode(encoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/eventinterfaces"
+)
+
+type LogSecurityEventEmitter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewLogSecurityEventEmitter(writer io.Writer) *LogSecurityEventEmitter {
+	return &LogSecurityEventEmitter{
+		writer: writer,
+	}
+}
+
+func (e *LogSecurityEventEmitter) Emit(ctx context.Context, event eventinterfaces.SecurityEvent) {
+	line, err := json.Marshal(map[string]any{
+		"time":     time.Now().UTC().Format(time.RFC3339Nano),
+		"kind":     event.Kind,
+		"identity": event.Identity,
+		"device":   event.Device,
+		"session":  event.Session,
+		"details":  event.Details,
+	})
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.writer.Write(append(line, '\n'))
+}
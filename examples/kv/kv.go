@@ -0,0 +1,678 @@
+// Package kv is a small embedded key-value store for single-node deployments. Data is held in
+// memory and persisted to an append-only log; each transaction is written as one checksummed
+// record, so after a crash a transaction is either entirely present or entirely absent.
+//
+// The log grows with every write. Once it has doubled since it was last compacted, and is larger
+// than the compaction threshold (DefaultCompactionThreshold, or as set with CompactAfter), the
+// write that crossed it compacts the log, rewriting it as the live data alone.
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrReadOnly = errors.New("kv: transaction is read only")
+	ErrClosed   = errors.New("kv: database closed")
+	// ErrFailed is returned once a write could not be undone, leaving the log in an unknown state.
+	ErrFailed = errors.New("kv: database failed, reopen to recover")
+	// ErrTooLarge is returned by Update for a transaction whose record would exceed the size limit.
+	ErrTooLarge = errors.New("kv: transaction too large")
+)
+
+const (
+	magic = "BAKV1\n"
+
+	opPut    = 1
+	opDelete = 2
+
+	recordHeaderLength = 8
+	maximumRecord      = 64 * 1024 * 1024
+
+	// DefaultCompactionThreshold is the log size below which the log is never compacted automatically.
+	DefaultCompactionThreshold = 16 * 1024 * 1024
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type DB struct {
+	mu      sync.RWMutex
+	path    string
+	file    *os.File
+	size    int64
+	buckets map[string]*bucket
+	noSync  bool
+	closed  bool
+	failed  bool
+
+	// compactAfter is the compaction threshold, and compactedSize the log size after the last
+	// compaction (or when opened).
+	compactAfter  int64
+	compactedSize int64
+	maximumRecord int
+}
+
+type bucket struct {
+	keys   []string
+	values map[string][]byte
+}
+
+type options struct {
+	noSync        bool
+	compactAfter  int64
+	maximumRecord int
+}
+
+type Option func(*options)
+
+// NoSync skips fsync after each transaction. Committed transactions may then be lost on power
+// failure (though never partially applied), which is acceptable for tests.
+func NoSync() Option {
+	return func(o *options) {
+		o.noSync = true
+	}
+}
+
+// CompactAfter sets the compaction threshold. Zero disables automatic compaction, leaving it to
+// explicit calls to Compact.
+func CompactAfter(size int64) Option {
+	return func(o *options) {
+		o.compactAfter = size
+	}
+}
+
+// withMaximumRecord lowers the record size limit, so tests can reach it cheaply.
+func withMaximumRecord(size int) Option {
+	return func(o *options) {
+		o.maximumRecord = size
+	}
+}
+
+// Open opens or creates the database at path, replaying its log. A torn or corrupt record ends the
+// log: it and anything after it are discarded, since a transaction is only acknowledged once its
+// record is complete and synced. A record over the size limit can't be torn, so it fails Open
+// instead, leaving the log untouched.
+func Open(path string, opts ...Option) (*DB, error) {
+	o := options{compactAfter: DefaultCompactionThreshold, maximumRecord: maximumRecord}
+	for _, option := range opts {
+		option(&o)
+	}
+
+	// a leftover compaction file was never renamed into place, so the log is authoritative
+	if err := os.Remove(compactionPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		path:          path,
+		file:          file,
+		buckets:       map[string]*bucket{},
+		noSync:        o.noSync,
+		compactAfter:  o.compactAfter,
+		maximumRecord: o.maximumRecord,
+	}
+
+	if err := db.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	db.compactedSize = db.size
+
+	return db, nil
+}
+
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return nil
+	}
+
+	db.closed = true
+
+	return db.file.Close()
+}
+
+// View runs fn in a read-only transaction.
+func (db *DB) View(fn func(tx *Tx) error) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return ErrClosed
+	}
+
+	return fn(&Tx{db: db})
+}
+
+// Update runs fn in a read-write transaction. Writes are visible to fn as they're made, and are
+// committed atomically when fn returns nil; if fn (or the commit) fails, none of them take effect.
+func (db *DB) Update(fn func(tx *Tx) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return ErrClosed
+	}
+
+	if db.failed {
+		return ErrFailed
+	}
+
+	tx := &Tx{db: db, writable: true}
+
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	if len(tx.ops) == 0 {
+		return nil
+	}
+
+	payload := tx.encode()
+	if len(payload) > db.maximumRecord {
+		tx.rollback()
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrTooLarge, len(payload), db.maximumRecord)
+	}
+
+	if err := db.append(payload); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	if db.compactAfter > 0 && db.size > db.compactAfter && db.size > 2*db.compactedSize {
+		// the transaction is committed whatever happens here, and a failed compaction leaves the
+		// log as it was, to be retried by a later write
+		_ = db.compact()
+	}
+
+	return nil
+}
+
+// Compact rewrites the log as the live data alone, discarding overwritten and deleted values. The
+// new log is synced before it replaces the old one.
+func (db *DB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return ErrClosed
+	}
+
+	if db.failed {
+		return ErrFailed
+	}
+
+	return db.compact()
+}
+
+func (db *DB) compact() error {
+	temporaryPath := compactionPath(db.path)
+	file, err := os.OpenFile(temporaryPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	size, err := db.writeCompacted(file)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if err == nil {
+		err = os.Rename(temporaryPath, db.path)
+	}
+
+	if err != nil {
+		file.Close()
+		os.Remove(temporaryPath)
+		return err
+	}
+
+	syncDirectory(db.path)
+
+	db.file.Close()
+	db.file = file
+	db.size = size
+	db.compactedSize = size
+
+	return nil
+}
+
+// writeCompacted writes the live data to file as a log, in records no larger than the record limit.
+// Each value was committed in a record within the limit, so each fits in a record of its own.
+func (db *DB) writeCompacted(file *os.File) (int64, error) {
+	writer := bufio.NewWriter(file)
+
+	if _, err := writer.WriteString(magic); err != nil {
+		return 0, err
+	}
+
+	size := int64(len(magic))
+	tx := &Tx{db: db}
+	var payload []byte
+
+	flush := func() error {
+		if len(payload) == 0 {
+			return nil
+		}
+
+		record := frame(payload)
+		if _, err := writer.Write(record); err != nil {
+			return err
+		}
+
+		size += int64(len(record))
+		payload = payload[:0]
+
+		return nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(db.buckets)) {
+		b := db.buckets[name]
+		for _, key := range b.keys {
+			tx.ops = append(tx.ops[:0], op{kind: opPut, bucket: name, key: key, value: b.values[key]})
+			encoded := tx.encode()
+
+			if len(payload)+len(encoded) > db.maximumRecord {
+				if err := flush(); err != nil {
+					return 0, err
+				}
+			}
+
+			payload = append(payload, encoded...)
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	return size, writer.Flush()
+}
+
+// Size reports the size of the log in bytes.
+func (db *DB) Size() int64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.size
+}
+
+func (db *DB) replay() error {
+	info, err := db.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() < int64(len(magic)) {
+		// new, or a crash while creating it
+		header := make([]byte, info.Size())
+		if _, err := io.ReadFull(db.file, header); err != nil || !strings.HasPrefix(magic, string(header)) {
+			return fmt.Errorf("kv: %s is not a database", db.path)
+		}
+
+		if _, err := db.file.WriteAt([]byte(magic), 0); err != nil {
+			return err
+		}
+
+		db.size = int64(len(magic))
+
+		return db.sync()
+	}
+
+	reader := bufio.NewReader(db.file)
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(reader, header); err != nil || string(header) != magic {
+		return fmt.Errorf("kv: %s is not a database", db.path)
+	}
+
+	offset := int64(len(magic))
+	for {
+		payload, err := readRecord(reader, db.maximumRecord)
+		if errors.Is(err, errRecordTooLarge) {
+			// not a torn write, which can only leave a zeroed or partial header, so keep the log
+			return fmt.Errorf("kv: %s: record at offset %d: %w", db.path, offset, err)
+		}
+
+		if err != nil {
+			break
+		}
+
+		ops, err := decode(payload)
+		if err != nil {
+			break
+		}
+
+		for _, o := range ops {
+			db.apply(o)
+		}
+
+		offset += int64(recordHeaderLength + len(payload))
+	}
+
+	if offset < info.Size() {
+		if err := db.file.Truncate(offset); err != nil {
+			return err
+		}
+
+		if err := db.sync(); err != nil {
+			return err
+		}
+	}
+
+	db.size = offset
+
+	return nil
+}
+
+func (db *DB) append(payload []byte) error {
+	record := frame(payload)
+
+	if _, err := db.file.WriteAt(record, db.size); err != nil {
+		return db.undoAppend(err)
+	}
+
+	if err := db.sync(); err != nil {
+		return db.undoAppend(err)
+	}
+
+	db.size += int64(len(record))
+
+	return nil
+}
+
+// undoAppend removes a partially written record, so later records aren't stranded behind it.
+func (db *DB) undoAppend(cause error) error {
+	if err := db.file.Truncate(db.size); err != nil {
+		db.failed = true
+		return fmt.Errorf("%w: %v", ErrFailed, cause)
+	}
+
+	return cause
+}
+
+func (db *DB) sync() error {
+	if db.noSync {
+		return nil
+	}
+
+	return db.file.Sync()
+}
+
+func (db *DB) apply(o op) {
+	b, ok := db.buckets[o.bucket]
+	if !ok {
+		if o.kind == opDelete {
+			return
+		}
+
+		b = &bucket{values: map[string][]byte{}}
+		db.buckets[o.bucket] = b
+	}
+
+	switch o.kind {
+	case opPut:
+		if _, exists := b.values[o.key]; !exists {
+			index, _ := slices.BinarySearch(b.keys, o.key)
+			b.keys = slices.Insert(b.keys, index, o.key)
+		}
+
+		b.values[o.key] = o.value
+	case opDelete:
+		if _, exists := b.values[o.key]; !exists {
+			return
+		}
+
+		index, _ := slices.BinarySearch(b.keys, o.key)
+		b.keys = slices.Delete(b.keys, index, index+1)
+		delete(b.values, o.key)
+	}
+}
+
+type op struct {
+	kind   byte
+	bucket string
+	key    string
+	value  []byte
+}
+
+type undo struct {
+	bucket  string
+	key     string
+	value   []byte
+	existed bool
+}
+
+// Tx is a transaction. It must not be used after the function it was passed to returns.
+type Tx struct {
+	db       *DB
+	writable bool
+	ops      []op
+	undos    []undo
+}
+
+// Get returns a copy of the value stored under key.
+func (tx *Tx) Get(bucketName, key string) ([]byte, bool) {
+	b, ok := tx.db.buckets[bucketName]
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := b.values[key]
+	if !ok {
+		return nil, false
+	}
+
+	return bytes.Clone(value), true
+}
+
+func (tx *Tx) Put(bucketName, key string, value []byte) error {
+	return tx.write(op{kind: opPut, bucket: bucketName, key: key, value: bytes.Clone(value)})
+}
+
+func (tx *Tx) Delete(bucketName, key string) error {
+	return tx.write(op{kind: opDelete, bucket: bucketName, key: key})
+}
+
+// Scan calls fn for each key with the given prefix, in ascending order. fn may write to the
+// bucket; keys written during the scan are not visited.
+func (tx *Tx) Scan(bucketName, prefix string, fn func(key string, value []byte) error) error {
+	b, ok := tx.db.buckets[bucketName]
+	if !ok {
+		return nil
+	}
+
+	start, _ := slices.BinarySearch(b.keys, prefix)
+	end := start
+	for end < len(b.keys) && strings.HasPrefix(b.keys[end], prefix) {
+		end++
+	}
+
+	for _, key := range slices.Clone(b.keys[start:end]) {
+		value, ok := b.values[key]
+		if !ok {
+			continue
+		}
+
+		if err := fn(key, bytes.Clone(value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tx *Tx) write(o op) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+
+	previous := undo{bucket: o.bucket, key: o.key}
+	if b, ok := tx.db.buckets[o.bucket]; ok {
+		previous.value, previous.existed = b.values[o.key]
+	}
+
+	tx.undos = append(tx.undos, previous)
+	tx.ops = append(tx.ops, o)
+	tx.db.apply(o)
+
+	return nil
+}
+
+func (tx *Tx) rollback() {
+	for i := len(tx.undos) - 1; i >= 0; i-- {
+		u := tx.undos[i]
+		if u.existed {
+			tx.db.apply(op{kind: opPut, bucket: u.bucket, key: u.key, value: u.value})
+		} else {
+			tx.db.apply(op{kind: opDelete, bucket: u.bucket, key: u.key})
+		}
+	}
+}
+
+// encode serializes the transaction's writes as a record payload: for each write, the kind, then
+// length-prefixed bucket, key and (for puts) value.
+func (tx *Tx) encode() []byte {
+	var payload []byte
+
+	for _, o := range tx.ops {
+		payload = append(payload, o.kind)
+		payload = appendField(payload, []byte(o.bucket))
+		payload = appendField(payload, []byte(o.key))
+		if o.kind == opPut {
+			payload = appendField(payload, o.value)
+		}
+	}
+
+	return payload
+}
+
+func appendField(payload, field []byte) []byte {
+	payload = binary.AppendUvarint(payload, uint64(len(field)))
+	return append(payload, field...)
+}
+
+func decode(payload []byte) ([]op, error) {
+	var ops []op
+
+	for len(payload) > 0 {
+		o := op{kind: payload[0]}
+		payload = payload[1:]
+
+		if o.kind != opPut && o.kind != opDelete {
+			return nil, fmt.Errorf("kv: unknown operation %d", o.kind)
+		}
+
+		var bucketName, key []byte
+		var err error
+
+		if bucketName, payload, err = readField(payload); err != nil {
+			return nil, err
+		}
+
+		if key, payload, err = readField(payload); err != nil {
+			return nil, err
+		}
+
+		o.bucket = string(bucketName)
+		o.key = string(key)
+
+		if o.kind == opPut {
+			if o.value, payload, err = readField(payload); err != nil {
+				return nil, err
+			}
+
+			o.value = bytes.Clone(o.value)
+		}
+
+		ops = append(ops, o)
+	}
+
+	return ops, nil
+}
+
+func readField(payload []byte) ([]byte, []byte, error) {
+	length, n := binary.Uvarint(payload)
+	if n <= 0 || length > uint64(len(payload)-n) {
+		return nil, nil, fmt.Errorf("kv: truncated field")
+	}
+
+	end := n + int(length)
+
+	return payload[n:end], payload[end:], nil
+}
+
+// frame prefixes payload with its length and CRC-32C.
+func frame(payload []byte) []byte {
+	record := make([]byte, recordHeaderLength, recordHeaderLength+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, castagnoli))
+
+	return append(record, payload...)
+}
+
+var errRecordTooLarge = errors.New("record exceeds the size limit")
+
+func readRecord(reader io.Reader, limit int) ([]byte, error) {
+	header := make([]byte, recordHeaderLength)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length == 0 {
+		return nil, fmt.Errorf("kv: invalid record length %d", length)
+	}
+
+	if int64(length) > int64(limit) {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", errRecordTooLarge, length, limit)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+
+	if crc32.Checksum(payload, castagnoli) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("kv: checksum mismatch")
+	}
+
+	return payload, nil
+}
+
+func compactionPath(path string) string {
+	return path + ".compact"
+}
+
+// syncDirectory makes a rename durable. Not every platform supports syncing a directory, so
+// failures are ignored.
+func syncDirectory(path string) {
+	directory, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+
+	directory.Sync()
+	directory.Close()
+}
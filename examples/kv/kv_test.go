@@ -0,0 +1,514 @@
+package kv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTemporary(t *testing.T) (*DB, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+
+	return db, path
+}
+
+func get(t *testing.T, db *DB, bucket, key string) (string, bool) {
+	t.Helper()
+
+	var value []byte
+	var ok bool
+
+	if err := db.View(func(tx *Tx) error {
+		value, ok = tx.Get(bucket, key)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to view: %v", err)
+	}
+
+	return string(value), ok
+}
+
+func TestPersistence(t *testing.T) {
+	db, path := openTemporary(t)
+
+	if err := db.Update(func(tx *Tx) error {
+		tx.Put("a", "1", []byte("one"))
+		tx.Put("a", "2", []byte("two"))
+		tx.Put("b", "1", []byte("uno"))
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	if err := db.Update(func(tx *Tx) error {
+		return tx.Delete("a", "1")
+	}); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	db.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	defer db.Close()
+
+	if _, ok := get(t, db, "a", "1"); ok {
+		t.Errorf("expected a/1 to have been deleted")
+	}
+
+	if value, _ := get(t, db, "a", "2"); value != "two" {
+		t.Errorf("expected a/2 to be two, got %q", value)
+	}
+
+	if value, _ := get(t, db, "b", "1"); value != "uno" {
+		t.Errorf("expected b/1 to be uno, got %q", value)
+	}
+}
+
+func TestFailedUpdateRollsBack(t *testing.T) {
+	db, path := openTemporary(t)
+
+	db.Update(func(tx *Tx) error {
+		return tx.Put("a", "kept", []byte("original"))
+	})
+
+	size := db.Size()
+	failure := errors.New("failure")
+
+	err := db.Update(func(tx *Tx) error {
+		tx.Put("a", "kept", []byte("changed"))
+		tx.Put("a", "added", []byte("added"))
+		tx.Delete("a", "kept")
+
+		if _, ok := tx.Get("a", "added"); !ok {
+			t.Errorf("expected writes to be visible within the transaction")
+		}
+
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected failure, got %v", err)
+	}
+
+	if db.Size() != size {
+		t.Errorf("expected nothing to be written")
+	}
+
+	if value, _ := get(t, db, "a", "kept"); value != "original" {
+		t.Errorf("expected kept to be restored, got %q", value)
+	}
+
+	if _, ok := get(t, db, "a", "added"); ok {
+		t.Errorf("expected added to be rolled back")
+	}
+
+	db.Close()
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	defer db.Close()
+
+	if value, _ := get(t, db, "a", "kept"); value != "original" {
+		t.Errorf("expected kept to be original after reopening, got %q", value)
+	}
+}
+
+func TestScan(t *testing.T) {
+	db, _ := openTemporary(t)
+	defer db.Close()
+
+	db.Update(func(tx *Tx) error {
+		for _, key := range []string{"b/2", "a/1", "b/1", "c/1", "b/3"} {
+			tx.Put("bucket", key, []byte(key))
+		}
+		return nil
+	})
+
+	var keys []string
+	err := db.Update(func(tx *Tx) error {
+		return tx.Scan("bucket", "b/", func(key string, value []byte) error {
+			keys = append(keys, key)
+			return tx.Delete("bucket", key)
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	if fmt.Sprint(keys) != "[b/1 b/2 b/3]" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	keys = nil
+	db.View(func(tx *Tx) error {
+		return tx.Scan("bucket", "", func(key string, value []byte) error {
+			keys = append(keys, key)
+			return nil
+		})
+	})
+
+	if fmt.Sprint(keys) != "[a/1 c/1]" {
+		t.Errorf("unexpected keys after deletion %v", keys)
+	}
+}
+
+func TestViewIsReadOnly(t *testing.T) {
+	db, _ := openTemporary(t)
+	defer db.Close()
+
+	err := db.View(func(tx *Tx) error {
+		return tx.Put("a", "b", nil)
+	})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+// writeBatches commits count transactions, each setting every key of a batch to the
+// transaction's index, and returns the log size after each commit.
+func writeBatches(t *testing.T, db *DB, count int) []int64 {
+	t.Helper()
+
+	sizes := []int64{db.Size()}
+
+	for i := range count {
+		err := db.Update(func(tx *Tx) error {
+			for key := range 5 {
+				if err := tx.Put("batch", fmt.Sprint(key), []byte(fmt.Sprint(i))); err != nil {
+					return err
+				}
+			}
+
+			return tx.Put("batch", "last", []byte(fmt.Sprint(i)))
+		})
+		if err != nil {
+			t.Fatalf("failed to update: %v", err)
+		}
+
+		sizes = append(sizes, db.Size())
+	}
+
+	return sizes
+}
+
+// expectBatches checks that exactly the first committed transactions survived, each entirely.
+func expectBatches(t *testing.T, db *DB, committed int) {
+	t.Helper()
+
+	expected := fmt.Sprint(committed - 1)
+	present := committed > 0
+
+	for _, key := range []string{"0", "1", "2", "3", "4", "last"} {
+		value, ok := get(t, db, "batch", key)
+		if ok != present || (present && value != expected) {
+			t.Fatalf("expected %d committed transactions, but %s is %q (present %v)", committed, key, value, ok)
+		}
+	}
+}
+
+func TestCrashAtEveryOffset(t *testing.T) {
+	db, path := openTemporary(t)
+	sizes := writeBatches(t, db, 4)
+	db.Close()
+
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	directory := t.TempDir()
+
+	// a crash can leave any prefix of the log on disk
+	for cut := 0; cut <= len(log); cut++ {
+		crashed := filepath.Join(directory, fmt.Sprintf("cut-%d", cut))
+		if err := os.WriteFile(crashed, log[:cut], 0o600); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+
+		committed := 0
+		for committed+1 < len(sizes) && sizes[committed+1] <= int64(cut) {
+			committed++
+		}
+
+		db, err := Open(crashed)
+		if err != nil {
+			t.Fatalf("cut %d: failed to open: %v", cut, err)
+		}
+
+		expectBatches(t, db, committed)
+
+		if db.Size() != sizes[committed] {
+			t.Fatalf("cut %d: expected the torn record to be truncated", cut)
+		}
+
+		// recovery leaves the log appendable
+		writeBatches(t, db, 1)
+		db.Close()
+
+		db, err = Open(crashed)
+		if err != nil {
+			t.Fatalf("cut %d: failed to reopen: %v", cut, err)
+		}
+
+		expectBatches(t, db, 1)
+		db.Close()
+	}
+}
+
+func TestCorruptRecord(t *testing.T) {
+	db, path := openTemporary(t)
+	sizes := writeBatches(t, db, 3)
+	db.Close()
+
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	// flip a bit in the payload of the final record
+	log[sizes[2]+recordHeaderLength+2] ^= 0x01
+	if err := os.WriteFile(path, log, 0o600); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	defer db.Close()
+
+	expectBatches(t, db, 2)
+}
+
+func TestRejectsForeignFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	if err := os.WriteFile(path, []byte("not a database"), 0o600); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Fatalf("expected a foreign file to be rejected")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	db, path := openTemporary(t)
+	writeBatches(t, db, 20)
+
+	db.Update(func(tx *Tx) error {
+		return tx.Put("other", "key", []byte("value"))
+	})
+
+	before := db.Size()
+
+	if err := db.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+
+	if db.Size() >= before {
+		t.Errorf("expected compaction to shrink the log from %d, got %d", before, db.Size())
+	}
+
+	expectBatches(t, db, 20)
+
+	// the compacted log is appendable
+	db.Update(func(tx *Tx) error {
+		return tx.Delete("other", "key")
+	})
+	db.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	defer db.Close()
+
+	expectBatches(t, db, 20)
+
+	if _, ok := get(t, db, "other", "key"); ok {
+		t.Errorf("expected the post-compaction delete to persist")
+	}
+}
+
+func TestInterruptedCompaction(t *testing.T) {
+	db, path := openTemporary(t)
+	writeBatches(t, db, 2)
+	db.Close()
+
+	// a crash before the rename leaves a partial compaction file beside the log
+	if err := os.WriteFile(compactionPath(path), []byte(magic+"garbage"), 0o600); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	defer db.Close()
+
+	expectBatches(t, db, 2)
+
+	if _, err := os.Stat(compactionPath(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the compaction file to be removed")
+	}
+}
+
+func TestUpdateRejectsOversizedTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := Open(path, withMaximumRecord(1024))
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	defer db.Close()
+
+	before := db.Size()
+
+	err = db.Update(func(tx *Tx) error {
+		return tx.Put("bucket", "key", bytes.Repeat([]byte{'x'}, 2048))
+	})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected an oversized transaction to be rejected, got %v", err)
+	}
+
+	if _, ok := get(t, db, "bucket", "key"); ok {
+		t.Errorf("expected the rejected write to be rolled back")
+	}
+
+	if db.Size() != before {
+		t.Errorf("expected nothing to be appended, log went from %d to %d bytes", before, db.Size())
+	}
+}
+
+func TestCompactSplitsRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := Open(path, withMaximumRecord(1024))
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+
+	value := bytes.Repeat([]byte{'x'}, 200)
+	for i := range 20 {
+		if err := db.Update(func(tx *Tx) error {
+			return tx.Put("bucket", fmt.Sprint(i), value)
+		}); err != nil {
+			t.Fatalf("failed to update: %v", err)
+		}
+	}
+
+	if err := db.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	db.Close()
+
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	reader := bytes.NewReader(log[len(magic):])
+	records := 0
+	for reader.Len() > 0 {
+		if _, err := readRecord(reader, 1024); err != nil {
+			t.Fatalf("record %d is unreadable within the limit: %v", records, err)
+		}
+
+		records++
+	}
+
+	if records < 4 {
+		t.Errorf("expected 4KiB of live data to be split into several records, got %d", records)
+	}
+
+	db, err = Open(path, withMaximumRecord(1024))
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	defer db.Close()
+
+	for i := range 20 {
+		if got, ok := get(t, db, "bucket", fmt.Sprint(i)); !ok || got != string(value) {
+			t.Fatalf("expected %d to survive compaction", i)
+		}
+	}
+}
+
+func TestOversizedRecordIsKept(t *testing.T) {
+	db, path := openTemporary(t)
+	if err := db.Update(func(tx *Tx) error {
+		return tx.Put("bucket", "key", bytes.Repeat([]byte{'x'}, 2048))
+	}); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	db.Close()
+
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat: %v", err)
+	}
+
+	if _, err := Open(path, withMaximumRecord(1024)); err == nil {
+		t.Fatalf("expected a record over the limit to fail opening")
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat: %v", err)
+	}
+
+	if after.Size() != before.Size() {
+		t.Fatalf("expected the log to be left alone, it went from %d to %d bytes", before.Size(), after.Size())
+	}
+}
+
+func TestAutomaticCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := Open(path, NoSync(), CompactAfter(4096))
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+
+	largest := int64(0)
+	for i := range 1000 {
+		if err := db.Update(func(tx *Tx) error {
+			return tx.Put("bucket", fmt.Sprint(i%10), []byte(fmt.Sprint(i)))
+		}); err != nil {
+			t.Fatalf("failed to update: %v", err)
+		}
+
+		largest = max(largest, db.Size())
+	}
+	db.Close()
+
+	if largest > 4096+64 {
+		t.Errorf("expected the log to be compacted past 4096 bytes, it reached %d", largest)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	defer db.Close()
+
+	for i := range 10 {
+		if value, _ := get(t, db, "bucket", fmt.Sprint(i)); value != fmt.Sprint(990+i) {
+			t.Fatalf("expected %d to be %d, got %q", i, 990+i, value)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// AuthenticationKeyStore rotates with a conditional UPDATE. Concurrent updates of a row queue on its
+// lock and re-check the condition once the holder commits, so of several rotations expecting the
+// same hash only the first matches.
+type AuthenticationKeyStore struct {
+	db *sql.DB
+}
+
+func NewAuthenticationKeyStore(db *sql.DB) *AuthenticationKeyStore {
+	return &AuthenticationKeyStore{
+		db: db,
+	}
+}
+
+func (s *AuthenticationKeyStore) Register(ctx context.Context, identity, device, publicKey, rotationHash string, existingIdentity bool) error {
+	return inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockIdentity(ctx, tx, identity, false); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO authentication_identities (identity) VALUES ($1) ON CONFLICT DO NOTHING",
+			identity,
+		); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO authentication_keys (identity, device, public_key, rotation_hash)
+			VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+			identity, device, publicKey, rotationHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		inserted, err := affected(result)
+		if err != nil {
+			return err
+		}
+
+		if !inserted {
+			return fmt.Errorf("already registered")
+		}
+
+		return nil
+	})
+}
+
+func (s *AuthenticationKeyStore) Public(ctx context.Context, identity, device string) (string, error) {
+	var publicKey string
+
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT public_key FROM authentication_keys WHERE identity = $1 AND device = $2",
+		identity, device,
+	).Scan(&publicKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", missing(ctx, s.db, identity)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return publicKey, nil
+}
+
+func (s *AuthenticationKeyStore) Rotate(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash string) error {
+	return inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockIdentity(ctx, tx, identity, false); err != nil {
+			return err
+		}
+
+		return rotate(ctx, tx, identity, device, previousRotationHash, publicKey, rotationHash)
+	})
+}
+
+func (s *AuthenticationKeyStore) Link(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash, linkedDevice, linkedPublicKey, linkedRotationHash string) error {
+	return inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockIdentity(ctx, tx, identity, false); err != nil {
+			return err
+		}
+
+		if err := rotate(ctx, tx, identity, device, previousRotationHash, publicKey, rotationHash); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO authentication_keys (identity, device, public_key, rotation_hash)
+			VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+			identity, linkedDevice, linkedPublicKey, linkedRotationHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		inserted, err := affected(result)
+		if err != nil {
+			return err
+		}
+
+		// rolls the rotation back with the insert
+		if !inserted {
+			return fmt.Errorf("already registered")
+		}
+
+		return nil
+	})
+}
+
+func rotate(ctx context.Context, tx *sql.Tx, identity, device, previousRotationHash, publicKey, rotationHash string) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE authentication_keys SET public_key = $4, rotation_hash = $5
+		WHERE identity = $1 AND device = $2 AND lower(rotation_hash) = lower($3)`,
+		identity, device, previousRotationHash, publicKey, rotationHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	rotated, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if rotated {
+		return nil
+	}
+
+	var exists bool
+
+	if err := tx.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM authentication_keys WHERE identity = $1 AND device = $2)",
+		identity, device,
+	).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return storageinterfaces.ErrRotationConflict
+	}
+
+	// on tx, which holds a connection of its own until it ends
+	return missing(ctx, tx, identity)
+}
+
+func (s *AuthenticationKeyStore) RevokeDevice(ctx context.Context, identity, device string) error {
+	return inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockIdentity(ctx, tx, identity, false); err != nil {
+			return err
+		}
+
+		if err := ensureIdentity(ctx, tx, identity); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM authentication_keys WHERE identity = $1 AND device = $2",
+			identity, device,
+		)
+
+		return err
+	})
+}
+
+func (s *AuthenticationKeyStore) RevokeDevices(ctx context.Context, identity string) error {
+	return inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockIdentity(ctx, tx, identity, true); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO authentication_identities (identity) VALUES ($1) ON CONFLICT DO NOTHING",
+			identity,
+		); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM authentication_keys WHERE identity = $1", identity)
+
+		return err
+	})
+}
+
+func (s *AuthenticationKeyStore) DeleteIdentity(ctx context.Context, identity string) error {
+	return inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockIdentity(ctx, tx, identity, true); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM authentication_identities WHERE identity = $1", identity)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := affected(result)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return fmt.Errorf("account not found")
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM authentication_keys WHERE identity = $1", identity)
+
+		return err
+	})
+}
+
+func (s *AuthenticationKeyStore) EnsureActive(ctx context.Context, identity, device string) error {
+	var exists bool
+
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM authentication_keys WHERE identity = $1 AND device = $2)",
+		identity, device,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("not found")
+	}
+
+	return nil
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// missing distinguishes an unknown account from an unknown device, as the in-memory store does.
+func missing(ctx context.Context, querier rowQuerier, identity string) error {
+	var exists bool
+
+	err := querier.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM authentication_identities WHERE identity = $1)",
+		identity,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+
+	return fmt.Errorf("device not found")
+}
+
+func ensureIdentity(ctx context.Context, tx *sql.Tx, identity string) error {
+	var exists bool
+
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM authentication_identities WHERE identity = $1)",
+		identity,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("account not found")
+	}
+
+	return nil
+}
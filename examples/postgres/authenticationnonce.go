@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+)
+
+type AuthenticationNonceStore struct {
+	db       *sql.DB
+	clock    clockinterfaces.Clock
+	lifetime time.Duration
+	noncer   cryptointerfaces.Noncer
+}
+
+func NewAuthenticationNonceStore(db *sql.DB, nonceLifetime time.Duration) *AuthenticationNonceStore {
+	return NewAuthenticationNonceStoreWithClock(db, nonceLifetime, clock.NewSystemClock())
+}
+
+func NewAuthenticationNonceStoreWithClock(db *sql.DB, nonceLifetime time.Duration, clock clockinterfaces.Clock) *AuthenticationNonceStore {
+	return &AuthenticationNonceStore{
+		db:       db,
+		clock:    clock,
+		lifetime: nonceLifetime,
+		noncer:   crypto.NewNoncer(),
+	}
+}
+
+func (s *AuthenticationNonceStore) Generate(ctx context.Context, identity string) (string, error) {
+	nonce, err := s.noncer.Generate128()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO authentication_nonces (nonce, identity, expires_at) VALUES ($1, $2, $3)",
+		nonce, identity, s.clock.Now().Add(s.lifetime),
+	); err != nil {
+		return "", err
+	}
+
+	return nonce, nil
+}
+
+func (s *AuthenticationNonceStore) Verify(ctx context.Context, nonce string) (string, error) {
+	var identity string
+
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT identity FROM authentication_nonces WHERE nonce = $1 AND expires_at > $2",
+		nonce, s.clock.Now(),
+	).Scan(&identity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("nonce not found")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return identity, nil
+}
+
+// Sweep deletes expired nonces. Run it periodically.
+func (s *AuthenticationNonceStore) Sweep(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM authentication_nonces WHERE expires_at <= $1", s.clock.Now())
+	return err
+}
@@ -0,0 +1,565 @@
+package postgres_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/postgres"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storagetest"
+)
+
+// The tests run against BETTER_AUTH_POSTGRES_DSN if set, and otherwise against a throwaway cluster
+// started with the PostgreSQL binaries on PATH. Without either they are skipped.
+
+var (
+	clusterOnce sync.Once
+	clusterDSN  string
+	clusterErr  error
+	clusterStop func()
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	if clusterStop != nil {
+		clusterStop()
+	}
+
+	os.Exit(code)
+}
+
+func startCluster() (string, func(), error) {
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+
+	directory, err := os.MkdirTemp("", "better-auth-postgres")
+	if err != nil {
+		return "", nil, err
+	}
+
+	data := filepath.Join(directory, "data")
+	if output, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust").CombinedOutput(); err != nil {
+		os.RemoveAll(directory)
+		return "", nil, fmt.Errorf("initdb: %v: %s", err, output)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(directory)
+		return "", nil, err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=''", port, directory)
+	if output, err := exec.Command(pgCtl, "-D", data, "-o", options, "-l", filepath.Join(directory, "log"), "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(directory)
+		return "", nil, fmt.Errorf("pg_ctl: %v: %s", err, output)
+	}
+
+	stop := func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(directory)
+	}
+
+	return fmt.Sprintf("host=%s port=%d user=postgres dbname=postgres sslmode=disable", directory, port), stop, nil
+}
+
+// openDatabase returns a connection whose search path is a fresh schema, so tests are isolated from
+// each other and from anything else in the database.
+func openDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("BETTER_AUTH_POSTGRES_DSN")
+	if dsn == "" {
+		clusterOnce.Do(func() {
+			clusterDSN, clusterStop, clusterErr = startCluster()
+		})
+
+		if clusterErr != nil {
+			t.Skipf("no database: set BETTER_AUTH_POSTGRES_DSN or put the PostgreSQL binaries on PATH (%v)", clusterErr)
+		}
+
+		dsn = clusterDSN
+	}
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	schema := "test_" + hex.EncodeToString(suffix)
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer admin.Close()
+
+	ctx := context.Background()
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		admin, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+
+		admin.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	db.SetMaxOpenConns(20)
+
+	if err := postgres.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return db
+}
+
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+
+		return dsn + separator + "search_path=" + schema
+	}
+
+	return dsn + " search_path=" + schema
+}
+
+// newClock starts at a whole microsecond, the database's resolution.
+func newClock() *clock.ManualClock {
+	return clock.NewManualClock(time.Now().Truncate(time.Microsecond))
+}
+
+func TestAuthenticationKeyStoreConformance(t *testing.T) {
+	storagetest.TestAuthenticationKeyStore(t, func(t *testing.T) storageinterfaces.AuthenticationKeyStore {
+		return postgres.NewAuthenticationKeyStore(openDatabase(t))
+	})
+}
+
+func TestAuthenticationNonceStoreConformance(t *testing.T) {
+	storagetest.TestAuthenticationNonceStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.AuthenticationNonceStore, storagetest.Advance) {
+		clock := newClock()
+		return postgres.NewAuthenticationNonceStoreWithClock(openDatabase(t), lifetime, clock), clock.Advance
+	})
+}
+
+func TestTimeLockStoreConformance(t *testing.T) {
+	storagetest.TestTimeLockStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.TimeLockStore, storagetest.Advance) {
+		clock := newClock()
+		return postgres.NewTimeLockStoreWithClock(openDatabase(t), "access", lifetime, clock), clock.Advance
+	})
+}
+
+func TestRevocationStoreConformance(t *testing.T) {
+	storagetest.TestRevocationStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.RevocationStore, storagetest.Advance) {
+		clock := newClock()
+		return postgres.NewRevocationStoreWithClock(openDatabase(t), lifetime, clock), clock.Advance
+	})
+}
+
+func TestRecoveryHashStoreConformance(t *testing.T) {
+	storagetest.TestRecoveryHashStore(t, func(t *testing.T) storageinterfaces.RecoveryHashStore {
+		return postgres.NewRecoveryHashStore(openDatabase(t))
+	})
+}
+
+func TestSessionStoreConformance(t *testing.T) {
+	storagetest.TestSessionStore(t, func(t *testing.T) (storageinterfaces.SessionStore, storagetest.Advance) {
+		clock := newClock()
+		return postgres.NewSessionStoreWithClock(openDatabase(t), clock), clock.Advance
+	})
+}
+
+func TestConcurrentDeleteIdentity(t *testing.T) {
+	db := openDatabase(t)
+	ctx := context.Background()
+
+	store := postgres.NewAuthenticationKeyStore(db)
+
+	for round := range 20 {
+		identity := fmt.Sprintf("identity-%d", round)
+
+		if err := store.Register(ctx, identity, "device-0", "key", "hash", false); err != nil {
+			t.Fatalf("failed to register: %v", err)
+		}
+
+		var wg sync.WaitGroup
+
+		for i := 1; i <= 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				store.Register(ctx, identity, fmt.Sprintf("device-%d", i), "key", "hash", true)
+			}()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := store.DeleteIdentity(ctx, identity); err != nil {
+				t.Errorf("failed to delete identity: %v", err)
+			}
+		}()
+
+		wg.Wait()
+	}
+
+	// a device registered while its identity was being deleted would be orphaned
+	var orphans int
+	if err := db.QueryRowContext(
+		ctx,
+		`SELECT count(*) FROM authentication_keys k
+		WHERE NOT EXISTS (SELECT 1 FROM authentication_identities i WHERE i.identity = k.identity)`,
+	).Scan(&orphans); err != nil {
+		t.Fatalf("failed to count orphans: %v", err)
+	}
+
+	if orphans != 0 {
+		t.Fatalf("expected no orphaned devices, found %d", orphans)
+	}
+}
+
+func TestAuthenticationKeyStore(t *testing.T) {
+	db := openDatabase(t)
+	hasher := crypto.NewBlake3()
+	ctx := context.Background()
+
+	store := postgres.NewAuthenticationKeyStore(db)
+
+	if _, err := store.Public(ctx, "identity", "device"); err == nil || err.Error() != "account not found" {
+		t.Fatalf("expected account not found, got %v", err)
+	}
+
+	if err := store.Register(ctx, "identity", "device", "key", hasher.Sum([]byte("next")), false); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if err := store.Register(ctx, "identity", "device", "key", "hash", true); err == nil {
+		t.Fatalf("expected duplicate registration to fail")
+	}
+
+	if _, err := store.Public(ctx, "identity", "other"); err == nil || err.Error() != "device not found" {
+		t.Fatalf("expected device not found, got %v", err)
+	}
+
+	if err := store.Rotate(ctx, "identity", "device", hasher.Sum([]byte("wrong")), "wrong", "hash"); !errors.Is(err, storageinterfaces.ErrRotationConflict) {
+		t.Fatalf("expected rotation with the wrong key to conflict, got %v", err)
+	}
+
+	if err := store.Rotate(ctx, "identity", "device", hasher.Sum([]byte("next")), "next", "hash"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	if err := store.RevokeDevice(ctx, "identity", "device"); err != nil {
+		t.Fatalf("failed to revoke device: %v", err)
+	}
+
+	if err := store.EnsureActive(ctx, "identity", "device"); err == nil {
+		t.Fatalf("expected device to be revoked")
+	}
+
+	if err := store.DeleteIdentity(ctx, "identity"); err != nil {
+		t.Fatalf("failed to delete identity: %v", err)
+	}
+
+	if err := store.DeleteIdentity(ctx, "identity"); err == nil {
+		t.Fatalf("expected deleting a deleted identity to fail")
+	}
+}
+
+func TestTimeLockStore(t *testing.T) {
+	db := openDatabase(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	replicaA := postgres.NewTimeLockStoreWithClock(db, "access", 30*time.Second, clock)
+	replicaB := postgres.NewTimeLockStoreWithClock(db, "access", 30*time.Second, clock)
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			store := replicaA
+			if i%2 == 1 {
+				store = replicaB
+			}
+
+			err := store.Reserve(ctx, "value")
+			switch {
+			case err == nil:
+				reserved.Add(1)
+			case !errors.Is(err, storageinterfaces.ErrReserved):
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if reserved.Load() != 1 {
+		t.Fatalf("expected exactly one reservation, got %d", reserved.Load())
+	}
+
+	if err := postgres.NewTimeLockStoreWithClock(db, "keyhash", time.Minute, clock).Reserve(ctx, "value"); err != nil {
+		t.Fatalf("expected buckets to separate stores: %v", err)
+	}
+
+	clock.Advance(30 * time.Second)
+
+	if err := replicaB.Reserve(ctx, "value"); err != nil {
+		t.Fatalf("expected the reservation to have lapsed: %v", err)
+	}
+
+	clock.Advance(time.Minute)
+
+	if err := replicaA.Sweep(ctx); err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+}
+
+func TestAuthenticationNonceStore(t *testing.T) {
+	db := openDatabase(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	store := postgres.NewAuthenticationNonceStoreWithClock(db, time.Minute, clock)
+
+	nonce, err := store.Generate(ctx, "identity")
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	if identity, err := store.Verify(ctx, nonce); err != nil || identity != "identity" {
+		t.Fatalf("expected identity, got %q (%v)", identity, err)
+	}
+
+	clock.Advance(time.Minute)
+
+	if _, err := store.Verify(ctx, nonce); err == nil {
+		t.Fatalf("expected expired nonce to fail")
+	}
+
+	if err := store.Sweep(ctx); err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+}
+
+func TestRevocationStore(t *testing.T) {
+	db := openDatabase(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	store := postgres.NewRevocationStoreWithClock(db, time.Hour, clock)
+
+	if err := store.Revoke(ctx, "session"); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+
+	if revoked, err := store.IsRevoked(ctx, "session"); err != nil || !revoked {
+		t.Fatalf("expected session to be revoked (%v)", err)
+	}
+
+	clock.Advance(time.Hour)
+
+	if revoked, err := store.IsRevoked(ctx, "session"); err != nil || revoked {
+		t.Fatalf("expected revocation to have lapsed (%v)", err)
+	}
+}
+
+func TestRecoveryHashStore(t *testing.T) {
+	db := openDatabase(t)
+	ctx := context.Background()
+
+	store := postgres.NewRecoveryHashStore(db)
+
+	if err := store.Register(ctx, "identity", "first"); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if err := store.Register(ctx, "identity", "first"); err == nil {
+		t.Fatalf("expected duplicate registration to fail")
+	}
+
+	var rotated atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := store.Rotate(ctx, "identity", "first", fmt.Sprintf("second-%d", i)); err == nil {
+				rotated.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if rotated.Load() != 1 {
+		t.Fatalf("expected exactly one recovery, got %d", rotated.Load())
+	}
+
+	if err := store.Rotate(ctx, "unknown", "first", "second"); err == nil || err.Error() != "not found" {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	if err := store.Change(ctx, "identity", "changed"); err != nil {
+		t.Fatalf("failed to change: %v", err)
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	db := openDatabase(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+	expiresAt := clock.Now().Add(time.Hour)
+
+	store := postgres.NewSessionStoreWithClock(db, clock)
+
+	if err := store.Create(ctx, "session-b", "identity", "device", expiresAt); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	clock.Advance(time.Second)
+
+	if err := store.Create(ctx, "session-a", "identity", "device", expiresAt); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	if err := store.Create(ctx, "session-a", "identity", "device", expiresAt); err == nil {
+		t.Fatalf("expected duplicate session to fail")
+	}
+
+	clock.Advance(time.Second)
+
+	if err := store.Refresh(ctx, "session-b", expiresAt); err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+
+	sessions, err := store.List(ctx, "identity")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	if len(sessions) != 2 || sessions[0].Id != "session-b" || sessions[1].Id != "session-a" {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+
+	// the database keeps microseconds
+	if !sessions[0].RefreshedAt.Equal(clock.Now().Truncate(time.Microsecond)) {
+		t.Errorf("expected refresh time %v, got %v", clock.Now(), sessions[0].RefreshedAt)
+	}
+
+	if err := store.Terminate(ctx, "other", "session-a"); err == nil {
+		t.Fatalf("expected terminating another identity's session to fail")
+	}
+
+	if err := store.Terminate(ctx, "identity", "session-a"); err != nil {
+		t.Fatalf("failed to terminate: %v", err)
+	}
+
+	if err := store.Refresh(ctx, "session-a", expiresAt); err == nil {
+		t.Fatalf("expected refreshing a terminated session to fail")
+	}
+
+	clock.Advance(time.Hour)
+
+	if err := store.Sweep(ctx); err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+
+	var remaining int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM sessions").Scan(&remaining); err != nil {
+		t.Fatalf("failed to count sessions: %v", err)
+	}
+
+	if remaining != 0 {
+		t.Fatalf("expected expired sessions to be swept, found %d", remaining)
+	}
+}
+
+func TestVerificationKeyStore(t *testing.T) {
+	db := openDatabase(t)
+	ctx := context.Background()
+
+	key, err := crypto.NewSecp256r1()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	identity, err := key.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive identity: %v", err)
+	}
+
+	store := postgres.NewVerificationKeyStore(db, crypto.NewSecp256r1Verifier())
+	if err := store.Add(ctx, identity, key); err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+
+	stored, err := store.Get(ctx, identity)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	publicKey, err := stored.Public()
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+
+	signature, err := key.Sign([]byte("message"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	if err := stored.Verifier().Verify(signature, publicKey, []byte("message")); err != nil {
+		t.Fatalf("expected the stored key to verify: %v", err)
+	}
+
+	if _, err := store.Get(ctx, "unknown"); err == nil {
+		t.Fatalf("expected an unknown identity to fail")
+	}
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type RecoveryHashStore struct {
+	db *sql.DB
+}
+
+func NewRecoveryHashStore(db *sql.DB) *RecoveryHashStore {
+	return &RecoveryHashStore{
+		db: db,
+	}
+}
+
+func (store *RecoveryHashStore) Register(ctx context.Context, identity, hash string) error {
+	result, err := store.db.ExecContext(
+		ctx,
+		"INSERT INTO recovery_hashes (identity, hash) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		identity, hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	inserted, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if !inserted {
+		return fmt.Errorf("already exists")
+	}
+
+	return nil
+}
+
+// Rotate compares and swaps in one statement, so concurrent recoveries can't both succeed.
+func (store *RecoveryHashStore) Rotate(ctx context.Context, identity, oldHash, newHash string) error {
+	result, err := store.db.ExecContext(
+		ctx,
+		"UPDATE recovery_hashes SET hash = $3 WHERE identity = $1 AND lower(hash) = lower($2)",
+		identity, oldHash, newHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	rotated, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if rotated {
+		return nil
+	}
+
+	var exists bool
+	if err := store.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM recovery_hashes WHERE identity = $1)",
+		identity,
+	).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("not found")
+	}
+
+	return fmt.Errorf("incorrect hash")
+}
+
+func (store *RecoveryHashStore) Change(ctx context.Context, identity, keyHash string) error {
+	result, err := store.db.ExecContext(
+		ctx,
+		"UPDATE recovery_hashes SET hash = $2 WHERE identity = $1",
+		identity, keyHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	changed, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return fmt.Errorf("not found")
+	}
+
+	return nil
+}
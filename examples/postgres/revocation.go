@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+)
+
+// RevocationStore forgets revocations after lifetime, which should be at least as long as the
+// longest-lived credential that may reference a revoked value.
+type RevocationStore struct {
+	db       *sql.DB
+	clock    clockinterfaces.Clock
+	lifetime time.Duration
+}
+
+func NewRevocationStore(db *sql.DB, lifetime time.Duration) *RevocationStore {
+	return NewRevocationStoreWithClock(db, lifetime, clock.NewSystemClock())
+}
+
+func NewRevocationStoreWithClock(db *sql.DB, lifetime time.Duration, clock clockinterfaces.Clock) *RevocationStore {
+	return &RevocationStore{
+		db:       db,
+		clock:    clock,
+		lifetime: lifetime,
+	}
+}
+
+func (store *RevocationStore) Revoke(ctx context.Context, value string) error {
+	_, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO revocations (value, expires_at) VALUES ($1, $2)
+		ON CONFLICT (value) DO UPDATE SET expires_at = EXCLUDED.expires_at`,
+		value, store.clock.Now().Add(store.lifetime),
+	)
+	return err
+}
+
+func (store *RevocationStore) IsRevoked(ctx context.Context, value string) (bool, error) {
+	var revoked bool
+
+	err := store.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM revocations WHERE value = $1 AND expires_at > $2)",
+		value, store.clock.Now(),
+	).Scan(&revoked)
+
+	return revoked, err
+}
+
+// Sweep deletes lapsed revocations. Run it periodically.
+func (store *RevocationStore) Sweep(ctx context.Context) error {
+	_, err := store.db.ExecContext(ctx, "DELETE FROM revocations WHERE expires_at <= $1", store.clock.Now())
+	return err
+}
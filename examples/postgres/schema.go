@@ -0,0 +1,116 @@
+// Package postgres implements the storage interfaces on PostgreSQL via database/sql, for
+// deployments running several replicas against one database. Callers register a driver (e.g.
+// github.com/lib/pq or github.com/jackc/pgx/v5/stdlib) and apply Schema before use.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+const Schema = `
+CREATE TABLE IF NOT EXISTS authentication_identities (
+	identity TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS authentication_keys (
+	identity      TEXT NOT NULL,
+	device        TEXT NOT NULL,
+	public_key    TEXT NOT NULL,
+	rotation_hash TEXT NOT NULL,
+	PRIMARY KEY (identity, device)
+);
+
+CREATE TABLE IF NOT EXISTS authentication_nonces (
+	nonce      TEXT PRIMARY KEY,
+	identity   TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS authentication_nonces_expires_at ON authentication_nonces (expires_at);
+
+CREATE TABLE IF NOT EXISTS recovery_hashes (
+	identity TEXT PRIMARY KEY,
+	hash     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS revocations (
+	value      TEXT PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revocations_expires_at ON revocations (expires_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id           TEXT PRIMARY KEY,
+	identity     TEXT NOT NULL,
+	device       TEXT NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL,
+	refreshed_at TIMESTAMPTZ NOT NULL,
+	expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_identity ON sessions (identity);
+CREATE INDEX IF NOT EXISTS sessions_expires_at ON sessions (expires_at);
+
+CREATE TABLE IF NOT EXISTS time_locks (
+	bucket     TEXT NOT NULL,
+	value      TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (bucket, value)
+);
+
+CREATE INDEX IF NOT EXISTS time_locks_expires_at ON time_locks (bucket, expires_at);
+
+CREATE TABLE IF NOT EXISTS verification_keys (
+	identity   TEXT PRIMARY KEY,
+	public_key TEXT NOT NULL
+);
+`
+
+// Migrate applies Schema, which is idempotent.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, Schema)
+	return err
+}
+
+// inTransaction runs fn in a transaction, committing if it returns nil.
+func inTransaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockNamespace is the first key of the advisory locks taken on identities, so they can't collide
+// with locks an application takes on its own two-key space.
+const lockNamespace = 0x42410001
+
+// lockIdentity takes a transaction-scoped advisory lock on identity. Operations on a single device
+// take it shared, and operations spanning every device of the identity take it exclusively, so
+// e.g. a registration can't slip in while RevokeDevices is running.
+func lockIdentity(ctx context.Context, tx *sql.Tx, identity string, exclusive bool) error {
+	query := "SELECT pg_advisory_xact_lock_shared($1, hashtext($2))"
+	if exclusive {
+		query = "SELECT pg_advisory_xact_lock($1, hashtext($2))"
+	}
+
+	_, err := tx.ExecContext(ctx, query, lockNamespace, identity)
+	return err
+}
+
+func affected(result sql.Result) (bool, error) {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	baerrors "github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+type SessionStore struct {
+	db    *sql.DB
+	clock clockinterfaces.Clock
+}
+
+func NewSessionStore(db *sql.DB) *SessionStore {
+	return NewSessionStoreWithClock(db, clock.NewSystemClock())
+}
+
+func NewSessionStoreWithClock(db *sql.DB, clock clockinterfaces.Clock) *SessionStore {
+	return &SessionStore{
+		db:    db,
+		clock: clock,
+	}
+}
+
+func (s *SessionStore) Create(ctx context.Context, session, identity, device string, expiresAt time.Time) error {
+	now := s.clock.Now()
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO sessions (id, identity, device, created_at, refreshed_at, expires_at)
+		VALUES ($1, $2, $3, $4, $4, $5) ON CONFLICT DO NOTHING`,
+		session, identity, device, now, expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	inserted, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if !inserted {
+		return fmt.Errorf("already exists")
+	}
+
+	return nil
+}
+
+func (s *SessionStore) Refresh(ctx context.Context, session string, expiresAt time.Time) error {
+	result, err := s.db.ExecContext(
+		ctx,
+		"UPDATE sessions SET refreshed_at = $2, expires_at = $3 WHERE id = $1 AND expires_at > $2",
+		session, s.clock.Now(), expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	refreshed, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if !refreshed {
+		return baerrors.NewUnknownSessionError(session)
+	}
+
+	return nil
+}
+
+func (s *SessionStore) List(ctx context.Context, identity string) ([]storageinterfaces.Session, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, identity, device, created_at, refreshed_at, expires_at FROM sessions
+		WHERE identity = $1 AND expires_at > $2 ORDER BY created_at, id`,
+		identity, s.clock.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []storageinterfaces.Session{}
+	for rows.Next() {
+		var session storageinterfaces.Session
+		if err := rows.Scan(&session.Id, &session.Identity, &session.Device, &session.CreatedAt, &session.RefreshedAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (s *SessionStore) Terminate(ctx context.Context, identity, session string) error {
+	result, err := s.db.ExecContext(
+		ctx,
+		"DELETE FROM sessions WHERE id = $1 AND identity = $2",
+		session, identity,
+	)
+	if err != nil {
+		return err
+	}
+
+	terminated, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if !terminated {
+		return baerrors.NewUnknownSessionError(session)
+	}
+
+	return nil
+}
+
+// Sweep deletes expired sessions. Run it periodically.
+func (s *SessionStore) Sweep(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= $1", s.clock.Now())
+	return err
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// TimeLockStore reserves values with a single conditional upsert, which only replaces a
+// reservation that has lapsed, so replicas can't both reserve the same value.
+type TimeLockStore struct {
+	db       *sql.DB
+	bucket   string
+	clock    clockinterfaces.Clock
+	lifetime time.Duration
+}
+
+// NewTimeLockStore stores reservations under bucket, which must be distinct for each time lock
+// store sharing db.
+func NewTimeLockStore(db *sql.DB, bucket string, lifetime time.Duration) *TimeLockStore {
+	return NewTimeLockStoreWithClock(db, bucket, lifetime, clock.NewSystemClock())
+}
+
+func NewTimeLockStoreWithClock(db *sql.DB, bucket string, lifetime time.Duration, clock clockinterfaces.Clock) *TimeLockStore {
+	return &TimeLockStore{
+		db:       db,
+		bucket:   bucket,
+		clock:    clock,
+		lifetime: lifetime,
+	}
+}
+
+func (store *TimeLockStore) Lifetime() time.Duration {
+	return store.lifetime
+}
+
+func (store *TimeLockStore) Reserve(ctx context.Context, value string) error {
+	return store.ReserveFor(ctx, value, store.lifetime)
+}
+
+func (store *TimeLockStore) ReserveFor(ctx context.Context, value string, lifetime time.Duration) error {
+	now := store.clock.Now()
+
+	result, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO time_locks (bucket, value, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (bucket, value) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE time_locks.expires_at <= $4`,
+		store.bucket, value, now.Add(lifetime), now,
+	)
+	if err != nil {
+		return err
+	}
+
+	reserved, err := affected(result)
+	if err != nil {
+		return err
+	}
+
+	if !reserved {
+		return storageinterfaces.ErrReserved
+	}
+
+	return nil
+}
+
+// Sweep deletes lapsed reservations. Run it periodically.
+func (store *TimeLockStore) Sweep(ctx context.Context) error {
+	_, err := store.db.ExecContext(
+		ctx,
+		"DELETE FROM time_locks WHERE bucket = $1 AND expires_at <= $2",
+		store.bucket, store.clock.Now(),
+	)
+	return err
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+)
+
+// VerificationKeyStore persists public keys, verifying with a single verifier.
+type VerificationKeyStore struct {
+	db       *sql.DB
+	verifier cryptointerfaces.Verifier
+}
+
+func NewVerificationKeyStore(db *sql.DB, verifier cryptointerfaces.Verifier) *VerificationKeyStore {
+	return &VerificationKeyStore{
+		db:       db,
+		verifier: verifier,
+	}
+}
+
+func (s *VerificationKeyStore) Add(ctx context.Context, identity string, key cryptointerfaces.VerificationKey) error {
+	publicKey, err := key.Public()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`INSERT INTO verification_keys (identity, public_key) VALUES ($1, $2)
+		ON CONFLICT (identity) DO UPDATE SET public_key = EXCLUDED.public_key`,
+		identity, publicKey,
+	)
+
+	return err
+}
+
+func (s *VerificationKeyStore) Get(ctx context.Context, identity string) (cryptointerfaces.VerificationKey, error) {
+	var publicKey string
+
+	err := s.db.QueryRowContext(
+		ctx,
+		"SELECT public_key FROM verification_keys WHERE identity = $1",
+		identity,
+	).Scan(&publicKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("key not found for identity: %s", identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &verificationKey{publicKey: publicKey, verifier: s.verifier}, nil
+}
+
+type verificationKey struct {
+	publicKey string
+	verifier  cryptointerfaces.Verifier
+}
+
+func (k *verificationKey) Verifier() cryptointerfaces.Verifier {
+	return k.verifier
+}
+
+func (k *verificationKey) Public() (string, error) {
+	return k.publicKey, nil
+}
@@ -0,0 +1,236 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Do once the client has been closed.
+var ErrClosed = errors.New("resp: client closed")
+
+const DefaultPoolSize = 10
+
+// Client issues commands over a pool of connections. At most the pool size are open at once;
+// callers beyond that wait for a connection to be returned, or for their context to be done.
+type Client struct {
+	address  string
+	dial     func(ctx context.Context, network, address string) (net.Conn, error)
+	password string
+
+	slots chan struct{}
+	idle  chan *conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type clientOptions struct {
+	dial     func(ctx context.Context, network, address string) (net.Conn, error)
+	password string
+	poolSize int
+}
+
+type ClientOption func(*clientOptions)
+
+// WithPoolSize bounds the number of open connections.
+func WithPoolSize(size int) ClientOption {
+	return func(o *clientOptions) {
+		o.poolSize = size
+	}
+}
+
+// WithPassword authenticates each new connection with AUTH.
+func WithPassword(password string) ClientOption {
+	return func(o *clientOptions) {
+		o.password = password
+	}
+}
+
+// WithDialer replaces the default TCP dialer, e.g. to add TLS.
+func WithDialer(dial func(ctx context.Context, network, address string) (net.Conn, error)) ClientOption {
+	return func(o *clientOptions) {
+		o.dial = dial
+	}
+}
+
+func NewClient(address string, options ...ClientOption) *Client {
+	o := clientOptions{
+		dial:     (&net.Dialer{}).DialContext,
+		poolSize: DefaultPoolSize,
+	}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	if o.poolSize < 1 {
+		o.poolSize = 1
+	}
+
+	return &Client{
+		address:  address,
+		dial:     o.dial,
+		password: o.password,
+		slots:    make(chan struct{}, o.poolSize),
+		idle:     make(chan *conn, o.poolSize),
+	}
+}
+
+// Do sends a command and returns its reply (see readValue). Error replies are returned as Error.
+// If ctx is done before the reply arrives, the connection is discarded and ctx.Err() returned.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-c.slots }()
+
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, args)
+	if err != nil {
+		cn.Close()
+		return nil, err
+	}
+
+	c.put(cn)
+
+	if replyErr, ok := reply.(Error); ok {
+		return nil, replyErr
+	}
+
+	return reply, nil
+}
+
+// Close closes idle connections. Connections in use are closed as they are returned.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+
+	if closed {
+		return nil, ErrClosed
+	}
+
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	netConn, err := c.dial(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{
+		Conn:   netConn,
+		reader: bufio.NewReader(netConn),
+		writer: bufio.NewWriter(netConn),
+	}
+
+	if c.password != "" {
+		reply, err := cn.do(ctx, []string{"AUTH", c.password})
+		if err == nil {
+			if replyErr, ok := reply.(Error); ok {
+				err = replyErr
+			}
+		}
+
+		if err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+
+	if closed {
+		cn.Close()
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func (cn *conn) do(ctx context.Context, args []string) (any, error) {
+	deadline, hasDeadline := ctx.Deadline()
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// unblock reads and writes as soon as ctx is cancelled, not just at its deadline
+	stop := context.AfterFunc(ctx, func() {
+		cn.SetDeadline(time.Unix(1, 0))
+	})
+
+	reply, err := cn.exchange(args)
+
+	if !stop() {
+		// the deadline may have been poisoned, so the connection can't be reused
+		return nil, ctx.Err()
+	}
+
+	if hasDeadline && errors.Is(err, os.ErrDeadlineExceeded) {
+		// the socket deadline can fire just before ctx's own timer
+		<-ctx.Done()
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return reply, err
+}
+
+func (cn *conn) exchange(args []string) (any, error) {
+	if err := writeCommand(cn.writer, args); err != nil {
+		return nil, err
+	}
+
+	return readValue(cn.reader)
+}
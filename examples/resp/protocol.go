@@ -0,0 +1,138 @@
+// Package resp implements enough of the Redis serialization protocol (RESP2) to back the storage
+// examples with a shared server: a pooled client, and an in-process server for tests.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrProtocol is returned (wrapped) when a peer sends something that isn't valid RESP.
+var ErrProtocol = errors.New("resp: protocol error")
+
+// Error is an error reply, e.g. "ERR unknown command". The connection remains usable.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	maximumBulkLength  = 16 * 1024 * 1024
+	maximumArrayLength = 1024 * 1024
+	maximumDepth       = 16
+)
+
+func writeCommand(w *bufio.Writer, args []string) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// readValue reads one reply. Simple and bulk strings are returned as string, integers as int64,
+// arrays as []any, error replies as Error, and null bulk strings and arrays as nil.
+func readValue(r *bufio.Reader) (any, error) {
+	return readValueAt(r, 0)
+}
+
+func readValueAt(r *bufio.Reader, depth int) (any, error) {
+	if depth > maximumDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrProtocol)
+	}
+
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%w: empty line", ErrProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		value, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer", ErrProtocol)
+		}
+
+		return value, nil
+	case '$':
+		length, err := readLength(line[1:], maximumBulkLength)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		if data[length] != '\r' || data[length+1] != '\n' {
+			return nil, fmt.Errorf("%w: unterminated bulk string", ErrProtocol)
+		}
+
+		return string(data[:length]), nil
+	case '*':
+		length, err := readLength(line[1:], maximumArrayLength)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+
+		values := make([]any, 0, min(length, 64))
+		for range length {
+			value, err := readValueAt(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected type %q", ErrProtocol, line[0])
+	}
+}
+
+// readLength parses a bulk or array length, where -1 denotes null.
+func readLength(value string, maximum int) (int, error) {
+	length, err := strconv.Atoi(value)
+	if err != nil || length < -1 || length > maximum {
+		return 0, fmt.Errorf("%w: invalid length %q", ErrProtocol, value)
+	}
+
+	return length, nil
+}
+
+// readLine reads a CRLF terminated line, bounded by the reader's buffer.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", fmt.Errorf("%w: line too long", ErrProtocol)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("%w: line not terminated by CRLF", ErrProtocol)
+	}
+
+	return string(line[:len(line)-2]), nil
+}
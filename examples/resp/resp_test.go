@@ -0,0 +1,238 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+)
+
+func startServer(t *testing.T) (*Server, *clock.ManualClock, string) {
+	t.Helper()
+
+	clock := clock.NewManualClock(time.Now())
+	server := NewServerWithClock(clock)
+
+	address, err := server.Start()
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return server, clock, address
+}
+
+func TestReadValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"+OK\r\n", "OK"},
+		{"-ERR bad\r\n", Error("ERR bad")},
+		{":-42\r\n", int64(-42)},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$0\r\n\r\n", ""},
+		{"$-1\r\n", nil},
+		{"*-1\r\n", nil},
+	}
+
+	for _, test := range tests {
+		value, err := readValue(bufio.NewReader(strings.NewReader(test.input)))
+		if err != nil {
+			t.Fatalf("%q: %v", test.input, err)
+		}
+
+		if value != test.expected {
+			t.Errorf("%q: expected %#v, got %#v", test.input, test.expected, value)
+		}
+	}
+
+	value, err := readValue(bufio.NewReader(strings.NewReader("*2\r\n$1\r\na\r\n*1\r\n:1\r\n")))
+	if err != nil {
+		t.Fatalf("failed to read array: %v", err)
+	}
+
+	array, ok := value.([]any)
+	if !ok || len(array) != 2 || array[0] != "a" {
+		t.Fatalf("unexpected array %#v", value)
+	}
+
+	if nested, ok := array[1].([]any); !ok || len(nested) != 1 || nested[0] != int64(1) {
+		t.Fatalf("unexpected nested array %#v", array[1])
+	}
+}
+
+func TestReadValueRejectsMalformed(t *testing.T) {
+	inputs := []string{
+		"\r\n",
+		"OK\r\n",
+		"+OK\n",
+		":1.5\r\n",
+		"$-2\r\n",
+		"$3\r\nabcd\r\n",
+		"$99999999999\r\n",
+		"*-5\r\n",
+		strings.Repeat("*1\r\n", maximumDepth+2) + ":1\r\n",
+		"+" + strings.Repeat("a", 8192) + "\r\n",
+	}
+
+	for _, input := range inputs {
+		if _, err := readValue(bufio.NewReader(strings.NewReader(input))); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestClientCommands(t *testing.T) {
+	_, clock, address := startServer(t)
+	ctx := context.Background()
+
+	client := NewClient(address)
+	defer client.Close()
+
+	if reply, err := client.Do(ctx, "PING"); err != nil || reply != "PONG" {
+		t.Fatalf("unexpected ping reply %#v (%v)", reply, err)
+	}
+
+	if reply, err := client.Do(ctx, "SET", "key", "value", "NX", "PX", "1000"); err != nil || reply != "OK" {
+		t.Fatalf("unexpected set reply %#v (%v)", reply, err)
+	}
+
+	if reply, err := client.Do(ctx, "SET", "key", "other", "NX", "PX", "1000"); err != nil || reply != nil {
+		t.Fatalf("expected conditional set to be refused, got %#v (%v)", reply, err)
+	}
+
+	if reply, err := client.Do(ctx, "GET", "key"); err != nil || reply != "value" {
+		t.Fatalf("unexpected get reply %#v (%v)", reply, err)
+	}
+
+	if reply, err := client.Do(ctx, "PTTL", "key"); err != nil || reply != int64(1000) {
+		t.Fatalf("unexpected pttl reply %#v (%v)", reply, err)
+	}
+
+	clock.Advance(time.Second)
+
+	if reply, err := client.Do(ctx, "GET", "key"); err != nil || reply != nil {
+		t.Fatalf("expected key to have expired, got %#v (%v)", reply, err)
+	}
+
+	_, err := client.Do(ctx, "NOPE")
+	var replyErr Error
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("expected an error reply, got %v", err)
+	}
+
+	// error replies leave the connection usable
+	if reply, err := client.Do(ctx, "PING"); err != nil || reply != "PONG" {
+		t.Fatalf("unexpected ping reply %#v (%v)", reply, err)
+	}
+}
+
+func TestClientPool(t *testing.T) {
+	server, _, address := startServer(t)
+	ctx := context.Background()
+
+	client := NewClient(address, WithPoolSize(3))
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := client.Do(ctx, "PING"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("ping failed: %v", err)
+	}
+
+	if accepted := server.Accepted(); accepted > 3 {
+		t.Errorf("expected at most 3 connections, got %d", accepted)
+	}
+}
+
+func TestClientContextCancellation(t *testing.T) {
+	// a server that accepts connections and never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer netConn.Close()
+		}
+	}()
+
+	client := NewClient(listener.Addr().String(), WithPoolSize(1))
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err := client.Do(ctx, "PING"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Do(ctx, "PING"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestClientWaitsForPoolSlot(t *testing.T) {
+	_, _, address := startServer(t)
+
+	client := NewClient(address, WithPoolSize(1))
+	defer client.Close()
+
+	// hold the only slot
+	client.slots <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Do(ctx, "PING"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	<-client.slots
+
+	if _, err := client.Do(context.Background(), "PING"); err != nil {
+		t.Fatalf("expected ping to succeed once the slot is free: %v", err)
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	_, _, address := startServer(t)
+
+	client := NewClient(address)
+	client.Close()
+
+	if _, err := client.Do(context.Background(), "PING"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,330 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+)
+
+// Server is an in-process stand-in for a Redis server, implementing the handful of commands the
+// storage examples use (PING, AUTH, GET, SET with NX/XX/PX/EX, GETDEL, DEL, EXISTS, PTTL and
+// FLUSHALL). Expiry follows the supplied clock, so tests can move time without sleeping.
+type Server struct {
+	mu       sync.Mutex
+	clock    clockinterfaces.Clock
+	values   map[string]entry
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	accepted int
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// status is a simple string reply, as opposed to a bulk string.
+type status string
+
+type entry struct {
+	value  string
+	expiry time.Time
+}
+
+func NewServer() *Server {
+	return NewServerWithClock(clock.NewSystemClock())
+}
+
+func NewServerWithClock(clock clockinterfaces.Clock) *Server {
+	return &Server{
+		clock:  clock,
+		values: map[string]entry{},
+		conns:  map[net.Conn]struct{}{},
+	}
+}
+
+// Start listens on a free loopback port and returns its address.
+func (s *Server) Start() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.serve(listener)
+
+	return listener.Addr().String(), nil
+}
+
+// Close stops listening, drops every connection and waits for handlers to exit.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+
+	for netConn := range s.conns {
+		netConn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+// Accepted reports how many connections have been accepted.
+func (s *Server) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.accepted
+}
+
+func (s *Server) serve(listener net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			netConn.Close()
+			return
+		}
+
+		s.conns[netConn] = struct{}{}
+		s.accepted++
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(netConn)
+	}
+}
+
+func (s *Server) handle(netConn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, netConn)
+		s.mu.Unlock()
+
+		netConn.Close()
+	}()
+
+	reader := bufio.NewReader(netConn)
+	writer := bufio.NewWriter(netConn)
+
+	for {
+		value, err := readValue(reader)
+		if err != nil {
+			return
+		}
+
+		args, ok := commandArgs(value)
+		if !ok {
+			writeReply(writer, Error("ERR protocol error: expected an array of bulk strings"))
+			writer.Flush()
+			return
+		}
+
+		writeReply(writer, s.execute(args))
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func commandArgs(value any) ([]string, bool) {
+	values, ok := value.([]any)
+	if !ok || len(values) == 0 {
+		return nil, false
+	}
+
+	args := make([]string, len(values))
+	for i, value := range values {
+		arg, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+
+		args[i] = arg
+	}
+
+	return args, true
+}
+
+func (s *Server) execute(args []string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	command := strings.ToUpper(args[0])
+	args = args[1:]
+
+	switch command {
+	case "PING":
+		return status("PONG")
+	case "AUTH":
+		return status("OK")
+	case "GET":
+		if len(args) != 1 {
+			return wrongArity(command)
+		}
+
+		if e, ok := s.lookup(args[0]); ok {
+			return e.value
+		}
+
+		return nil
+	case "GETDEL":
+		if len(args) != 1 {
+			return wrongArity(command)
+		}
+
+		e, ok := s.lookup(args[0])
+		if !ok {
+			return nil
+		}
+
+		delete(s.values, args[0])
+
+		return e.value
+	case "SET":
+		return s.set(args)
+	case "DEL", "EXISTS":
+		if len(args) == 0 {
+			return wrongArity(command)
+		}
+
+		count := int64(0)
+		for _, key := range args {
+			if _, ok := s.lookup(key); ok {
+				count++
+				if command == "DEL" {
+					delete(s.values, key)
+				}
+			}
+		}
+
+		return count
+	case "PTTL":
+		if len(args) != 1 {
+			return wrongArity(command)
+		}
+
+		e, ok := s.lookup(args[0])
+		if !ok {
+			return int64(-2)
+		}
+
+		if e.expiry.IsZero() {
+			return int64(-1)
+		}
+
+		return e.expiry.Sub(s.clock.Now()).Milliseconds()
+	case "FLUSHALL":
+		s.values = map[string]entry{}
+		return status("OK")
+	default:
+		return Error(fmt.Sprintf("ERR unknown command '%s'", command))
+	}
+}
+
+func (s *Server) set(args []string) any {
+	if len(args) < 2 {
+		return wrongArity("SET")
+	}
+
+	key, value := args[0], args[1]
+	var nx, xx bool
+	var expiry time.Time
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "PX", "EX":
+			if i+1 == len(args) || !expiry.IsZero() {
+				return Error("ERR syntax error")
+			}
+
+			amount, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || amount <= 0 {
+				return Error("ERR invalid expire time in 'set' command")
+			}
+
+			unit := time.Millisecond
+			if strings.ToUpper(args[i]) == "EX" {
+				unit = time.Second
+			}
+
+			expiry = s.clock.Now().Add(time.Duration(amount) * unit)
+			i++
+		default:
+			return Error("ERR syntax error")
+		}
+	}
+
+	if nx && xx {
+		return Error("ERR syntax error")
+	}
+
+	_, exists := s.lookup(key)
+	if (nx && exists) || (xx && !exists) {
+		return nil
+	}
+
+	s.values[key] = entry{value: value, expiry: expiry}
+
+	return status("OK")
+}
+
+// lookup returns a live entry, evicting it if it has expired.
+func (s *Server) lookup(key string) (entry, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		return entry{}, false
+	}
+
+	if !e.expiry.IsZero() && !s.clock.Now().Before(e.expiry) {
+		delete(s.values, key)
+		return entry{}, false
+	}
+
+	return e, true
+}
+
+func wrongArity(command string) Error {
+	return Error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(command)))
+}
+
+func writeReply(w *bufio.Writer, reply any) {
+	switch reply := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case Error:
+		fmt.Fprintf(w, "-%s\r\n", reply)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", reply)
+	case status:
+		fmt.Fprintf(w, "+%s\r\n", reply)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(reply), reply)
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"github.com/jasoncolburne/better-auth-go/api"
 	"github.com/jasoncolburne/better-auth-go/examples/crypto"
 	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/examples/events"
 	"github.com/jasoncolburne/better-auth-go/examples/storage"
 	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/messages"
 )
 
@@ -31,6 +33,23 @@ type MockResponsePayload struct {
 	WasBar string `json:"wasBar"`
 }
 
+type SessionSummary struct {
+	Session     string `json:"session"`
+	Device      string `json:"device"`
+	CreatedAt   string `json:"createdAt"`
+	RefreshedAt string `json:"refreshedAt"`
+}
+
+type ListSessionsResponsePayload struct {
+	Sessions []SessionSummary `json:"sessions"`
+}
+
+type TerminateSessionRequestPayload struct {
+	Session string `json:"session"`
+}
+
+type TerminateSessionResponsePayload struct{}
+
 type MockAccessRequest = messages.AccessRequest[MockRequestPayload, MockTokenAttributes]
 type MockAccessResponse = messages.ServerResponse[MockResponsePayload]
 
@@ -38,6 +57,7 @@ type Server struct {
 	ba                *api.BetterAuthServer[MockTokenAttributes]
 	av                *api.AccessVerifier[MockTokenAttributes]
 	serverResponseKey cryptointerfaces.SigningKey
+	timestamper       encodinginterfaces.Timestamper
 }
 
 func NewServer() (*Server, error) {
@@ -52,9 +72,11 @@ func NewServer() (*Server, error) {
 
 	accessKeyHashStore := storage.NewInMemoryTimeLockStore(refreshLifetime)
 	accessNonceStore := storage.NewInMemoryTimeLockStore(accessWindow)
-	authenticationKeyStore := storage.NewInMemoryAuthenticationKeyStore(hasher)
+	revocationStore := storage.NewInMemoryRevocationStore(refreshLifetime)
+	authenticationKeyStore := storage.NewInMemoryAuthenticationKeyStore()
 	authenticationNonceStore := storage.NewInMemoryAuthenticationNonceStore(authenticationChallengeLifetime)
 	recoveryHashStore := storage.NewInMemoryRecoveryHashStore()
+	sessionStore := storage.NewInMemorySessionStore()
 
 	identityVerifier := encoding.NewMockIdentityVerifier(hasher)
 	timestamper := encoding.NewRfc3339()
@@ -99,6 +121,7 @@ func NewServer() (*Server, error) {
 		&api.StoresContainer{
 			Access: &api.AccessStoreContainer{
 				KeyHash:         accessKeyHashStore,
+				Revocation:      revocationStore,
 				VerificationKey: accessKeyStore,
 			},
 			Authentication: &api.AuthenticationStoreContainer{
@@ -108,7 +131,11 @@ func NewServer() (*Server, error) {
 			Recovery: &api.RecoveryStoreContainer{
 				Hash: recoveryHashStore,
 			},
+			Session: &api.SessionStoreContainer{
+				Registry: sessionStore,
+			},
 		},
+		api.WithSecurityEventEmitter(events.NewLogSecurityEventEmitter(os.Stderr)),
 	)
 
 	av := api.NewAccessVerifier[MockTokenAttributes](
@@ -123,12 +150,14 @@ func NewServer() (*Server, error) {
 			AccessNonce: accessNonceStore,
 			AccessKey:   accessKeyStore,
 		},
+		api.CheckRevocation(revocationStore),
 	)
 
 	return &Server{
 		ba:                ba,
 		av:                av,
 		serverResponseKey: serverResponseKey,
+		timestamper:       timestamper,
 	}, nil
 }
 
@@ -245,12 +274,108 @@ func (s *Server) respondToAccessRequest(ctx context.Context, message string, bad
 	return reply, nil
 }
 
+func signResponse[PayloadType any](key cryptointerfaces.SigningKey, payload PayloadType, nonce string) (string, error) {
+	serverIdentity, err := key.Identity()
+	if err != nil {
+		return "", err
+	}
+
+	response := messages.NewServerResponse(payload, serverIdentity, nonce)
+
+	if err := response.Sign(key); err != nil {
+		return "", err
+	}
+
+	return response.Serialize()
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	wrapResponse(w, r, func(ctx context.Context, message string) (string, error) {
+		_, token, nonce, err := s.av.Verify(ctx, message, &MockTokenAttributes{})
+		if err != nil {
+			return "", err
+		}
+
+		sessions, err := s.ba.ListSessions(ctx, token.Identity)
+		if err != nil {
+			return "", err
+		}
+
+		summaries := make([]SessionSummary, 0, len(sessions))
+		for _, session := range sessions {
+			summaries = append(summaries, SessionSummary{
+				Session:     session.Id,
+				Device:      session.Device,
+				CreatedAt:   s.timestamper.Format(session.CreatedAt),
+				RefreshedAt: s.timestamper.Format(session.RefreshedAt),
+			})
+		}
+
+		return signResponse(s.serverResponseKey, ListSessionsResponsePayload{Sessions: summaries}, nonce)
+	})
+}
+
+func (s *Server) terminateSession(w http.ResponseWriter, r *http.Request) {
+	wrapResponse(w, r, func(ctx context.Context, message string) (string, error) {
+		requestJson, token, nonce, err := s.av.Verify(ctx, message, &MockTokenAttributes{})
+		if err != nil {
+			return "", err
+		}
+
+		request := &TerminateSessionRequestPayload{}
+		if err := json.Unmarshal(requestJson, request); err != nil {
+			return "", err
+		}
+
+		if err := s.ba.TerminateSession(ctx, token.Identity, request.Session); err != nil {
+			return "", err
+		}
+
+		return signResponse(s.serverResponseKey, TerminateSessionResponsePayload{}, nonce)
+	})
+}
+
 func (s *Server) fooBar(w http.ResponseWriter, r *http.Request) {
 	wrapResponse(w, r, func(ctx context.Context, message string) (string, error) {
 		return s.respondToAccessRequest(ctx, message, false)
 	})
 }
 
+func (s *Server) fooBarBatch(w http.ResponseWriter, r *http.Request) {
+	wrapResponse(w, r, func(ctx context.Context, message string) (string, error) {
+		requests, _, nonce, err := s.av.VerifyBatch(ctx, message, &MockTokenAttributes{})
+		if err != nil {
+			return "", err
+		}
+
+		responses := make([]MockResponsePayload, 0, len(requests))
+		for _, requestJson := range requests {
+			request := &MockRequestPayload{}
+			if err := json.Unmarshal(requestJson, request); err != nil {
+				return "", err
+			}
+
+			responses = append(responses, MockResponsePayload{
+				WasFoo: request.Foo,
+				WasBar: request.Bar,
+			})
+		}
+
+		serverIdentity, err := s.serverResponseKey.Identity()
+		if err != nil {
+			return "", err
+		}
+
+		response := messages.NewBatchResponse(responses, serverIdentity, nonce)
+
+		if err := response.Sign(s.serverResponseKey); err != nil {
+			return "", err
+		}
+
+		return response.Serialize()
+	})
+}
+
 func (s *Server) badNonce(w http.ResponseWriter, r *http.Request) {
 	wrapResponse(w, r, func(ctx context.Context, message string) (string, error) {
 		return s.respondToAccessRequest(ctx, message, true)
@@ -265,6 +390,8 @@ func (s *Server) StartServer() error {
 	http.HandleFunc("/session/request", s.startAuthentication)
 	http.HandleFunc("/session/create", s.finishAuthentication)
 	http.HandleFunc("/session/refresh", s.rotateAccess)
+	http.HandleFunc("/session/list", s.listSessions)
+	http.HandleFunc("/session/terminate", s.terminateSession)
 
 	http.HandleFunc("/device/rotate", s.rotateAuthentication)
 	http.HandleFunc("/device/link", s.link)
@@ -275,6 +402,7 @@ func (s *Server) StartServer() error {
 	http.HandleFunc("/key/response", s.responseKey)
 
 	http.HandleFunc("/foo/bar", s.fooBar)
+	http.HandleFunc("/foo/bar/batch", s.fooBarBatch)
 	http.HandleFunc("/bad/nonce", s.badNonce)
 
 	return http.ListenAndServe("localhost:8080", nil)
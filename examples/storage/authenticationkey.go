@@ -6,7 +6,7 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
 )
 
 type KeyState struct {
@@ -16,13 +16,11 @@ type KeyState struct {
 
 type InMemoryAuthenticationKeyStore struct {
 	mu           sync.RWMutex
-	hasher       cryptointerfaces.Hasher
 	knownDevices map[string]map[string]KeyState
 }
 
-func NewInMemoryAuthenticationKeyStore(hasher cryptointerfaces.Hasher) *InMemoryAuthenticationKeyStore {
+func NewInMemoryAuthenticationKeyStore() *InMemoryAuthenticationKeyStore {
 	return &InMemoryAuthenticationKeyStore{
-		hasher:       hasher,
 		knownDevices: map[string]map[string]KeyState{},
 	}
 }
@@ -68,7 +66,7 @@ func (s *InMemoryAuthenticationKeyStore) Public(ctx context.Context, identity, d
 	return instance.publicKey, nil
 }
 
-func (s *InMemoryAuthenticationKeyStore) Rotate(ctx context.Context, identity, device, publicKey, rotationHash string) error {
+func (s *InMemoryAuthenticationKeyStore) Rotate(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -82,10 +80,8 @@ func (s *InMemoryAuthenticationKeyStore) Rotate(ctx context.Context, identity, d
 		return fmt.Errorf("device not found")
 	}
 
-	hash := s.hasher.Sum([]byte(publicKey))
-
-	if !strings.EqualFold(hash, instance.rotationHash) {
-		return fmt.Errorf("hash mismatch")
+	if !strings.EqualFold(previousRotationHash, instance.rotationHash) {
+		return storageinterfaces.ErrRotationConflict
 	}
 
 	devices[device] = KeyState{
@@ -98,6 +94,41 @@ func (s *InMemoryAuthenticationKeyStore) Rotate(ctx context.Context, identity, d
 	return nil
 }
 
+func (s *InMemoryAuthenticationKeyStore) Link(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash, linkedDevice, linkedPublicKey, linkedRotationHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devices, ok := s.knownDevices[identity]
+	if !ok {
+		return fmt.Errorf("account not found")
+	}
+
+	instance, ok := devices[device]
+	if !ok {
+		return fmt.Errorf("device not found")
+	}
+
+	if !strings.EqualFold(previousRotationHash, instance.rotationHash) {
+		return storageinterfaces.ErrRotationConflict
+	}
+
+	if _, ok := devices[linkedDevice]; ok {
+		return fmt.Errorf("already registered")
+	}
+
+	devices[device] = KeyState{
+		publicKey:    publicKey,
+		rotationHash: rotationHash,
+	}
+
+	devices[linkedDevice] = KeyState{
+		publicKey:    linkedPublicKey,
+		rotationHash: linkedRotationHash,
+	}
+
+	return nil
+}
+
 func (s *InMemoryAuthenticationKeyStore) RevokeDevice(ctx context.Context, identity, device string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
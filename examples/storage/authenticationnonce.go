@@ -6,12 +6,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
 	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
 )
 
 type InMemoryAuthenticationNonceStore struct {
 	mu               sync.RWMutex
+	clock            clockinterfaces.Clock
 	dataByNonce      map[string]string
 	lifetime         time.Duration
 	nonceExpirations map[string]time.Time
@@ -19,11 +22,26 @@ type InMemoryAuthenticationNonceStore struct {
 }
 
 func NewInMemoryAuthenticationNonceStore(nonceLifetime time.Duration) *InMemoryAuthenticationNonceStore {
+	return NewInMemoryAuthenticationNonceStoreWithClock(nonceLifetime, clock.NewSystemClock())
+}
+
+func NewInMemoryAuthenticationNonceStoreWithClock(nonceLifetime time.Duration, clock clockinterfaces.Clock) *InMemoryAuthenticationNonceStore {
+	return NewInMemoryAuthenticationNonceStoreWithNoncer(nonceLifetime, clock, crypto.NewNoncer())
+}
+
+// NewInMemoryAuthenticationNonceStoreWithNoncer draws nonces from noncer, which may be seeded to make
+// them reproducible.
+func NewInMemoryAuthenticationNonceStoreWithNoncer(
+	nonceLifetime time.Duration,
+	clock clockinterfaces.Clock,
+	noncer cryptointerfaces.Noncer,
+) *InMemoryAuthenticationNonceStore {
 	return &InMemoryAuthenticationNonceStore{
+		clock:            clock,
 		dataByNonce:      map[string]string{},
 		lifetime:         nonceLifetime,
 		nonceExpirations: map[string]time.Time{},
-		noncer:           crypto.NewNoncer(),
+		noncer:           noncer,
 	}
 }
 
@@ -37,7 +55,7 @@ func (s *InMemoryAuthenticationNonceStore) Generate(ctx context.Context, identit
 	defer s.mu.Unlock()
 
 	s.dataByNonce[nonce] = identity
-	s.nonceExpirations[nonce] = time.Now().Add(s.lifetime)
+	s.nonceExpirations[nonce] = s.clock.Now().Add(s.lifetime)
 
 	return nonce, nil
 }
@@ -56,7 +74,7 @@ func (s *InMemoryAuthenticationNonceStore) Verify(ctx context.Context, nonce str
 		return "", fmt.Errorf("expiration not found")
 	}
 
-	if time.Now().After(expiration) {
+	if !s.clock.Now().Before(expiration) {
 		return "", fmt.Errorf("expired nonce")
 	}
 
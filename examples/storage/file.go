@@ -0,0 +1,710 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/kv"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	baerrors "github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// The File* stores persist to a kv.DB, and may share one. Expiring values live in TTL-indexed
+// buckets: the value is stored with its expiry, and an index bucket orders keys by expiry so that
+// writes can sweep out what has lapsed. Swept and overwritten values leave the log once the kv.DB
+// compacts it, which it does as the log grows (see kv.CompactAfter).
+
+const (
+	authenticationIdentityBucket = "authentication:identities"
+	authenticationKeyBucket      = "authentication:keys"
+	authenticationNonceBucket    = "authentication:nonces"
+	recoveryHashBucket           = "recovery:hashes"
+	revocationBucket             = "revocations"
+	sessionBucket                = "sessions"
+	sessionIdentityBucket        = "sessions:identities"
+	verificationKeyBucket        = "verification:keys"
+
+	// sweepLimit bounds the work any one write does removing expired values.
+	sweepLimit = 100
+)
+
+type FileTimeLockStore struct {
+	db       *kv.DB
+	bucket   string
+	clock    clockinterfaces.Clock
+	lifetime time.Duration
+}
+
+// NewFileTimeLockStore stores reservations in bucket, which must be distinct for each time lock
+// store sharing db.
+func NewFileTimeLockStore(db *kv.DB, bucket string, lifetime time.Duration) *FileTimeLockStore {
+	return NewFileTimeLockStoreWithClock(db, bucket, lifetime, clock.NewSystemClock())
+}
+
+func NewFileTimeLockStoreWithClock(db *kv.DB, bucket string, lifetime time.Duration, clock clockinterfaces.Clock) *FileTimeLockStore {
+	return &FileTimeLockStore{
+		db:       db,
+		bucket:   "timelock:" + bucket,
+		clock:    clock,
+		lifetime: lifetime,
+	}
+}
+
+func (store *FileTimeLockStore) Lifetime() time.Duration {
+	return store.lifetime
+}
+
+func (store *FileTimeLockStore) Reserve(ctx context.Context, value string) error {
+	return store.ReserveFor(ctx, value, store.lifetime)
+}
+
+func (store *FileTimeLockStore) ReserveFor(ctx context.Context, value string, lifetime time.Duration) error {
+	return store.db.Update(func(tx *kv.Tx) error {
+		now := store.clock.Now()
+
+		if err := sweep(tx, store.bucket, now); err != nil {
+			return err
+		}
+
+		if _, ok := getExpiring(tx, store.bucket, value, now); ok {
+			return storageinterfaces.ErrReserved
+		}
+
+		return putExpiring(tx, store.bucket, value, nil, now.Add(lifetime))
+	})
+}
+
+type FileAuthenticationNonceStore struct {
+	db       *kv.DB
+	clock    clockinterfaces.Clock
+	lifetime time.Duration
+	noncer   cryptointerfaces.Noncer
+}
+
+func NewFileAuthenticationNonceStore(db *kv.DB, nonceLifetime time.Duration) *FileAuthenticationNonceStore {
+	return NewFileAuthenticationNonceStoreWithClock(db, nonceLifetime, clock.NewSystemClock())
+}
+
+func NewFileAuthenticationNonceStoreWithClock(db *kv.DB, nonceLifetime time.Duration, clock clockinterfaces.Clock) *FileAuthenticationNonceStore {
+	return &FileAuthenticationNonceStore{
+		db:       db,
+		clock:    clock,
+		lifetime: nonceLifetime,
+		noncer:   crypto.NewNoncer(),
+	}
+}
+
+func (s *FileAuthenticationNonceStore) Generate(ctx context.Context, identity string) (string, error) {
+	nonce, err := s.noncer.Generate128()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Update(func(tx *kv.Tx) error {
+		now := s.clock.Now()
+
+		if err := sweep(tx, authenticationNonceBucket, now); err != nil {
+			return err
+		}
+
+		return putExpiring(tx, authenticationNonceBucket, nonce, []byte(identity), now.Add(s.lifetime))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return nonce, nil
+}
+
+func (s *FileAuthenticationNonceStore) Verify(ctx context.Context, nonce string) (string, error) {
+	var identity string
+
+	err := s.db.View(func(tx *kv.Tx) error {
+		value, ok := getExpiring(tx, authenticationNonceBucket, nonce, s.clock.Now())
+		if !ok {
+			return fmt.Errorf("nonce not found")
+		}
+
+		identity = string(value)
+
+		return nil
+	})
+
+	return identity, err
+}
+
+// FileRevocationStore forgets revocations after lifetime, which should be at least as long as the
+// longest-lived credential that may reference a revoked value.
+type FileRevocationStore struct {
+	db       *kv.DB
+	clock    clockinterfaces.Clock
+	lifetime time.Duration
+}
+
+func NewFileRevocationStore(db *kv.DB, lifetime time.Duration) *FileRevocationStore {
+	return NewFileRevocationStoreWithClock(db, lifetime, clock.NewSystemClock())
+}
+
+func NewFileRevocationStoreWithClock(db *kv.DB, lifetime time.Duration, clock clockinterfaces.Clock) *FileRevocationStore {
+	return &FileRevocationStore{
+		db:       db,
+		clock:    clock,
+		lifetime: lifetime,
+	}
+}
+
+func (store *FileRevocationStore) Revoke(ctx context.Context, value string) error {
+	return store.db.Update(func(tx *kv.Tx) error {
+		now := store.clock.Now()
+
+		if err := sweep(tx, revocationBucket, now); err != nil {
+			return err
+		}
+
+		return putExpiring(tx, revocationBucket, value, nil, now.Add(store.lifetime))
+	})
+}
+
+func (store *FileRevocationStore) IsRevoked(ctx context.Context, value string) (bool, error) {
+	var revoked bool
+
+	err := store.db.View(func(tx *kv.Tx) error {
+		_, revoked = getExpiring(tx, revocationBucket, value, store.clock.Now())
+		return nil
+	})
+
+	return revoked, err
+}
+
+type fileKeyState struct {
+	PublicKey    string `json:"publicKey"`
+	RotationHash string `json:"rotationHash"`
+}
+
+type FileAuthenticationKeyStore struct {
+	db *kv.DB
+}
+
+func NewFileAuthenticationKeyStore(db *kv.DB) *FileAuthenticationKeyStore {
+	return &FileAuthenticationKeyStore{
+		db: db,
+	}
+}
+
+func (s *FileAuthenticationKeyStore) Register(ctx context.Context, identity, device, publicKey, rotationHash string, existingIdentity bool) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		if _, ok := tx.Get(authenticationKeyBucket, deviceKey(identity, device)); ok {
+			return fmt.Errorf("already registered")
+		}
+
+		if err := tx.Put(authenticationIdentityBucket, identity, nil); err != nil {
+			return err
+		}
+
+		return putJSON(tx, authenticationKeyBucket, deviceKey(identity, device), fileKeyState{
+			PublicKey:    publicKey,
+			RotationHash: rotationHash,
+		})
+	})
+}
+
+func (s *FileAuthenticationKeyStore) Public(ctx context.Context, identity, device string) (string, error) {
+	var publicKey string
+
+	err := s.db.View(func(tx *kv.Tx) error {
+		state, err := s.state(tx, identity, device)
+		if err != nil {
+			return err
+		}
+
+		publicKey = state.PublicKey
+
+		return nil
+	})
+
+	return publicKey, err
+}
+
+func (s *FileAuthenticationKeyStore) Rotate(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash string) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		state, err := s.state(tx, identity, device)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(previousRotationHash, state.RotationHash) {
+			return storageinterfaces.ErrRotationConflict
+		}
+
+		return putJSON(tx, authenticationKeyBucket, deviceKey(identity, device), fileKeyState{
+			PublicKey:    publicKey,
+			RotationHash: rotationHash,
+		})
+	})
+}
+
+func (s *FileAuthenticationKeyStore) Link(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash, linkedDevice, linkedPublicKey, linkedRotationHash string) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		state, err := s.state(tx, identity, device)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(previousRotationHash, state.RotationHash) {
+			return storageinterfaces.ErrRotationConflict
+		}
+
+		if _, ok := tx.Get(authenticationKeyBucket, deviceKey(identity, linkedDevice)); ok {
+			return fmt.Errorf("already registered")
+		}
+
+		if err := putJSON(tx, authenticationKeyBucket, deviceKey(identity, device), fileKeyState{
+			PublicKey:    publicKey,
+			RotationHash: rotationHash,
+		}); err != nil {
+			return err
+		}
+
+		return putJSON(tx, authenticationKeyBucket, deviceKey(identity, linkedDevice), fileKeyState{
+			PublicKey:    linkedPublicKey,
+			RotationHash: linkedRotationHash,
+		})
+	})
+}
+
+func (s *FileAuthenticationKeyStore) RevokeDevice(ctx context.Context, identity, device string) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		if _, ok := tx.Get(authenticationIdentityBucket, identity); !ok {
+			return fmt.Errorf("account not found")
+		}
+
+		return tx.Delete(authenticationKeyBucket, deviceKey(identity, device))
+	})
+}
+
+// RevokeDevices removes every device of identity in one transaction.
+func (s *FileAuthenticationKeyStore) RevokeDevices(ctx context.Context, identity string) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		if err := tx.Put(authenticationIdentityBucket, identity, nil); err != nil {
+			return err
+		}
+
+		return deletePrefix(tx, authenticationKeyBucket, deviceKey(identity, ""))
+	})
+}
+
+// DeleteIdentity removes identity and all of its devices in one transaction.
+func (s *FileAuthenticationKeyStore) DeleteIdentity(ctx context.Context, identity string) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		if _, ok := tx.Get(authenticationIdentityBucket, identity); !ok {
+			return fmt.Errorf("account not found")
+		}
+
+		if err := tx.Delete(authenticationIdentityBucket, identity); err != nil {
+			return err
+		}
+
+		return deletePrefix(tx, authenticationKeyBucket, deviceKey(identity, ""))
+	})
+}
+
+func (s *FileAuthenticationKeyStore) EnsureActive(ctx context.Context, identity, device string) error {
+	return s.db.View(func(tx *kv.Tx) error {
+		if _, ok := tx.Get(authenticationKeyBucket, deviceKey(identity, device)); !ok {
+			return fmt.Errorf("not found")
+		}
+
+		return nil
+	})
+}
+
+func (s *FileAuthenticationKeyStore) state(tx *kv.Tx, identity, device string) (fileKeyState, error) {
+	var state fileKeyState
+
+	if _, ok := tx.Get(authenticationIdentityBucket, identity); !ok {
+		return state, fmt.Errorf("account not found")
+	}
+
+	found, err := getJSON(tx, authenticationKeyBucket, deviceKey(identity, device), &state)
+	if err != nil {
+		return state, err
+	}
+
+	if !found {
+		return state, fmt.Errorf("device not found")
+	}
+
+	return state, nil
+}
+
+type FileRecoveryHashStore struct {
+	db *kv.DB
+}
+
+func NewFileRecoveryHashStore(db *kv.DB) *FileRecoveryHashStore {
+	return &FileRecoveryHashStore{
+		db: db,
+	}
+}
+
+func (store *FileRecoveryHashStore) Register(ctx context.Context, identity, hash string) error {
+	return store.db.Update(func(tx *kv.Tx) error {
+		if _, ok := tx.Get(recoveryHashBucket, identity); ok {
+			return fmt.Errorf("already exists")
+		}
+
+		return tx.Put(recoveryHashBucket, identity, []byte(hash))
+	})
+}
+
+func (store *FileRecoveryHashStore) Rotate(ctx context.Context, identity, oldHash, newHash string) error {
+	return store.db.Update(func(tx *kv.Tx) error {
+		stored, ok := tx.Get(recoveryHashBucket, identity)
+		if !ok {
+			return fmt.Errorf("not found")
+		}
+
+		if !strings.EqualFold(string(stored), oldHash) {
+			return fmt.Errorf("incorrect hash")
+		}
+
+		return tx.Put(recoveryHashBucket, identity, []byte(newHash))
+	})
+}
+
+func (store *FileRecoveryHashStore) Change(ctx context.Context, identity, keyHash string) error {
+	return store.db.Update(func(tx *kv.Tx) error {
+		if _, ok := tx.Get(recoveryHashBucket, identity); !ok {
+			return fmt.Errorf("not found")
+		}
+
+		return tx.Put(recoveryHashBucket, identity, []byte(keyHash))
+	})
+}
+
+type FileSessionStore struct {
+	db    *kv.DB
+	clock clockinterfaces.Clock
+}
+
+func NewFileSessionStore(db *kv.DB) *FileSessionStore {
+	return NewFileSessionStoreWithClock(db, clock.NewSystemClock())
+}
+
+func NewFileSessionStoreWithClock(db *kv.DB, clock clockinterfaces.Clock) *FileSessionStore {
+	return &FileSessionStore{
+		db:    db,
+		clock: clock,
+	}
+}
+
+func (s *FileSessionStore) Create(ctx context.Context, session, identity, device string, expiresAt time.Time) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		now := s.clock.Now()
+
+		if err := sweepSessions(tx, now); err != nil {
+			return err
+		}
+
+		if _, ok := tx.Get(sessionBucket, session); ok {
+			return fmt.Errorf("already exists")
+		}
+
+		return putSession(tx, storageinterfaces.Session{
+			Id:          session,
+			Identity:    identity,
+			Device:      device,
+			CreatedAt:   now,
+			RefreshedAt: now,
+			ExpiresAt:   expiresAt,
+		})
+	})
+}
+
+func (s *FileSessionStore) Refresh(ctx context.Context, session string, expiresAt time.Time) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		now := s.clock.Now()
+
+		record, found, err := getSession(tx, session, now)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return baerrors.NewUnknownSessionError(session)
+		}
+
+		record.RefreshedAt = now
+		record.ExpiresAt = expiresAt
+
+		return putSession(tx, record)
+	})
+}
+
+func (s *FileSessionStore) List(ctx context.Context, identity string) ([]storageinterfaces.Session, error) {
+	sessions := []storageinterfaces.Session{}
+
+	err := s.db.View(func(tx *kv.Tx) error {
+		now := s.clock.Now()
+
+		return tx.Scan(sessionIdentityBucket, deviceKey(identity, ""), func(key string, _ []byte) error {
+			record, found, err := getSession(tx, strings.TrimPrefix(key, deviceKey(identity, "")), now)
+			if err != nil || !found {
+				return err
+			}
+
+			sessions = append(sessions, record)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(sessions, func(a, b storageinterfaces.Session) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return sessions, nil
+}
+
+func (s *FileSessionStore) Terminate(ctx context.Context, identity, session string) error {
+	return s.db.Update(func(tx *kv.Tx) error {
+		record, found, err := getSession(tx, session, s.clock.Now())
+		if err != nil {
+			return err
+		}
+
+		if !found || record.Identity != identity {
+			return baerrors.NewUnknownSessionError(session)
+		}
+
+		if err := deleteExpiring(tx, sessionIdentityBucket, deviceKey(identity, session)); err != nil {
+			return err
+		}
+
+		return deleteExpiring(tx, sessionBucket, session)
+	})
+}
+
+// putSession stores a session and its identity index entry, both expiring with the session.
+func putSession(tx *kv.Tx, session storageinterfaces.Session) error {
+	encoded, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	if err := putExpiring(tx, sessionIdentityBucket, deviceKey(session.Identity, session.Id), nil, session.ExpiresAt); err != nil {
+		return err
+	}
+
+	return putExpiring(tx, sessionBucket, session.Id, encoded, session.ExpiresAt)
+}
+
+func getSession(tx *kv.Tx, session string, now time.Time) (storageinterfaces.Session, bool, error) {
+	var record storageinterfaces.Session
+
+	encoded, ok := getExpiring(tx, sessionBucket, session, now)
+	if !ok {
+		return record, false, nil
+	}
+
+	return record, true, json.Unmarshal(encoded, &record)
+}
+
+func sweepSessions(tx *kv.Tx, now time.Time) error {
+	if err := sweep(tx, sessionIdentityBucket, now); err != nil {
+		return err
+	}
+
+	return sweep(tx, sessionBucket, now)
+}
+
+// FileVerificationKeyStore persists public keys, verifying with a single verifier.
+type FileVerificationKeyStore struct {
+	db       *kv.DB
+	verifier cryptointerfaces.Verifier
+}
+
+func NewFileVerificationKeyStore(db *kv.DB, verifier cryptointerfaces.Verifier) *FileVerificationKeyStore {
+	return &FileVerificationKeyStore{
+		db:       db,
+		verifier: verifier,
+	}
+}
+
+func (s *FileVerificationKeyStore) Add(identity string, key cryptointerfaces.VerificationKey) error {
+	publicKey, err := key.Public()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *kv.Tx) error {
+		return tx.Put(verificationKeyBucket, identity, []byte(publicKey))
+	})
+}
+
+func (s *FileVerificationKeyStore) Get(ctx context.Context, identity string) (cryptointerfaces.VerificationKey, error) {
+	var key cryptointerfaces.VerificationKey
+
+	err := s.db.View(func(tx *kv.Tx) error {
+		publicKey, ok := tx.Get(verificationKeyBucket, identity)
+		if !ok {
+			return fmt.Errorf("key not found for identity: %s", identity)
+		}
+
+		key = &storedVerificationKey{publicKey: string(publicKey), verifier: s.verifier}
+
+		return nil
+	})
+
+	return key, err
+}
+
+type storedVerificationKey struct {
+	publicKey string
+	verifier  cryptointerfaces.Verifier
+}
+
+func (k *storedVerificationKey) Verifier() cryptointerfaces.Verifier {
+	return k.verifier
+}
+
+func (k *storedVerificationKey) Public() (string, error) {
+	return k.publicKey, nil
+}
+
+// deviceKey joins identity and a second component such that all keys of an identity share a prefix.
+// Identities are CESR encoded, so they never contain the separator.
+func deviceKey(identity, second string) string {
+	return identity + "/" + second
+}
+
+func putJSON(tx *kv.Tx, bucket, key string, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return tx.Put(bucket, key, encoded)
+}
+
+func getJSON(tx *kv.Tx, bucket, key string, value any) (bool, error) {
+	encoded, ok := tx.Get(bucket, key)
+	if !ok {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(encoded, value)
+}
+
+func deletePrefix(tx *kv.Tx, bucket, prefix string) error {
+	return tx.Scan(bucket, prefix, func(key string, _ []byte) error {
+		return tx.Delete(bucket, key)
+	})
+}
+
+// putExpiring stores value with its expiry, replacing any index entry for a previous value.
+func putExpiring(tx *kv.Tx, bucket, key string, value []byte, expiry time.Time) error {
+	if previous, ok := tx.Get(bucket, key); ok && len(previous) >= 8 {
+		if err := tx.Delete(expiryBucket(bucket), expiryKey(decodeExpiry(previous), key)); err != nil {
+			return err
+		}
+	}
+
+	encoded := binary.BigEndian.AppendUint64(nil, uint64(expiry.UnixNano()))
+	encoded = append(encoded, value...)
+
+	if err := tx.Put(bucket, key, encoded); err != nil {
+		return err
+	}
+
+	return tx.Put(expiryBucket(bucket), expiryKey(expiry, key), nil)
+}
+
+// getExpiring returns a value that has not yet expired at now.
+func getExpiring(tx *kv.Tx, bucket, key string, now time.Time) ([]byte, bool) {
+	encoded, ok := tx.Get(bucket, key)
+	if !ok || len(encoded) < 8 {
+		return nil, false
+	}
+
+	if !now.Before(decodeExpiry(encoded)) {
+		return nil, false
+	}
+
+	return encoded[8:], true
+}
+
+// deleteExpiring removes a value and its index entry.
+func deleteExpiring(tx *kv.Tx, bucket, key string) error {
+	if previous, ok := tx.Get(bucket, key); ok && len(previous) >= 8 {
+		if err := tx.Delete(expiryBucket(bucket), expiryKey(decodeExpiry(previous), key)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Delete(bucket, key)
+}
+
+var errSwept = errors.New("swept")
+
+// sweep deletes up to sweepLimit values that expired by now, oldest first.
+func sweep(tx *kv.Tx, bucket string, now time.Time) error {
+	swept := 0
+
+	err := tx.Scan(expiryBucket(bucket), "", func(indexKey string, _ []byte) error {
+		expiry, key := splitExpiryKey(indexKey)
+		if now.Before(expiry) || swept == sweepLimit {
+			return errSwept
+		}
+
+		if err := tx.Delete(expiryBucket(bucket), indexKey); err != nil {
+			return err
+		}
+
+		swept++
+
+		return tx.Delete(bucket, key)
+	})
+	if err == errSwept {
+		return nil
+	}
+
+	return err
+}
+
+func expiryBucket(bucket string) string {
+	return bucket + ":expiry"
+}
+
+// expiryKey orders index entries by expiry: fixed width hex of the expiry's unix nanoseconds.
+func expiryKey(expiry time.Time, key string) string {
+	return fmt.Sprintf("%016x/%s", uint64(expiry.UnixNano()), key)
+}
+
+func splitExpiryKey(indexKey string) (time.Time, string) {
+	nanoseconds, _ := strconv.ParseUint(indexKey[:16], 16, 64)
+
+	return time.Unix(0, int64(nanoseconds)), indexKey[17:]
+}
+
+func decodeExpiry(encoded []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(encoded[:8])))
+}
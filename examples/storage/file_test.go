@@ -0,0 +1,450 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/kv"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storagetest"
+)
+
+// fileDB opens a database that can be reopened, as a restarted process would.
+type fileDB struct {
+	t    *testing.T
+	path string
+	db   *kv.DB
+}
+
+func newFileDB(t *testing.T) *fileDB {
+	t.Helper()
+
+	f := &fileDB{t: t, path: filepath.Join(t.TempDir(), "store")}
+	f.reopen()
+	t.Cleanup(func() { f.db.Close() })
+
+	return f
+}
+
+func (f *fileDB) reopen() {
+	f.t.Helper()
+
+	if f.db != nil {
+		f.db.Close()
+	}
+
+	db, err := kv.Open(f.path, kv.NoSync())
+	if err != nil {
+		f.t.Fatalf("failed to open: %v", err)
+	}
+
+	f.db = db
+}
+
+func TestFileTimeLockStore(t *testing.T) {
+	f := newFileDB(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	store := storage.NewFileTimeLockStoreWithClock(f.db, "access", 30*time.Second, clock)
+	if err := store.Reserve(ctx, "value"); err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+
+	f.reopen()
+	store = storage.NewFileTimeLockStoreWithClock(f.db, "access", 30*time.Second, clock)
+
+	if err := store.Reserve(ctx, "value"); !errors.Is(err, storageinterfaces.ErrReserved) {
+		t.Fatalf("expected the reservation to survive a restart, got %v", err)
+	}
+
+	other := storage.NewFileTimeLockStoreWithClock(f.db, "keyhash", 30*time.Second, clock)
+	if err := other.Reserve(ctx, "value"); err != nil {
+		t.Fatalf("expected buckets to separate stores: %v", err)
+	}
+
+	clock.Advance(30 * time.Second)
+
+	if err := store.Reserve(ctx, "value"); err != nil {
+		t.Fatalf("expected the reservation to have lapsed: %v", err)
+	}
+}
+
+func TestFileTimeLockStoreSweeps(t *testing.T) {
+	f := newFileDB(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	store := storage.NewFileTimeLockStoreWithClock(f.db, "access", time.Second, clock)
+	for i := range 50 {
+		if err := store.Reserve(ctx, string(rune('a'+i))); err != nil {
+			t.Fatalf("failed to reserve: %v", err)
+		}
+	}
+
+	if err := f.db.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	full := f.db.Size()
+
+	clock.Advance(time.Second)
+
+	if err := store.Reserve(ctx, "fresh"); err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+
+	if err := f.db.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+
+	if f.db.Size() >= full/10 {
+		t.Errorf("expected expired reservations to be swept, log went from %d to %d bytes", full, f.db.Size())
+	}
+}
+
+func TestFileSessionStoreSweeps(t *testing.T) {
+	f := newFileDB(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	store := storage.NewFileSessionStoreWithClock(f.db, clock)
+	for i := range 50 {
+		if err := store.Create(ctx, fmt.Sprintf("session-%d", i), "identity", "device", clock.Now().Add(time.Second)); err != nil {
+			t.Fatalf("failed to create: %v", err)
+		}
+	}
+
+	if err := f.db.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	full := f.db.Size()
+
+	clock.Advance(time.Second)
+
+	if err := store.Create(ctx, "fresh", "identity", "device", clock.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	if err := f.db.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+
+	if f.db.Size() >= full/10 {
+		t.Errorf("expected expired sessions to be swept, log went from %d to %d bytes", full, f.db.Size())
+	}
+}
+
+func TestFileAuthenticationNonceStore(t *testing.T) {
+	f := newFileDB(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	store := storage.NewFileAuthenticationNonceStoreWithClock(f.db, time.Minute, clock)
+	nonce, err := store.Generate(ctx, "identity")
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	f.reopen()
+	store = storage.NewFileAuthenticationNonceStoreWithClock(f.db, time.Minute, clock)
+
+	identity, err := store.Verify(ctx, nonce)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+
+	if identity != "identity" {
+		t.Errorf("expected identity, got %q", identity)
+	}
+
+	clock.Advance(time.Minute)
+
+	if _, err := store.Verify(ctx, nonce); err == nil {
+		t.Errorf("expected expired nonce to fail")
+	}
+}
+
+func TestFileRevocationStore(t *testing.T) {
+	f := newFileDB(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+
+	store := storage.NewFileRevocationStoreWithClock(f.db, time.Hour, clock)
+	if err := store.Revoke(ctx, "session"); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+
+	clock.Advance(30 * time.Minute)
+
+	// revoking again extends the revocation
+	if err := store.Revoke(ctx, "session"); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+
+	f.reopen()
+	store = storage.NewFileRevocationStoreWithClock(f.db, time.Hour, clock)
+
+	clock.Advance(45 * time.Minute)
+
+	if revoked, err := store.IsRevoked(ctx, "session"); err != nil || !revoked {
+		t.Fatalf("expected session to be revoked (%v)", err)
+	}
+
+	clock.Advance(15 * time.Minute)
+
+	if revoked, err := store.IsRevoked(ctx, "session"); err != nil || revoked {
+		t.Fatalf("expected revocation to have lapsed (%v)", err)
+	}
+}
+
+func TestFileAuthenticationKeyStore(t *testing.T) {
+	f := newFileDB(t)
+	hasher := crypto.NewBlake3()
+	ctx := context.Background()
+
+	store := storage.NewFileAuthenticationKeyStore(f.db)
+
+	nextKey := "next"
+	if err := store.Register(ctx, "identity", "device-1", "first", hasher.Sum([]byte(nextKey)), false); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if err := store.Register(ctx, "identity", "device-1", "first", "hash", true); err == nil {
+		t.Fatalf("expected duplicate registration to fail")
+	}
+
+	if err := store.Register(ctx, "identity", "device-2", "second", "hash", true); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if err := store.Rotate(ctx, "identity", "device-1", hasher.Sum([]byte("wrong")), "wrong", "hash"); !errors.Is(err, storageinterfaces.ErrRotationConflict) {
+		t.Fatalf("expected rotation with the wrong key to conflict, got %v", err)
+	}
+
+	if err := store.Rotate(ctx, "identity", "device-1", hasher.Sum([]byte(nextKey)), nextKey, "hash"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	f.reopen()
+	store = storage.NewFileAuthenticationKeyStore(f.db)
+
+	if publicKey, err := store.Public(ctx, "identity", "device-1"); err != nil || publicKey != nextKey {
+		t.Fatalf("expected rotated key to persist, got %q (%v)", publicKey, err)
+	}
+
+	if err := store.RevokeDevices(ctx, "identity"); err != nil {
+		t.Fatalf("failed to revoke devices: %v", err)
+	}
+
+	for _, device := range []string{"device-1", "device-2"} {
+		if err := store.EnsureActive(ctx, "identity", device); err == nil {
+			t.Errorf("expected %s to be revoked", device)
+		}
+	}
+
+	if err := store.Register(ctx, "identity", "device-3", "third", "hash", true); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if err := store.DeleteIdentity(ctx, "identity"); err != nil {
+		t.Fatalf("failed to delete identity: %v", err)
+	}
+
+	if err := store.DeleteIdentity(ctx, "identity"); err == nil {
+		t.Fatalf("expected deleting a deleted identity to fail")
+	}
+
+	f.reopen()
+	store = storage.NewFileAuthenticationKeyStore(f.db)
+
+	if _, err := store.Public(ctx, "identity", "device-3"); err == nil {
+		t.Fatalf("expected identity deletion to persist")
+	}
+}
+
+func TestFileRecoveryHashStore(t *testing.T) {
+	f := newFileDB(t)
+	ctx := context.Background()
+
+	store := storage.NewFileRecoveryHashStore(f.db)
+	if err := store.Register(ctx, "identity", "first"); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if err := store.Register(ctx, "identity", "first"); err == nil {
+		t.Fatalf("expected duplicate registration to fail")
+	}
+
+	if err := store.Rotate(ctx, "identity", "wrong", "second"); err == nil {
+		t.Fatalf("expected rotation with the wrong hash to fail")
+	}
+
+	if err := store.Rotate(ctx, "identity", "first", "second"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	f.reopen()
+	store = storage.NewFileRecoveryHashStore(f.db)
+
+	if err := store.Rotate(ctx, "identity", "second", "third"); err != nil {
+		t.Fatalf("expected rotation to persist: %v", err)
+	}
+
+	if err := store.Change(ctx, "unknown", "hash"); err == nil {
+		t.Fatalf("expected changing an unknown identity to fail")
+	}
+}
+
+func TestFileSessionStore(t *testing.T) {
+	f := newFileDB(t)
+	clock := clock.NewManualClock(time.Now())
+	ctx := context.Background()
+	expiresAt := clock.Now().Add(time.Hour)
+
+	store := storage.NewFileSessionStoreWithClock(f.db, clock)
+	if err := store.Create(ctx, "session-b", "identity", "device", expiresAt); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	clock.Advance(time.Second)
+
+	if err := store.Create(ctx, "session-a", "identity", "device", expiresAt); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	if err := store.Create(ctx, "session-c", "other", "device", expiresAt); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	clock.Advance(time.Second)
+
+	if err := store.Refresh(ctx, "session-b", expiresAt); err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+
+	f.reopen()
+	store = storage.NewFileSessionStoreWithClock(f.db, clock)
+
+	sessions, err := store.List(ctx, "identity")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	if len(sessions) != 2 || sessions[0].Id != "session-b" || sessions[1].Id != "session-a" {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+
+	if !sessions[0].RefreshedAt.Equal(clock.Now()) {
+		t.Errorf("expected refresh time to persist")
+	}
+
+	if err := store.Terminate(ctx, "other", "session-a"); err == nil {
+		t.Fatalf("expected terminating another identity's session to fail")
+	}
+
+	if err := store.Terminate(ctx, "identity", "session-a"); err != nil {
+		t.Fatalf("failed to terminate: %v", err)
+	}
+
+	if err := store.Refresh(ctx, "session-a", expiresAt); err == nil {
+		t.Fatalf("expected refreshing a terminated session to fail")
+	}
+
+	sessions, err = store.List(ctx, "identity")
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("expected one session to remain, got %+v (%v)", sessions, err)
+	}
+}
+
+func TestFileVerificationKeyStore(t *testing.T) {
+	f := newFileDB(t)
+	ctx := context.Background()
+
+	key, err := crypto.NewSecp256r1()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	identity, err := key.Identity()
+	if err != nil {
+		t.Fatalf("failed to derive identity: %v", err)
+	}
+
+	store := storage.NewFileVerificationKeyStore(f.db, crypto.NewSecp256r1Verifier())
+	if err := store.Add(identity, key); err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+
+	f.reopen()
+	store = storage.NewFileVerificationKeyStore(f.db, crypto.NewSecp256r1Verifier())
+
+	stored, err := store.Get(ctx, identity)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+
+	publicKey, err := stored.Public()
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+
+	signature, err := key.Sign([]byte("message"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	if err := stored.Verifier().Verify(signature, publicKey, []byte("message")); err != nil {
+		t.Fatalf("expected the stored key to verify: %v", err)
+	}
+
+	if _, err := store.Get(ctx, "unknown"); err == nil {
+		t.Fatalf("expected an unknown identity to fail")
+	}
+}
+
+func TestFileAuthenticationKeyStoreConformance(t *testing.T) {
+	storagetest.TestAuthenticationKeyStore(t, func(t *testing.T) storageinterfaces.AuthenticationKeyStore {
+		return storage.NewFileAuthenticationKeyStore(newFileDB(t).db)
+	})
+}
+
+func TestFileAuthenticationNonceStoreConformance(t *testing.T) {
+	storagetest.TestAuthenticationNonceStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.AuthenticationNonceStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewFileAuthenticationNonceStoreWithClock(newFileDB(t).db, lifetime, clock), clock.Advance
+	})
+}
+
+func TestFileTimeLockStoreConformance(t *testing.T) {
+	storagetest.TestTimeLockStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.TimeLockStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewFileTimeLockStoreWithClock(newFileDB(t).db, "access", lifetime, clock), clock.Advance
+	})
+}
+
+func TestFileRevocationStoreConformance(t *testing.T) {
+	storagetest.TestRevocationStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.RevocationStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewFileRevocationStoreWithClock(newFileDB(t).db, lifetime, clock), clock.Advance
+	})
+}
+
+func TestFileRecoveryHashStoreConformance(t *testing.T) {
+	storagetest.TestRecoveryHashStore(t, func(t *testing.T) storageinterfaces.RecoveryHashStore {
+		return storage.NewFileRecoveryHashStore(newFileDB(t).db)
+	})
+}
+
+func TestFileSessionStoreConformance(t *testing.T) {
+	storagetest.TestSessionStore(t, func(t *testing.T) (storageinterfaces.SessionStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewFileSessionStoreWithClock(newFileDB(t).db, clock), clock.Advance
+	})
+}
@@ -0,0 +1,51 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storagetest"
+)
+
+func TestInMemoryAuthenticationKeyStore(t *testing.T) {
+	storagetest.TestAuthenticationKeyStore(t, func(t *testing.T) storageinterfaces.AuthenticationKeyStore {
+		return storage.NewInMemoryAuthenticationKeyStore()
+	})
+}
+
+func TestInMemoryAuthenticationNonceStore(t *testing.T) {
+	storagetest.TestAuthenticationNonceStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.AuthenticationNonceStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewInMemoryAuthenticationNonceStoreWithClock(lifetime, clock), clock.Advance
+	})
+}
+
+func TestInMemoryTimeLockStore(t *testing.T) {
+	storagetest.TestTimeLockStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.TimeLockStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewInMemoryTimeLockStoreWithClock(lifetime, clock), clock.Advance
+	})
+}
+
+func TestInMemoryRevocationStore(t *testing.T) {
+	storagetest.TestRevocationStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.RevocationStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewInMemoryRevocationStoreWithClock(lifetime, clock), clock.Advance
+	})
+}
+
+func TestInMemoryRecoveryHashStore(t *testing.T) {
+	storagetest.TestRecoveryHashStore(t, func(t *testing.T) storageinterfaces.RecoveryHashStore {
+		return storage.NewInMemoryRecoveryHashStore()
+	})
+}
+
+func TestInMemorySessionStore(t *testing.T) {
+	storagetest.TestSessionStore(t, func(t *testing.T) (storageinterfaces.SessionStore, storagetest.Advance) {
+		clock := clock.NewManualClock(time.Now())
+		return storage.NewInMemorySessionStoreWithClock(clock), clock.Advance
+	})
+}
@@ -0,0 +1,166 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/resp"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storagetest"
+)
+
+func newRedis(t *testing.T) (*clock.ManualClock, string) {
+	t.Helper()
+
+	clock := clock.NewManualClock(time.Now())
+	server := resp.NewServerWithClock(clock)
+
+	address, err := server.Start()
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return clock, address
+}
+
+func newRedisClient(t *testing.T, address string) *resp.Client {
+	t.Helper()
+
+	client := resp.NewClient(address)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestRedisTimeLockStoreAcrossReplicas(t *testing.T) {
+	clock, address := newRedis(t)
+	ctx := context.Background()
+
+	replicaA := storage.NewRedisTimeLockStore(newRedisClient(t, address), "nonce:", 30*time.Second)
+	replicaB := storage.NewRedisTimeLockStore(newRedisClient(t, address), "nonce:", 30*time.Second)
+
+	if err := replicaA.Reserve(ctx, "value"); err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+
+	if err := replicaB.Reserve(ctx, "value"); !errors.Is(err, storageinterfaces.ErrReserved) {
+		t.Fatalf("expected replay on another replica to be refused, got %v", err)
+	}
+
+	clock.Advance(29 * time.Second)
+
+	if err := replicaA.Reserve(ctx, "value"); !errors.Is(err, storageinterfaces.ErrReserved) {
+		t.Fatalf("expected reservation to hold, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+
+	if err := replicaB.Reserve(ctx, "value"); err != nil {
+		t.Fatalf("expected reservation to have lapsed: %v", err)
+	}
+
+	other := storage.NewRedisTimeLockStore(newRedisClient(t, address), "keyhash:", 30*time.Second)
+	if err := other.Reserve(ctx, "value"); err != nil {
+		t.Fatalf("expected prefixes to separate stores: %v", err)
+	}
+}
+
+func TestRedisTimeLockStoreConcurrentReserve(t *testing.T) {
+	_, address := newRedis(t)
+	ctx := context.Background()
+
+	stores := []*storage.RedisTimeLockStore{
+		storage.NewRedisTimeLockStore(newRedisClient(t, address), "", time.Minute),
+		storage.NewRedisTimeLockStore(newRedisClient(t, address), "", time.Minute),
+	}
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := stores[i%len(stores)].Reserve(ctx, "contested")
+			switch {
+			case err == nil:
+				reserved.Add(1)
+			case !errors.Is(err, storageinterfaces.ErrReserved):
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if reserved.Load() != 1 {
+		t.Fatalf("expected exactly one reservation, got %d", reserved.Load())
+	}
+}
+
+func TestRedisAuthenticationNonceStore(t *testing.T) {
+	clock, address := newRedis(t)
+	ctx := context.Background()
+
+	replicaA := storage.NewRedisAuthenticationNonceStore(newRedisClient(t, address), "challenge:", time.Minute)
+	replicaB := storage.NewRedisAuthenticationNonceStore(newRedisClient(t, address), "challenge:", time.Minute)
+
+	nonce, err := replicaA.Generate(ctx, "identity")
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	identity, err := replicaB.Verify(ctx, nonce)
+	if err != nil {
+		t.Fatalf("failed to verify on another replica: %v", err)
+	}
+
+	if identity != "identity" {
+		t.Errorf("expected identity, got %q", identity)
+	}
+
+	if _, err := replicaB.Verify(ctx, "unknown"); err == nil {
+		t.Errorf("expected unknown nonce to fail")
+	}
+
+	clock.Advance(time.Minute)
+
+	if _, err := replicaA.Verify(ctx, nonce); err == nil {
+		t.Errorf("expected expired nonce to fail")
+	}
+}
+
+func TestRedisStoreContextCancellation(t *testing.T) {
+	_, address := newRedis(t)
+
+	store := storage.NewRedisTimeLockStore(newRedisClient(t, address), "", time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := store.Reserve(ctx, "value"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+}
+
+func TestRedisTimeLockStoreConformance(t *testing.T) {
+	storagetest.TestTimeLockStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.TimeLockStore, storagetest.Advance) {
+		clock, address := newRedis(t)
+		return storage.NewRedisTimeLockStore(newRedisClient(t, address), "access:", lifetime), clock.Advance
+	})
+}
+
+func TestRedisAuthenticationNonceStoreConformance(t *testing.T) {
+	storagetest.TestAuthenticationNonceStore(t, func(t *testing.T, lifetime time.Duration) (storageinterfaces.AuthenticationNonceStore, storagetest.Advance) {
+		clock, address := newRedis(t)
+		return storage.NewRedisAuthenticationNonceStore(newRedisClient(t, address), "nonce:", lifetime), clock.Advance
+	})
+}
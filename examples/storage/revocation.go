@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+)
+
+// InMemoryRevocationStore forgets revocations after lifetime, which should be at least as long as
+// the longest-lived credential that may reference a revoked value.
+type InMemoryRevocationStore struct {
+	mu       sync.RWMutex
+	clock    clockinterfaces.Clock
+	lifetime time.Duration
+	values   map[string]time.Time
+}
+
+func NewInMemoryRevocationStore(lifetime time.Duration) *InMemoryRevocationStore {
+	return NewInMemoryRevocationStoreWithClock(lifetime, clock.NewSystemClock())
+}
+
+func NewInMemoryRevocationStoreWithClock(lifetime time.Duration, clock clockinterfaces.Clock) *InMemoryRevocationStore {
+	return &InMemoryRevocationStore{
+		clock:    clock,
+		lifetime: lifetime,
+		values:   map[string]time.Time{},
+	}
+}
+
+func (store *InMemoryRevocationStore) Revoke(ctx context.Context, value string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.values[value] = store.clock.Now().Add(store.lifetime)
+
+	return nil
+}
+
+func (store *InMemoryRevocationStore) IsRevoked(ctx context.Context, value string) (bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	expiresAt, ok := store.values[value]
+	if !ok {
+		return false, nil
+	}
+
+	return store.clock.Now().Before(expiresAt), nil
+}
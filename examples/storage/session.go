@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	baerrors "github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+type InMemorySessionStore struct {
+	mu       sync.RWMutex
+	clock    clockinterfaces.Clock
+	sessions map[string]storageinterfaces.Session
+}
+
+func NewInMemorySessionStore() *InMemorySessionStore {
+	return NewInMemorySessionStoreWithClock(clock.NewSystemClock())
+}
+
+func NewInMemorySessionStoreWithClock(clock clockinterfaces.Clock) *InMemorySessionStore {
+	return &InMemorySessionStore{
+		clock:    clock,
+		sessions: map[string]storageinterfaces.Session{},
+	}
+}
+
+func (s *InMemorySessionStore) Create(ctx context.Context, session, identity, device string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	for id, record := range s.sessions {
+		if !now.Before(record.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+
+	_, ok := s.sessions[session]
+	if ok {
+		return fmt.Errorf("already exists")
+	}
+
+	s.sessions[session] = storageinterfaces.Session{
+		Id:          session,
+		Identity:    identity,
+		Device:      device,
+		CreatedAt:   now,
+		RefreshedAt: now,
+		ExpiresAt:   expiresAt,
+	}
+
+	return nil
+}
+
+func (s *InMemorySessionStore) Refresh(ctx context.Context, session string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	record, ok := s.sessions[session]
+	if !ok || !now.Before(record.ExpiresAt) {
+		return baerrors.NewUnknownSessionError(session)
+	}
+
+	record.RefreshedAt = now
+	record.ExpiresAt = expiresAt
+	s.sessions[session] = record
+
+	return nil
+}
+
+func (s *InMemorySessionStore) List(ctx context.Context, identity string) ([]storageinterfaces.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.clock.Now()
+
+	sessions := []storageinterfaces.Session{}
+	for _, record := range s.sessions {
+		if record.Identity == identity && now.Before(record.ExpiresAt) {
+			sessions = append(sessions, record)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b storageinterfaces.Session) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return sessions, nil
+}
+
+func (s *InMemorySessionStore) Terminate(ctx context.Context, identity, session string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.sessions[session]
+	if !ok || record.Identity != identity {
+		return baerrors.NewUnknownSessionError(session)
+	}
+
+	delete(s.sessions, session)
+
+	return nil
+}
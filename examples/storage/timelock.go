@@ -2,19 +2,28 @@ package storage
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
 )
 
 type InMemoryTimeLockStore struct {
 	mu       sync.RWMutex
+	clock    clockinterfaces.Clock
 	lifetime time.Duration
 	values   map[string]time.Time
 }
 
 func NewInMemoryTimeLockStore(lifetime time.Duration) *InMemoryTimeLockStore {
+	return NewInMemoryTimeLockStoreWithClock(lifetime, clock.NewSystemClock())
+}
+
+func NewInMemoryTimeLockStoreWithClock(lifetime time.Duration, clock clockinterfaces.Clock) *InMemoryTimeLockStore {
 	return &InMemoryTimeLockStore{
+		clock:    clock,
 		lifetime: lifetime,
 		values:   map[string]time.Time{},
 	}
@@ -25,20 +34,21 @@ func (store *InMemoryTimeLockStore) Lifetime() time.Duration {
 }
 
 func (store *InMemoryTimeLockStore) Reserve(ctx context.Context, value string) error {
+	return store.ReserveFor(ctx, value, store.lifetime)
+}
+
+func (store *InMemoryTimeLockStore) ReserveFor(ctx context.Context, value string, lifetime time.Duration) error {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
+	now := store.clock.Now()
 	validAt, ok := store.values[value]
 
-	if ok {
-		now := time.Now()
-
-		if now.Before(validAt) {
-			return fmt.Errorf("value reserved too recently")
-		}
+	if ok && now.Before(validAt) {
+		return storageinterfaces.ErrReserved
 	}
 
-	newValidAt := time.Now().Add(store.lifetime)
+	newValidAt := now.Add(lifetime)
 	store.values[value] = newValidAt
 
 	return nil
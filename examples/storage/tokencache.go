@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// TokenCacheStats counts cache activity since the cache was created.
+type TokenCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Expirations   uint64
+	Evictions     uint64
+	Invalidations uint64
+	Size          int
+}
+
+// InMemoryTokenCache holds at most capacity tokens, evicting the least recently used when full.
+type InMemoryTokenCache struct {
+	mu       sync.Mutex
+	clock    clockinterfaces.Clock
+	capacity int
+
+	entries          map[string]*list.Element
+	recency          *list.List
+	bySession        map[string]map[string]struct{}
+	byServerIdentity map[string]map[string]struct{}
+	stats            TokenCacheStats
+}
+
+type tokenCacheEntry struct {
+	token  string
+	cached *storageinterfaces.CachedToken
+}
+
+func NewInMemoryTokenCache(capacity int) *InMemoryTokenCache {
+	return NewInMemoryTokenCacheWithClock(capacity, clock.NewSystemClock())
+}
+
+func NewInMemoryTokenCacheWithClock(capacity int, clock clockinterfaces.Clock) *InMemoryTokenCache {
+	return &InMemoryTokenCache{
+		clock:            clock,
+		capacity:         capacity,
+		entries:          map[string]*list.Element{},
+		recency:          list.New(),
+		bySession:        map[string]map[string]struct{}{},
+		byServerIdentity: map[string]map[string]struct{}{},
+	}
+}
+
+func (c *InMemoryTokenCache) Get(token string) (*storageinterfaces.CachedToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[token]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*tokenCacheEntry)
+	if c.clock.Now().After(entry.cached.Expiry) {
+		c.remove(element)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.recency.MoveToFront(element)
+	c.stats.Hits++
+
+	return entry.cached, true
+}
+
+func (c *InMemoryTokenCache) Put(token string, cached *storageinterfaces.CachedToken) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[token]; ok {
+		c.remove(element)
+	}
+
+	for c.recency.Len() >= c.capacity {
+		c.remove(c.recency.Back())
+		c.stats.Evictions++
+	}
+
+	c.entries[token] = c.recency.PushFront(&tokenCacheEntry{token: token, cached: cached})
+	addToIndex(c.bySession, cached.Session, token)
+	addToIndex(c.byServerIdentity, cached.ServerIdentity, token)
+}
+
+func (c *InMemoryTokenCache) InvalidateSession(session string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(c.bySession[session])
+}
+
+func (c *InMemoryTokenCache) InvalidateServerIdentity(serverIdentity string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(c.byServerIdentity[serverIdentity])
+}
+
+func (c *InMemoryTokenCache) Stats() TokenCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.recency.Len()
+
+	return stats
+}
+
+func (c *InMemoryTokenCache) invalidate(tokens map[string]struct{}) {
+	for token := range tokens {
+		c.remove(c.entries[token])
+		c.stats.Invalidations++
+	}
+}
+
+func (c *InMemoryTokenCache) remove(element *list.Element) {
+	entry := element.Value.(*tokenCacheEntry)
+
+	c.recency.Remove(element)
+	delete(c.entries, entry.token)
+	removeFromIndex(c.bySession, entry.cached.Session, entry.token)
+	removeFromIndex(c.byServerIdentity, entry.cached.ServerIdentity, entry.token)
+}
+
+func addToIndex(by map[string]map[string]struct{}, key, token string) {
+	if key == "" {
+		return
+	}
+
+	tokens, ok := by[key]
+	if !ok {
+		tokens = map[string]struct{}{}
+		by[key] = tokens
+	}
+
+	tokens[token] = struct{}{}
+}
+
+func removeFromIndex(by map[string]map[string]struct{}, key, token string) {
+	tokens, ok := by[key]
+	if !ok {
+		return
+	}
+
+	delete(tokens, token)
+	if len(tokens) == 0 {
+		delete(by, key)
+	}
+}
@@ -0,0 +1,108 @@
+package storage_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+func TestInMemoryTokenCache(t *testing.T) {
+	clock := clock.NewManualClock(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	cache := storage.NewInMemoryTokenCacheWithClock(3, clock)
+
+	put := func(token, server, session string, lifetime time.Duration) {
+		cache.Put(token, &storageinterfaces.CachedToken{
+			Token:          token,
+			ServerIdentity: server,
+			Session:        session,
+			Expiry:         clock.Now().Add(lifetime),
+		})
+	}
+
+	expect := func(token string, present bool) {
+		t.Helper()
+
+		cached, ok := cache.Get(token)
+		if ok != present {
+			t.Fatalf("%s: expected present %v", token, present)
+		}
+
+		if ok && cached.Token != token {
+			t.Fatalf("%s: got %v", token, cached.Token)
+		}
+	}
+
+	put("a", "server 1", "session 1", time.Minute)
+	put("b", "server 1", "session 2", time.Minute)
+	put("c", "server 2", "session 2", time.Hour)
+	expect("a", true)
+
+	// b is now least recently used
+	put("d", "server 2", "session 3", time.Minute)
+	expect("b", false)
+	expect("a", true)
+	expect("c", true)
+
+	clock.Advance(2 * time.Minute)
+	expect("a", false)
+	expect("c", true)
+
+	put("e", "server 1", "session 2", time.Hour)
+	cache.InvalidateSession("session 2")
+	expect("c", false)
+	expect("e", false)
+
+	put("f", "server 1", "", time.Hour)
+	put("g", "server 2", "", time.Hour)
+	cache.InvalidateServerIdentity("server 1")
+	expect("f", false)
+	expect("g", true)
+
+	stats := cache.Stats()
+	expected := storage.TokenCacheStats{
+		Hits:          5,
+		Misses:        5,
+		Expirations:   1,
+		Evictions:     1,
+		Invalidations: 3,
+		// d has expired, but is only dropped when looked up or evicted
+		Size: 2,
+	}
+
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestInMemoryTokenCacheCapacity(t *testing.T) {
+	cache := storage.NewInMemoryTokenCache(0)
+	cache.Put("token", &storageinterfaces.CachedToken{Expiry: time.Now().Add(time.Hour)})
+
+	if _, ok := cache.Get("token"); ok {
+		t.Error("expected a cache without capacity to hold nothing")
+	}
+
+	cache = storage.NewInMemoryTokenCache(100)
+	for i := range 1000 {
+		cache.Put(fmt.Sprint(i), &storageinterfaces.CachedToken{
+			Session: fmt.Sprint(i % 7),
+			Expiry:  time.Now().Add(time.Hour),
+		})
+	}
+
+	if stats := cache.Stats(); stats.Size != 100 || stats.Evictions != 900 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	for i := range 7 {
+		cache.InvalidateSession(fmt.Sprint(i))
+	}
+
+	if stats := cache.Stats(); stats.Size != 0 || stats.Invalidations != 100 {
+		t.Errorf("expected every token to be invalidated, got %+v", stats)
+	}
+}
@@ -26,6 +26,15 @@ func (s *VerificationKeyStore) Add(identity string, key cryptointerfaces.Verific
 	s.keys[identity] = key
 }
 
+// Remove retires the key for identity. Tokens it signed are rejected from then on, including by
+// verifiers that have them cached.
+func (s *VerificationKeyStore) Remove(identity string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, identity)
+}
+
 func (s *VerificationKeyStore) Get(ctx context.Context, identity string) (cryptointerfaces.VerificationKey, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -2,6 +2,9 @@ module github.com/jasoncolburne/better-auth-go
 
 go 1.25.1
 
-require github.com/zeebo/blake3 v0.2.4
+require (
+	github.com/lib/pq v1.12.3
+	github.com/zeebo/blake3 v0.2.4
+)
 
 require github.com/klauspost/cpuid/v2 v2.0.12 // indirect
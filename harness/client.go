@@ -0,0 +1,731 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+// Keyring supplies a client's keys, digests and nonces. Keys and digests are named, so that a
+// keyring can derive or record them by name; keyrings that don't, ignore the names.
+type Keyring interface {
+	Key(name string) (cryptointerfaces.SigningKey, error)
+	Hash(name string, inputs ...string) string
+	Nonce() (string, error)
+}
+
+type keyring struct {
+	entropy io.Reader
+	hasher  *crypto.Blake3
+	noncer  *crypto.Noncer
+}
+
+// NewKeyring generates keys and nonces from entropy, or from the system when it is nil.
+func NewKeyring(entropy io.Reader) Keyring {
+	return &keyring{
+		entropy: entropy,
+		hasher:  crypto.NewBlake3(),
+		noncer:  newNoncer(entropy),
+	}
+}
+
+func (k *keyring) Key(string) (cryptointerfaces.SigningKey, error) {
+	return newKey(k.entropy)
+}
+
+func (k *keyring) Hash(_ string, inputs ...string) string {
+	return k.hasher.Sum([]byte(strings.Join(inputs, "")))
+}
+
+func (k *keyring) Nonce() (string, error) {
+	return k.noncer.Generate128()
+}
+
+// Device is the client side of one device of an account. Its keys only advance when the server
+// accepts a rotation.
+type Device struct {
+	Name       string
+	Identity   string
+	Device     string
+	Generation int
+
+	Current cryptointerfaces.SigningKey
+	Next    cryptointerfaces.SigningKey
+}
+
+func (d *Device) rotated(nextNext cryptointerfaces.SigningKey) {
+	d.Generation++
+	d.Current = d.Next
+	d.Next = nextNext
+}
+
+// Account is what a user holds offline: the identity, the device it was opened or last recovered
+// on, and its recovery key.
+type Account struct {
+	Name     string
+	Identity string
+	Device   *Device
+
+	RecoveryGeneration int
+	Recovery           cryptointerfaces.SigningKey
+}
+
+// Session is the client side of an access key chain.
+type Session struct {
+	Name       string
+	Device     *Device
+	Generation int
+	Token      string
+
+	Current cryptointerfaces.SigningKey
+	Next    cryptointerfaces.SigningKey
+}
+
+type Signable interface {
+	Sign(signer cryptointerfaces.SigningKey) error
+	Serialize() (string, error)
+}
+
+// Message signs request with key, if given, and serializes it.
+func Message(request Signable, key cryptointerfaces.SigningKey) (string, error) {
+	if key != nil {
+		if err := request.Sign(key); err != nil {
+			return "", err
+		}
+	}
+
+	return request.Serialize()
+}
+
+// Client performs the protocol flows over a transport. Keys are named for the device, account or
+// session they belong to, and the flows only advance client state once the server accepts them.
+type Client struct {
+	transport   Transport
+	keys        Keyring
+	timestamper encodinginterfaces.Timestamper
+}
+
+// NewClient builds a client timestamping access requests with timestamper.
+func NewClient(transport Transport, keys Keyring, timestamper encodinginterfaces.Timestamper) *Client {
+	return &Client{
+		transport:   transport,
+		keys:        keys,
+		timestamper: timestamper,
+	}
+}
+
+func (c *Client) send(ctx context.Context, operation Operation, request Signable, key cryptointerfaces.SigningKey) (string, error) {
+	message, err := Message(request, key)
+	if err != nil {
+		return "", err
+	}
+
+	return c.transport.Call(ctx, operation, message)
+}
+
+// commitment digests the public key of key, under name.
+func (c *Client) commitment(name string, key cryptointerfaces.SigningKey) (string, error) {
+	publicKey, err := key.Public()
+	if err != nil {
+		return "", err
+	}
+
+	return c.keys.Hash(name, publicKey), nil
+}
+
+// newDevice derives a device's first key and the next one it will commit to, without registering it.
+func (c *Client) newDevice(name string) (*Device, error) {
+	current, err := c.keys.Key(name + " device 0")
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := c.keys.Key(name + " device 1")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Device{Name: name, Current: current, Next: next}, nil
+}
+
+// rotation commits to a fresh key after the device's next one, returning the commitment and the key.
+func (c *Client) rotation(d *Device) (string, cryptointerfaces.SigningKey, error) {
+	nextNext, err := c.keys.Key(fmt.Sprintf("%s device %d", d.Name, d.Generation+2))
+	if err != nil {
+		return "", nil, err
+	}
+
+	rotationHash, err := c.commitment(fmt.Sprintf("%s device %d rotation hash", d.Name, d.Generation+1), nextNext)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return rotationHash, nextNext, nil
+}
+
+// CreateAccount creates an account whose first device is named device.
+func (c *Client) CreateAccount(ctx context.Context, account, device string) (*Account, error) {
+	d, err := c.newDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
+	recovery, err := c.keys.Key(account + " recovery 0")
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := d.Current.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	rotationHash, err := c.commitment(device+" device 0 rotation hash", d.Next)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryHash, err := c.commitment(account+" recovery 0 hash", recovery)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Device = c.keys.Hash(device+" device 0", publicKey, rotationHash)
+	d.Identity = c.keys.Hash(account+" identity", publicKey, rotationHash, recoveryHash)
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return nil, err
+	}
+
+	request := messages.NewCreateAccountRequest(
+		messages.CreateAccountRequestPayload{
+			Authentication: messages.CreateAccountRequestAuthentication{
+				Device:       d.Device,
+				Identity:     d.Identity,
+				PublicKey:    publicKey,
+				RecoveryHash: recoveryHash,
+				RotationHash: rotationHash,
+			},
+		},
+		nonce,
+	)
+
+	if _, err := c.send(ctx, CreateAccount, request, d.Current); err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		Name:     account,
+		Identity: d.Identity,
+		Device:   d,
+		Recovery: recovery,
+	}, nil
+}
+
+// RotateDevice presents key, normally the committed next key, as the device's new key.
+func (c *Client) RotateDevice(ctx context.Context, d *Device, key cryptointerfaces.SigningKey) error {
+	rotationHash, nextNext, err := c.rotation(d)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := key.Public()
+	if err != nil {
+		return err
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return err
+	}
+
+	request := messages.NewRotateDeviceRequest(
+		messages.RotateDeviceRequestPayload{
+			Authentication: messages.RotateDeviceRequestAuthentication{
+				Device:       d.Device,
+				Identity:     d.Identity,
+				PublicKey:    publicKey,
+				RotationHash: rotationHash,
+			},
+		},
+		nonce,
+	)
+
+	if _, err := c.send(ctx, RotateDevice, request, key); err != nil {
+		return err
+	}
+
+	d.rotated(nextNext)
+
+	return nil
+}
+
+// ChangeRecoveryKey replaces the account's recovery key from the device d.
+func (c *Client) ChangeRecoveryKey(ctx context.Context, a *Account, d *Device) error {
+	rotationHash, nextNext, err := c.rotation(d)
+	if err != nil {
+		return err
+	}
+
+	recovery, err := c.keys.Key(fmt.Sprintf("%s recovery %d", a.Name, a.RecoveryGeneration+1))
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := d.Next.Public()
+	if err != nil {
+		return err
+	}
+
+	recoveryHash, err := c.commitment(fmt.Sprintf("%s recovery %d hash", a.Name, a.RecoveryGeneration+1), recovery)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return err
+	}
+
+	request := messages.NewChangeRecoveryKeyRequest(
+		messages.ChangeRecoveryKeyRequestPayload{
+			Authentication: messages.ChangeRecoveryKeyRequestAuthentication{
+				Device:       d.Device,
+				Identity:     d.Identity,
+				PublicKey:    publicKey,
+				RecoveryHash: recoveryHash,
+				RotationHash: rotationHash,
+			},
+		},
+		nonce,
+	)
+
+	if _, err := c.send(ctx, ChangeRecoveryKey, request, d.Next); err != nil {
+		return err
+	}
+
+	d.rotated(nextNext)
+	a.RecoveryGeneration++
+	a.Recovery = recovery
+
+	return nil
+}
+
+// RecoverAccount uses the account's recovery key to replace its devices with a new one, named
+// device, which becomes the account's device.
+func (c *Client) RecoverAccount(ctx context.Context, a *Account, device string) (*Device, error) {
+	d, err := c.newDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Identity = a.Identity
+
+	recovery, err := c.keys.Key(fmt.Sprintf("%s recovery %d", a.Name, a.RecoveryGeneration+1))
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := d.Current.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	rotationHash, err := c.commitment(device+" device 0 rotation hash", d.Next)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Device = c.keys.Hash(device+" device 0", publicKey, rotationHash)
+
+	recoveryHash, err := c.commitment(fmt.Sprintf("%s recovery %d hash", a.Name, a.RecoveryGeneration+1), recovery)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryKey, err := a.Recovery.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return nil, err
+	}
+
+	request := messages.NewRecoverAccountRequest(
+		messages.RecoverAccountRequestPayload{
+			Authentication: messages.RecoverAccountRequestAuthentication{
+				Device:       d.Device,
+				Identity:     a.Identity,
+				PublicKey:    publicKey,
+				RecoveryHash: recoveryHash,
+				RecoveryKey:  recoveryKey,
+				RotationHash: rotationHash,
+			},
+		},
+		nonce,
+	)
+
+	if _, err := c.send(ctx, RecoverAccount, request, a.Recovery); err != nil {
+		return nil, err
+	}
+
+	a.Device = d
+	a.RecoveryGeneration++
+	a.Recovery = recovery
+
+	return d, nil
+}
+
+// DeleteAccount deletes the account of the device d.
+func (c *Client) DeleteAccount(ctx context.Context, d *Device) error {
+	rotationHash, nextNext, err := c.rotation(d)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := d.Next.Public()
+	if err != nil {
+		return err
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return err
+	}
+
+	request := messages.NewDeleteAccountRequest(
+		messages.DeleteAccountRequestPayload{
+			Authentication: messages.DeleteAccountRequestAuthentication{
+				Device:       d.Device,
+				Identity:     d.Identity,
+				PublicKey:    publicKey,
+				RotationHash: rotationHash,
+			},
+		},
+		nonce,
+	)
+
+	if _, err := c.send(ctx, DeleteAccount, request, d.Next); err != nil {
+		return err
+	}
+
+	d.rotated(nextNext)
+
+	return nil
+}
+
+// LinkDevice links a new device, named device, from the existing device by. identity is claimed
+// in the new device's link container, and is normally the account's.
+func (c *Client) LinkDevice(ctx context.Context, by *Device, device, identity string) (*Device, error) {
+	linked, err := c.newDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
+	linked.Identity = by.Identity
+
+	publicKey, err := linked.Current.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	rotationHash, err := c.commitment(device+" device 0 rotation hash", linked.Next)
+	if err != nil {
+		return nil, err
+	}
+
+	linked.Device = c.keys.Hash(device+" device 0", publicKey, rotationHash)
+
+	link := messages.NewLinkContainer(
+		messages.LinkContainerPayload{
+			Authentication: messages.LinkContainerAuthentication{
+				Device:       linked.Device,
+				Identity:     identity,
+				PublicKey:    publicKey,
+				RotationHash: rotationHash,
+			},
+		},
+		nil,
+	)
+
+	if err := link.Sign(linked.Current); err != nil {
+		return nil, err
+	}
+
+	nextRotationHash, nextNext, err := c.rotation(by)
+	if err != nil {
+		return nil, err
+	}
+
+	nextPublicKey, err := by.Next.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return nil, err
+	}
+
+	request := messages.NewLinkDeviceRequest(
+		messages.LinkDeviceRequestPayload{
+			Authentication: messages.LinkDeviceRequestAuthentication{
+				Device:       by.Device,
+				Identity:     by.Identity,
+				PublicKey:    nextPublicKey,
+				RotationHash: nextRotationHash,
+			},
+			Link: *link,
+		},
+		nonce,
+	)
+
+	if _, err := c.send(ctx, LinkDevice, request, by.Next); err != nil {
+		return nil, err
+	}
+
+	by.rotated(nextNext)
+
+	return linked, nil
+}
+
+// UnlinkDevice unlinks target from its account, acting from the device by. The target keeps its
+// keys, so it can still be used to show that the server refuses it.
+func (c *Client) UnlinkDevice(ctx context.Context, by, target *Device) error {
+	rotationHash, nextNext, err := c.rotation(by)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := by.Next.Public()
+	if err != nil {
+		return err
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return err
+	}
+
+	request := messages.NewUnlinkDeviceRequest(
+		messages.UnlinkDeviceRequestPayload{
+			Authentication: messages.UnlinkDeviceRequestAuthentication{
+				Device:       by.Device,
+				Identity:     by.Identity,
+				PublicKey:    publicKey,
+				RotationHash: rotationHash,
+			},
+			Link: messages.UnlinkDeviceRequestLink{
+				Device: target.Device,
+			},
+		},
+		nonce,
+	)
+
+	if _, err := c.send(ctx, UnlinkDevice, request, by.Next); err != nil {
+		return err
+	}
+
+	by.rotated(nextNext)
+
+	return nil
+}
+
+// CreateSession requests a challenge for the device and answers it, opening a session whose access
+// keys are named for session.
+func (c *Client) CreateSession(ctx context.Context, d *Device, session string) (*Session, error) {
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return nil, err
+	}
+
+	request := messages.NewRequestSessionRequest(
+		messages.RequestSessionRequestPayload{
+			Authentication: messages.RequestSessionRequestAuthentication{
+				Identity: d.Identity,
+			},
+		},
+		nonce,
+	)
+
+	reply, err := c.send(ctx, RequestSession, request, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	requestSessionResponse, err := messages.ParseRequestSessionResponse(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := c.keys.Key(session + " access 0")
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := c.keys.Key(session + " access 1")
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := current.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	rotationHash, err := c.commitment(session+" access 0 rotation hash", next)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err = c.keys.Nonce()
+	if err != nil {
+		return nil, err
+	}
+
+	createRequest := messages.NewCreateSessionRequest(
+		messages.CreateSessionRequestPayload{
+			Access: messages.CreateSessionRequestAccess{
+				PublicKey:    publicKey,
+				RotationHash: rotationHash,
+			},
+			Authentication: messages.CreateSessionRequestAuthentication{
+				Device: d.Device,
+				Nonce:  requestSessionResponse.Payload.Response.Authentication.Nonce,
+			},
+		},
+		nonce,
+	)
+
+	reply, err = c.send(ctx, CreateSession, createRequest, d.Current)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := messages.ParseCreateSessionResponse(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		Name:    session,
+		Device:  d,
+		Token:   response.Payload.Response.Access.Token,
+		Current: current,
+		Next:    next,
+	}, nil
+}
+
+// RefreshMessage builds a refresh request for the session's current link, returning it with the key
+// it commits to. Sending it is left to the caller, so that it can also be replayed.
+func (c *Client) RefreshMessage(session *Session) (string, cryptointerfaces.SigningKey, error) {
+	nextNext, err := c.keys.Key(fmt.Sprintf("%s access %d", session.Name, session.Generation+2))
+	if err != nil {
+		return "", nil, err
+	}
+
+	publicKey, err := session.Next.Public()
+	if err != nil {
+		return "", nil, err
+	}
+
+	rotationHash, err := c.commitment(fmt.Sprintf("%s access %d rotation hash", session.Name, session.Generation+1), nextNext)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return "", nil, err
+	}
+
+	request := messages.NewRefreshSessionRequest(
+		messages.RefreshSessionRequestPayload{
+			Access: messages.RefreshSessionRequestAccess{
+				PublicKey:    publicKey,
+				RotationHash: rotationHash,
+				Token:        session.Token,
+			},
+		},
+		nonce,
+	)
+
+	message, err := Message(request, session.Next)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return message, nextNext, nil
+}
+
+// Refreshed advances the session to the token in reply, the server's answer to a refresh message
+// committing to nextNext.
+func (c *Client) Refreshed(session *Session, reply string, nextNext cryptointerfaces.SigningKey) error {
+	response, err := messages.ParseRefreshSessionResponse(reply)
+	if err != nil {
+		return err
+	}
+
+	session.Generation++
+	session.Token = response.Payload.Response.Access.Token
+	session.Current = session.Next
+	session.Next = nextNext
+
+	return nil
+}
+
+// RefreshSession exchanges the session's token for a new one, rotating its access key.
+func (c *Client) RefreshSession(ctx context.Context, session *Session) error {
+	message, nextNext, err := c.RefreshMessage(session)
+	if err != nil {
+		return err
+	}
+
+	reply, err := c.transport.Call(ctx, RefreshSession, message)
+	if err != nil {
+		return err
+	}
+
+	return c.Refreshed(session, reply, nextNext)
+}
+
+// AccessMessage builds an access request for payload, timestamped by timestamper or, when it is
+// nil, the client's.
+func (c *Client) AccessMessage(session *Session, timestamper encodinginterfaces.Timestamper, payload any) (string, error) {
+	if timestamper == nil {
+		timestamper = c.timestamper
+	}
+
+	nonce, err := c.keys.Nonce()
+	if err != nil {
+		return "", err
+	}
+
+	request := messages.NewAccessRequest[any, Attributes, messages.AccessRequest[any, Attributes]](
+		payload,
+		timestamper,
+		session.Token,
+		nonce,
+	)
+
+	return Message(request, session.Current)
+}
+
+// Access presents the session's token with payload, returning the reply.
+func (c *Client) Access(ctx context.Context, session *Session, payload any) (string, error) {
+	message, err := c.AccessMessage(session, nil, payload)
+	if err != nil {
+		return "", err
+	}
+
+	return c.transport.Call(ctx, Access, message)
+}
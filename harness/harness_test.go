@@ -0,0 +1,77 @@
+package harness_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/harness"
+)
+
+func TestFlows(t *testing.T) {
+	ctx := context.Background()
+
+	node, err := harness.New()
+	if err != nil {
+		t.Fatalf("failed to build node: %v", err)
+	}
+
+	client := harness.NewClient(node, harness.NewKeyring(nil), node.Timestamper)
+
+	account, err := client.CreateAccount(ctx, "alice", "laptop")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	laptop := account.Device
+	if err := client.RotateDevice(ctx, laptop, laptop.Next); err != nil {
+		t.Fatalf("failed to rotate device: %v", err)
+	}
+
+	phone, err := client.LinkDevice(ctx, laptop, "phone", account.Identity)
+	if err != nil {
+		t.Fatalf("failed to link device: %v", err)
+	}
+
+	session, err := client.CreateSession(ctx, phone, "browser")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if err := client.RefreshSession(ctx, session); err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+
+	reply, err := client.Access(ctx, session, map[string]string{"resource": "documents"})
+	if err != nil {
+		t.Fatalf("failed to access: %v", err)
+	}
+
+	if reply != `{"resource":"documents"}` {
+		t.Errorf("unexpected reply %s", reply)
+	}
+
+	if err := client.UnlinkDevice(ctx, laptop, phone); err != nil {
+		t.Fatalf("failed to unlink device: %v", err)
+	}
+
+	if err := client.ChangeRecoveryKey(ctx, account, laptop); err != nil {
+		t.Fatalf("failed to change recovery key: %v", err)
+	}
+
+	tablet, err := client.RecoverAccount(ctx, account, "tablet")
+	if err != nil {
+		t.Fatalf("failed to recover account: %v", err)
+	}
+
+	if account.Device != tablet || account.RecoveryGeneration != 2 {
+		t.Errorf("expected the account to move to the recovered device")
+	}
+
+	if err := client.DeleteAccount(ctx, tablet); err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+
+	if _, err := client.CreateSession(ctx, tablet, "stale"); err == nil {
+		t.Errorf("expected the deleted account to be refused")
+	}
+}
@@ -0,0 +1,306 @@
+// Package harness wires a BetterAuthServer and an AccessVerifier from the example implementations
+// with in-memory stores, and drives the client side of the protocol flows against them. The
+// simulator, the test vector generator and the benchmarks are all built on it.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/examples/storage"
+	"github.com/jasoncolburne/better-auth-go/pkg/clockinterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+const (
+	AccessLifetime  = 15 * time.Minute
+	RefreshLifetime = 12 * time.Hour
+	NonceLifetime   = 1 * time.Minute
+	AccessWindow    = 30 * time.Second
+)
+
+type Attributes struct {
+	PermissionsByRole map[string][]string `json:"permissionsByRole"`
+}
+
+// DefaultSessionAttributes are granted to sessions unless WithSessionAttributes says otherwise.
+var DefaultSessionAttributes = Attributes{
+	PermissionsByRole: map[string][]string{
+		"admin": {"read", "write"},
+	},
+}
+
+type Operation string
+
+const (
+	CreateAccount     Operation = "createAccount"
+	RecoverAccount    Operation = "recoverAccount"
+	DeleteAccount     Operation = "deleteAccount"
+	ChangeRecoveryKey Operation = "changeRecoveryKey"
+	LinkDevice        Operation = "linkDevice"
+	UnlinkDevice      Operation = "unlinkDevice"
+	RotateDevice      Operation = "rotateDevice"
+	RequestSession    Operation = "requestSession"
+	CreateSession     Operation = "createSession"
+	RefreshSession    Operation = "refreshSession"
+	Access            Operation = "access"
+)
+
+// Transport delivers a serialized request and returns the serialized reply.
+type Transport interface {
+	Call(ctx context.Context, operation Operation, message string) (string, error)
+}
+
+type nodeOptions struct {
+	clock             clockinterfaces.Clock
+	entropy           io.Reader
+	responseKey       cryptointerfaces.SigningKey
+	accessKey         cryptointerfaces.SigningKey
+	noncer            cryptointerfaces.Noncer
+	nonceStore        storageinterfaces.AuthenticationNonceStore
+	sessionAttributes *Attributes
+	serverOptions     []api.BetterAuthServerOption
+}
+
+type NodeOption func(*nodeOptions)
+
+// WithClock drives every time-sensitive component from clock, instead of the system clock.
+func WithClock(clock clockinterfaces.Clock) NodeOption {
+	return func(o *nodeOptions) {
+		o.clock = clock
+	}
+}
+
+// WithEntropy draws the server keys and nonces from entropy, which may be seeded to make them
+// reproducible.
+func WithEntropy(entropy io.Reader) NodeOption {
+	return func(o *nodeOptions) {
+		o.entropy = entropy
+	}
+}
+
+// WithKeys uses the given server response and access keys instead of generating them.
+func WithKeys(response, access cryptointerfaces.SigningKey) NodeOption {
+	return func(o *nodeOptions) {
+		o.responseKey = response
+		o.accessKey = access
+	}
+}
+
+// WithNoncer generates the server's session ids and refresh chains with noncer.
+func WithNoncer(noncer cryptointerfaces.Noncer) NodeOption {
+	return func(o *nodeOptions) {
+		o.noncer = noncer
+	}
+}
+
+// WithAuthenticationNonceStore issues authentication challenges from store.
+func WithAuthenticationNonceStore(store storageinterfaces.AuthenticationNonceStore) NodeOption {
+	return func(o *nodeOptions) {
+		o.nonceStore = store
+	}
+}
+
+// WithSessionAttributes grants attributes to every session the node creates.
+func WithSessionAttributes(attributes Attributes) NodeOption {
+	return func(o *nodeOptions) {
+		o.sessionAttributes = &attributes
+	}
+}
+
+// WithServerOptions configures the server with options.
+func WithServerOptions(options ...api.BetterAuthServerOption) NodeOption {
+	return func(o *nodeOptions) {
+		o.serverOptions = append(o.serverOptions, options...)
+	}
+}
+
+// Node is a server and access verifier sharing a clock, an access key and a revocation store.
+type Node struct {
+	Server   *api.BetterAuthServer[Attributes]
+	Verifier *api.AccessVerifier[Attributes]
+
+	Hasher       *crypto.Blake3
+	Timestamper  *encoding.Rfc3339
+	TokenEncoder encodinginterfaces.TokenEncoder
+	ResponseKey  cryptointerfaces.SigningKey
+
+	sessionAttributes Attributes
+}
+
+func New(options ...NodeOption) (*Node, error) {
+	o := nodeOptions{}
+	for _, option := range options {
+		option(&o)
+	}
+
+	if o.clock == nil {
+		o.clock = clock.NewSystemClock()
+	}
+
+	if o.noncer == nil {
+		o.noncer = newNoncer(o.entropy)
+	}
+
+	if o.nonceStore == nil {
+		o.nonceStore = storage.NewInMemoryAuthenticationNonceStoreWithNoncer(NonceLifetime, o.clock, o.noncer)
+	}
+
+	if o.sessionAttributes == nil {
+		o.sessionAttributes = &DefaultSessionAttributes
+	}
+
+	if o.responseKey == nil {
+		responseKey, err := newKey(o.entropy)
+		if err != nil {
+			return nil, err
+		}
+
+		accessKey, err := newKey(o.entropy)
+		if err != nil {
+			return nil, err
+		}
+
+		o.responseKey = responseKey
+		o.accessKey = accessKey
+	}
+
+	hasher := crypto.NewBlake3()
+	verifier := crypto.NewSecp256r1Verifier()
+	timestamper := encoding.NewRfc3339WithClock(o.clock)
+	tokenEncoder := encoding.NewTokenEncoder[Attributes]()
+
+	accessIdentity, err := o.accessKey.Identity()
+	if err != nil {
+		return nil, err
+	}
+
+	accessKeyStore := storage.NewVerificationKeyStore()
+	accessKeyStore.Add(accessIdentity, o.accessKey)
+
+	revocationStore := storage.NewInMemoryRevocationStoreWithClock(RefreshLifetime, o.clock)
+
+	server := api.NewBetterAuthServer[Attributes](
+		&api.CryptoContainer{
+			Hasher: hasher,
+			KeyPair: &api.KeyPairContainer{
+				Access:   o.accessKey,
+				Response: o.responseKey,
+			},
+			Noncer:   o.noncer,
+			Verifier: verifier,
+		},
+		&api.EncodingContainer{
+			IdentityVerifier: encoding.NewMockIdentityVerifier(hasher),
+			Timestamper:      timestamper,
+			TokenEncoder:     tokenEncoder,
+		},
+		&api.ExpiryContainer{
+			Access:  AccessLifetime,
+			Refresh: RefreshLifetime,
+		},
+		&api.StoresContainer{
+			Access: &api.AccessStoreContainer{
+				KeyHash:         storage.NewInMemoryTimeLockStoreWithClock(RefreshLifetime, o.clock),
+				Revocation:      revocationStore,
+				VerificationKey: accessKeyStore,
+			},
+			Authentication: &api.AuthenticationStoreContainer{
+				Key:   storage.NewInMemoryAuthenticationKeyStore(),
+				Nonce: o.nonceStore,
+			},
+			Recovery: &api.RecoveryStoreContainer{
+				Hash: storage.NewInMemoryRecoveryHashStore(),
+			},
+			Session: &api.SessionStoreContainer{
+				Registry: storage.NewInMemorySessionStoreWithClock(o.clock),
+			},
+		},
+		o.serverOptions...,
+	)
+
+	accessVerifier := api.NewAccessVerifier[Attributes](
+		&api.VerifierCryptoContainer{
+			Verifier: verifier,
+		},
+		&api.VerifierEncodingContainer{
+			TokenEncoder: tokenEncoder,
+			Timestamper:  timestamper,
+		},
+		&api.VerifierStoreContainer{
+			AccessNonce: storage.NewInMemoryTimeLockStoreWithClock(AccessWindow, o.clock),
+			AccessKey:   accessKeyStore,
+		},
+		api.CheckRevocation(revocationStore),
+	)
+
+	return &Node{
+		Server:            server,
+		Verifier:          accessVerifier,
+		Hasher:            hasher,
+		Timestamper:       timestamper,
+		TokenEncoder:      tokenEncoder,
+		ResponseKey:       o.responseKey,
+		sessionAttributes: *o.sessionAttributes,
+	}, nil
+}
+
+// Call hands the request to the operation's handler, so a node is its own in-process transport.
+// Access requests respond with the verified request payload.
+func (n *Node) Call(ctx context.Context, operation Operation, message string) (string, error) {
+	switch operation {
+	case CreateAccount:
+		return n.Server.CreateAccount(ctx, message)
+	case RecoverAccount:
+		return n.Server.RecoverAccount(ctx, message)
+	case DeleteAccount:
+		return n.Server.DeleteAccount(ctx, message)
+	case ChangeRecoveryKey:
+		return n.Server.ChangeRecoveryKey(ctx, message)
+	case LinkDevice:
+		return n.Server.LinkDevice(ctx, message)
+	case UnlinkDevice:
+		return n.Server.UnlinkDevice(ctx, message)
+	case RotateDevice:
+		return n.Server.RotateDevice(ctx, message)
+	case RequestSession:
+		return n.Server.RequestSession(ctx, message)
+	case CreateSession:
+		return n.Server.CreateSession(ctx, message, n.sessionAttributes)
+	case RefreshSession:
+		return n.Server.RefreshSession(ctx, message)
+	case Access:
+		payload, _, _, err := n.Verifier.Verify(ctx, message, &Attributes{})
+		if err != nil {
+			return "", err
+		}
+
+		return string(payload), nil
+	}
+
+	return "", fmt.Errorf("unknown operation %s", operation)
+}
+
+func newKey(entropy io.Reader) (*crypto.Secp256r1, error) {
+	if entropy == nil {
+		return crypto.NewSecp256r1()
+	}
+
+	return crypto.NewSecp256r1WithEntropy(entropy)
+}
+
+func newNoncer(entropy io.Reader) *crypto.Noncer {
+	if entropy == nil {
+		return crypto.NewNoncer()
+	}
+
+	return crypto.NewNoncerWithEntropy(entropy)
+}
@@ -0,0 +1,134 @@
+// Package cesr describes the subset of CESR (Composable Event Streaming Representation) primitives
+// used by better-auth: qualified digests, salts, public keys and signatures in base64url text form.
+package cesr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Digest Kind = iota
+	Salt
+	PublicKey
+	Signature
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Digest:
+		return "digest"
+	case Salt:
+		return "salt"
+	case PublicKey:
+		return "public key"
+	case Signature:
+		return "signature"
+	}
+
+	return "unknown"
+}
+
+type Primitive struct {
+	Code        string
+	Kind        Kind
+	Length      int
+	Description string
+}
+
+var primitives = map[string]Primitive{
+	"B":    {"B", PublicKey, 44, "Ed25519 non-transferable public key"},
+	"D":    {"D", PublicKey, 44, "Ed25519 public key"},
+	"E":    {"E", Digest, 44, "Blake3-256 digest"},
+	"F":    {"F", Digest, 44, "Blake2b-256 digest"},
+	"G":    {"G", Digest, 44, "Blake2s-256 digest"},
+	"H":    {"H", Digest, 44, "SHA3-256 digest"},
+	"I":    {"I", Digest, 44, "SHA2-256 digest"},
+	"0A":   {"0A", Salt, 24, "128 bit random salt"},
+	"0B":   {"0B", Signature, 88, "Ed25519 signature"},
+	"0C":   {"0C", Signature, 88, "ECDSA secp256k1 signature"},
+	"0I":   {"0I", Signature, 88, "ECDSA secp256r1 signature"},
+	"1AAA": {"1AAA", PublicKey, 48, "ECDSA secp256k1 non-transferable public key"},
+	"1AAB": {"1AAB", PublicKey, 48, "ECDSA secp256k1 public key"},
+	"1AAI": {"1AAI", PublicKey, 48, "ECDSA secp256r1 non-transferable public key"},
+	"1AAJ": {"1AAJ", PublicKey, 48, "ECDSA secp256r1 public key"},
+}
+
+// codeLength returns the length of the code selected by the first character of a primitive.
+func codeLength(value string) (int, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty primitive")
+	}
+
+	switch selector := value[0]; {
+	case selector >= 'A' && selector <= 'Z', selector >= 'a' && selector <= 'z':
+		return 1, nil
+	case selector == '0':
+		return 2, nil
+	case selector == '1':
+		return 4, nil
+	}
+
+	return 0, fmt.Errorf("unsupported code selector %q", value[0])
+}
+
+// Lookup returns the primitive type at the start of value, which may be followed by other data.
+func Lookup(value string) (Primitive, error) {
+	length, err := codeLength(value)
+	if err != nil {
+		return Primitive{}, err
+	}
+
+	if len(value) < length {
+		return Primitive{}, fmt.Errorf("truncated code")
+	}
+
+	primitive, ok := primitives[value[:length]]
+	if !ok {
+		return Primitive{}, fmt.Errorf("unknown code %q", value[:length])
+	}
+
+	return primitive, nil
+}
+
+// Validate ensures value is exactly one primitive of one of kinds.
+func Validate(value string, kinds ...Kind) error {
+	primitive, err := Lookup(value)
+	if err != nil {
+		return err
+	}
+
+	acceptable := false
+	for _, kind := range kinds {
+		if primitive.Kind == kind {
+			acceptable = true
+		}
+	}
+
+	if !acceptable {
+		expected := make([]string, len(kinds))
+		for i, kind := range kinds {
+			expected[i] = kind.String()
+		}
+
+		return fmt.Errorf("expected %s, got %s", strings.Join(expected, " or "), primitive.Description)
+	}
+
+	if len(value) != primitive.Length {
+		return fmt.Errorf("expected %d characters for %s, got %d", primitive.Length, primitive.Description, len(value))
+	}
+
+	for i := len(primitive.Code); i < len(value); i++ {
+		if !isBase64URL(value[i]) {
+			return fmt.Errorf("invalid character %q at offset %d", value[i], i)
+		}
+	}
+
+	return nil
+}
+
+func isBase64URL(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_'
+}
@@ -0,0 +1,51 @@
+package cesr_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/cesr"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		value string
+		kinds []cesr.Kind
+		valid bool
+	}{
+		{"E" + strings.Repeat("a", 43), []cesr.Kind{cesr.Digest}, true},
+		{"0A" + strings.Repeat("a", 22), []cesr.Kind{cesr.Salt}, true},
+		{"1AAI" + strings.Repeat("a", 44), []cesr.Kind{cesr.PublicKey}, true},
+		{"0I" + strings.Repeat("a", 86), []cesr.Kind{cesr.Signature}, true},
+		{"1AAI" + strings.Repeat("a", 44), []cesr.Kind{cesr.Digest, cesr.PublicKey}, true},
+		{"E" + strings.Repeat("a", 43), []cesr.Kind{cesr.PublicKey}, false},
+		{"E" + strings.Repeat("a", 42), []cesr.Kind{cesr.Digest}, false},
+		{"E" + strings.Repeat("a", 42) + "=", []cesr.Kind{cesr.Digest}, false},
+		{"0Z" + strings.Repeat("a", 22), []cesr.Kind{cesr.Salt}, false},
+		{"1AA", []cesr.Kind{cesr.PublicKey}, false},
+		{"", []cesr.Kind{cesr.Digest}, false},
+		{"-" + strings.Repeat("a", 43), []cesr.Kind{cesr.Digest}, false},
+	}
+
+	for _, test := range tests {
+		err := cesr.Validate(test.value, test.kinds...)
+		if test.valid && err != nil {
+			t.Errorf("expected %q to be valid: %v", test.value, err)
+		}
+
+		if !test.valid && err == nil {
+			t.Errorf("expected %q to be invalid", test.value)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	primitive, err := cesr.Lookup("0I" + strings.Repeat("a", 200))
+	if err != nil {
+		t.Fatalf("failed to look up signature: %v", err)
+	}
+
+	if primitive.Kind != cesr.Signature || primitive.Length != 88 {
+		t.Fatalf("unexpected primitive: %+v", primitive)
+	}
+}
@@ -0,0 +1,9 @@
+package clockinterfaces
+
+import "time"
+
+// Clock is the source of the current time for time-sensitive components, so it can be controlled
+// in tests.
+type Clock interface {
+	Now() time.Time
+}
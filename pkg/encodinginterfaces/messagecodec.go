@@ -0,0 +1,10 @@
+package encodinginterfaces
+
+// MessageCodec converts serialized messages between JSON and an alternative wire format. Decode
+// must produce RFC 8785 (JCS) canonical JSON. Codecs that also implement SignedEncoding have
+// messages signed over their encoded payloads; for any other codec, messages are signed over
+// canonical JSON payloads so that signatures survive the round trip.
+type MessageCodec interface {
+	Encode(message string) (string, error)
+	Decode(message string) (string, error)
+}
@@ -0,0 +1,9 @@
+package encodinginterfaces
+
+// SignedEncoding is implemented by message codecs and token encoders whose encoding is
+// deterministic. Payloads carried in such an encoding are signed over their encoded bytes rather
+// than their JSON, so clients can sign and verify without a JSON serializer.
+type SignedEncoding interface {
+	// EncodePayload encodes a JSON payload to the bytes its signature covers.
+	EncodePayload(payload []byte) ([]byte, error)
+}
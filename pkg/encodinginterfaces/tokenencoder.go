@@ -5,3 +5,9 @@ type TokenEncoder interface {
 	Decode(token string) (string, error)
 	SignatureLength(token string) (int, error)
 }
+
+// TokenBytesDecoder is implemented by token encoders that can decode straight to bytes, sparing
+// parsers a string copy. The returned slice belongs to the caller.
+type TokenBytesDecoder interface {
+	DecodeBytes(token string) ([]byte, error)
+}
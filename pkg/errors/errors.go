@@ -1,7 +1,9 @@
 // Package errors provides standardized error types for better-auth.
 //
 // This package defines the error hierarchy for Better Auth following
-// the specification in ERRORS.md in the root repository.
+// the specification in ERRORS.md in the root repository. Errors the
+// specification has no code for reuse the closest code it does have,
+// and name the specific case in their context.
 package errors
 
 import (
@@ -113,6 +115,28 @@ func NewInvalidHashError(expected, actual, hashType string) error {
 	return err
 }
 
+// NewRotationConflictError creates an error for keys that were already rotated, or never committed to
+func NewRotationConflictError(identity, device string) error {
+	err := newError("BA104", "Key does not match the device's rotation commitment")
+	err.withContext("hashType", "rotation")
+	if identity != "" {
+		err.withContext("identity", identity)
+	}
+	if device != "" {
+		err.withContext("device", device)
+	}
+	return err
+}
+
+// NewUnsupportedVersionError creates an error for messages in a protocol version the server doesn't speak
+func NewUnsupportedVersionError(version string, supported []string) error {
+	err := newError("BA101", "Protocol version is not supported")
+	err.withContext("field", "payload.access.version")
+	err.withContext("version", version)
+	err.withContext("supportedVersions", supported)
+	return err
+}
+
 // ============================================================================
 // Cryptographic Errors
 // ============================================================================
@@ -152,12 +176,30 @@ func NewMismatchedIdentitiesError(linkContainerIdentity, requestIdentity string)
 	return err
 }
 
+// NewMismatchedTenantError creates an error for credentials presented to the wrong tenant
+func NewMismatchedTenantError(expected, actual string) error {
+	err := newError("BA302", "Tenant does not match")
+	err.withContext("reason", "tenant")
+	if expected != "" {
+		err.withContext("expected", expected)
+	}
+	if actual != "" {
+		err.withContext("actual", actual)
+	}
+	return err
+}
+
 // ============================================================================
 // Token Errors
 // ============================================================================
 
 // NewExpiredTokenError creates an error for expired tokens
 func NewExpiredTokenError(expiryTime, currentTime, tokenType string) error {
+	return NewExpiredTokenErrorWithSkew(expiryTime, currentTime, tokenType, 0)
+}
+
+// NewExpiredTokenErrorWithSkew creates an error for tokens expired beyond the allowed clock skew
+func NewExpiredTokenErrorWithSkew(expiryTime, currentTime, tokenType string, allowedSkew float64) error {
 	err := newError("BA401", "Token has expired")
 	if expiryTime != "" {
 		err.withContext("expiryTime", expiryTime)
@@ -168,11 +210,20 @@ func NewExpiredTokenError(expiryTime, currentTime, tokenType string) error {
 	if tokenType != "" {
 		err.withContext("tokenType", tokenType)
 	}
+	if allowedSkew != 0 {
+		err.withContext("allowedSkew", allowedSkew)
+	}
 	return err
 }
 
 // NewFutureTokenError creates an error for tokens issued in the future
 func NewFutureTokenError(issuedAt, currentTime string, timeDifference float64) error {
+	return NewFutureTokenErrorWithSkew(issuedAt, currentTime, timeDifference, 0)
+}
+
+// NewFutureTokenErrorWithSkew creates an error for tokens issued further in the future than the
+// allowed clock skew
+func NewFutureTokenErrorWithSkew(issuedAt, currentTime string, timeDifference, allowedSkew float64) error {
 	err := newError("BA403", "Token issued_at timestamp is in the future")
 	if issuedAt != "" {
 		err.withContext("issuedAt", issuedAt)
@@ -183,6 +234,58 @@ func NewFutureTokenError(issuedAt, currentTime string, timeDifference float64) e
 	if timeDifference != 0 {
 		err.withContext("timeDifference", timeDifference)
 	}
+	if allowedSkew != 0 {
+		err.withContext("allowedSkew", allowedSkew)
+	}
+	return err
+}
+
+// NewStaleAuthenticationError creates an error for tokens whose authentication is too old
+func NewStaleAuthenticationError(authenticatedAt, currentTime string, maximumAge int64) error {
+	err := newError("BA401", "Token authentication is too old for this operation")
+	err.withContext("tokenType", "authentication")
+	if authenticatedAt != "" {
+		err.withContext("authenticatedAt", authenticatedAt)
+	}
+	if currentTime != "" {
+		err.withContext("currentTime", currentTime)
+	}
+	if maximumAge != 0 {
+		err.withContext("maximumAge", maximumAge)
+	}
+	return err
+}
+
+// NewRevokedSessionError creates an error for tokens belonging to a revoked session
+func NewRevokedSessionError(session string) error {
+	err := newError("BA401", "Session has been revoked")
+	err.withContext("tokenType", "session")
+	err.withContext("reason", "revoked")
+	if session != "" {
+		err.withContext("session", session)
+	}
+	return err
+}
+
+// NewRefreshReuseError creates an error for refresh attempts against an already consumed token
+func NewRefreshReuseError(session string) error {
+	err := newError("BA401", "Refresh token reuse detected, session revoked")
+	err.withContext("tokenType", "refresh")
+	err.withContext("reason", "reused")
+	if session != "" {
+		err.withContext("session", session)
+	}
+	return err
+}
+
+// NewUnknownSessionError creates an error for sessions that are not registered, or have expired or been terminated
+func NewUnknownSessionError(session string) error {
+	err := newError("BA401", "Session not found")
+	err.withContext("tokenType", "session")
+	err.withContext("reason", "unknown")
+	if session != "" {
+		err.withContext("session", session)
+	}
 	return err
 }
 
@@ -207,6 +310,12 @@ func NewStaleRequestError(requestTimestamp, currentTime string, maximumAge int64
 
 // NewFutureRequestError creates an error for requests from the future
 func NewFutureRequestError(requestTimestamp, currentTime string, timeDifference float64) error {
+	return NewFutureRequestErrorWithSkew(requestTimestamp, currentTime, timeDifference, 0)
+}
+
+// NewFutureRequestErrorWithSkew creates an error for requests timestamped further in the future than
+// the allowed clock skew
+func NewFutureRequestErrorWithSkew(requestTimestamp, currentTime string, timeDifference, allowedSkew float64) error {
 	err := newError("BA502", "Request timestamp is in the future")
 	if requestTimestamp != "" {
 		err.withContext("requestTimestamp", requestTimestamp)
@@ -217,5 +326,8 @@ func NewFutureRequestError(requestTimestamp, currentTime string, timeDifference
 	if timeDifference != 0 {
 		err.withContext("timeDifference", timeDifference)
 	}
+	if allowedSkew != 0 {
+		err.withContext("allowedSkew", allowedSkew)
+	}
 	return err
 }
@@ -0,0 +1,21 @@
+package eventinterfaces
+
+import "context"
+
+const (
+	// RefreshReuseDetected is emitted when a consumed refresh link is presented again. The session
+	// is revoked when this happens, since either the legitimate client or an attacker holds a copy.
+	RefreshReuseDetected = "refresh_reuse_detected"
+)
+
+type SecurityEvent struct {
+	Kind     string
+	Identity string
+	Device   string
+	Session  string
+	Details  map[string]any
+}
+
+type SecurityEventEmitter interface {
+	Emit(ctx context.Context, event SecurityEvent)
+}
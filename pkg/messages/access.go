@@ -1,9 +1,11 @@
 package messages
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
 	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
@@ -11,18 +13,81 @@ import (
 	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
 )
 
+// ClockSkew tolerates disagreement between the issuer's clock and the verifier's. Future is how far
+// ahead of the verifier a token or request may be timestamped, Past how long after expiry a token is
+// still honoured. Requests are accepted until the access nonce lifetime after their timestamp, so
+// Past does not extend them. A request timestamped ahead of the verifier stays acceptable for longer
+// than the nonce lifetime, so its nonce is reserved until the request goes stale.
+type ClockSkew struct {
+	Future time.Duration
+	Past   time.Duration
+}
+
 type AccessToken[AttributesType any] struct {
-	ServerIdentity string         `json:"serverIdentity"`
-	Device         string         `json:"device"`
-	Identity       string         `json:"identity"`
-	PublicKey      string         `json:"publicKey"`
-	RotationHash   string         `json:"rotationHash"`
-	IssuedAt       string         `json:"issuedAt"`
-	Expiry         string         `json:"expiry"`
-	RefreshExpiry  string         `json:"refreshExpiry"`
-	Attributes     AttributesType `json:"attributes"`
+	ServerIdentity  string         `json:"serverIdentity"`
+	Device          string         `json:"device"`
+	Identity        string         `json:"identity"`
+	PublicKey       string         `json:"publicKey"`
+	RotationHash    string         `json:"rotationHash"`
+	IssuedAt        string         `json:"issuedAt"`
+	Expiry          string         `json:"expiry"`
+	RefreshExpiry   string         `json:"refreshExpiry"`
+	SessionExpiry   string         `json:"sessionExpiry,omitempty"`
+	AuthenticatedAt string         `json:"authenticatedAt,omitempty"`
+	Session         string         `json:"session,omitempty"`
+	Chain           string         `json:"chain,omitempty"`
+	Tenant          string         `json:"tenant,omitempty"`
+	Attributes      AttributesType `json:"attributes"`
 
 	signature *string `json:"-"`
+	raw       []byte  `json:"-"`
+}
+
+// AccessTokenOption sets one of the optional claims of a new access token.
+type AccessTokenOption func(*accessTokenClaims)
+
+type accessTokenClaims struct {
+	sessionExpiry   string
+	authenticatedAt string
+	session         string
+	chain           string
+	tenant          string
+}
+
+// WithSessionExpiry bounds the session the token belongs to, past which it can't be refreshed.
+func WithSessionExpiry(sessionExpiry string) AccessTokenOption {
+	return func(c *accessTokenClaims) {
+		c.sessionExpiry = sessionExpiry
+	}
+}
+
+// WithAuthenticatedAt records when the session was authenticated.
+func WithAuthenticatedAt(authenticatedAt string) AccessTokenOption {
+	return func(c *accessTokenClaims) {
+		c.authenticatedAt = authenticatedAt
+	}
+}
+
+// WithSession ties the token to a registered session.
+func WithSession(session string) AccessTokenOption {
+	return func(c *accessTokenClaims) {
+		c.session = session
+	}
+}
+
+// WithChain names the refresh chain the token is a link of. It is carried forward on refresh, so
+// that presenting a consumed link again can revoke every token descending from the chain's start.
+func WithChain(chain string) AccessTokenOption {
+	return func(c *accessTokenClaims) {
+		c.chain = chain
+	}
+}
+
+// WithTenant scopes the token to a tenant.
+func WithTenant(tenant string) AccessTokenOption {
+	return func(c *accessTokenClaims) {
+		c.tenant = tenant
+	}
 }
 
 func NewAccessToken[AttributesType any](
@@ -35,17 +100,28 @@ func NewAccessToken[AttributesType any](
 	expiry string,
 	refreshExpiry string,
 	attributes AttributesType,
+	options ...AccessTokenOption,
 ) *AccessToken[AttributesType] {
+	claims := accessTokenClaims{}
+	for _, option := range options {
+		option(&claims)
+	}
+
 	return &AccessToken[AttributesType]{
-		ServerIdentity: serverIdentity,
-		Device:         device,
-		Identity:       identity,
-		PublicKey:      publicKey,
-		RotationHash:   rotationHash,
-		IssuedAt:       issuedAt,
-		Expiry:         expiry,
-		RefreshExpiry:  refreshExpiry,
-		Attributes:     attributes,
+		ServerIdentity:  serverIdentity,
+		Device:          device,
+		Identity:        identity,
+		PublicKey:       publicKey,
+		RotationHash:    rotationHash,
+		IssuedAt:        issuedAt,
+		Expiry:          expiry,
+		RefreshExpiry:   refreshExpiry,
+		SessionExpiry:   claims.sessionExpiry,
+		AuthenticatedAt: claims.authenticatedAt,
+		Session:         claims.session,
+		Chain:           claims.chain,
+		Tenant:          claims.tenant,
+		Attributes:      attributes,
 	}
 }
 
@@ -58,34 +134,54 @@ func ParseAccessToken[AttributesType any](
 		return nil, err
 	}
 
-	if len(message) < signatureLength {
+	// the length comes from a pluggable encoder, so don't trust it to be in range
+	if signatureLength <= 0 || len(message) < signatureLength {
 		return nil, errors.NewInvalidMessageError("message", "too short for signature")
 	}
 
 	signature := message[:signatureLength]
 	rest := message[signatureLength:]
 
-	tokenString, err := tokenEncoder.Decode(rest)
+	raw, err := decodeToken(tokenEncoder, rest)
 	if err != nil {
 		return nil, err
 	}
 
 	accessToken := &AccessToken[AttributesType]{}
-	if err := json.Unmarshal([]byte(tokenString), accessToken); err != nil {
+	if err := json.Unmarshal(raw, accessToken); err != nil {
+		return nil, decodeError("token", err)
+	}
+
+	if err := accessToken.Validate("token"); err != nil {
 		return nil, err
 	}
 
 	accessToken.signature = &signature
+	accessToken.raw = raw
 
 	return accessToken, nil
 }
 
+// decodeToken decodes the token body, straight to bytes when the encoder supports it.
+func decodeToken(tokenEncoder encodinginterfaces.TokenEncoder, token string) ([]byte, error) {
+	if decoder, ok := tokenEncoder.(encodinginterfaces.TokenBytesDecoder); ok {
+		return decoder.DecodeBytes(token)
+	}
+
+	decoded, err := tokenEncoder.Decode(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(decoded), nil
+}
+
 func (at *AccessToken[AttributesType]) SerializeToken(tokenEncoder encodinginterfaces.TokenEncoder) (string, error) {
 	if at.signature == nil {
 		return "", errors.NewInvalidMessageError("signature", "signature is null")
 	}
 
-	composedPayload, err := at.ComposePayload()
+	composedPayload, err := at.payloadBytes()
 	if err != nil {
 		return "", err
 	}
@@ -95,13 +191,44 @@ func (at *AccessToken[AttributesType]) SerializeToken(tokenEncoder encodinginter
 		return "", err
 	}
 
-	token := fmt.Sprintf("%s%s", *at.signature, rawToken)
+	return *at.signature + rawToken, nil
+}
 
-	return token, nil
+// Clone returns a deep copy of the token, decoded afresh from its payload, that can be modified
+// without affecting the original.
+func (at *AccessToken[AttributesType]) Clone() (*AccessToken[AttributesType], error) {
+	composedPayload, err := at.payloadBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	clone := &AccessToken[AttributesType]{}
+	if err := json.Unmarshal(composedPayload, clone); err != nil {
+		return nil, decodeError("token", err)
+	}
+
+	if at.signature != nil {
+		signature := *at.signature
+		clone.signature = &signature
+	}
+
+	if at.raw != nil {
+		clone.raw = bytes.Clone(at.raw)
+	}
+
+	return clone, nil
+}
+
+func (at *AccessToken[AttributesType]) payloadBytes() ([]byte, error) {
+	if at.raw != nil {
+		return at.raw, nil
+	}
+
+	return marshal(at)
 }
 
 func (at *AccessToken[AttributesType]) ComposePayload() (string, error) {
-	composedPayload, err := json.Marshal(at)
+	composedPayload, err := marshal(at)
 	if err != nil {
 		return "", err
 	}
@@ -109,6 +236,16 @@ func (at *AccessToken[AttributesType]) ComposePayload() (string, error) {
 	return string(composedPayload), nil
 }
 
+// ComposeCanonicalPayload composes the token in RFC 8785 (JCS) canonical form.
+func (at *AccessToken[AttributesType]) ComposeCanonicalPayload() (string, error) {
+	canonical, err := canonicalPayload(at)
+	if err != nil {
+		return "", err
+	}
+
+	return string(canonical), nil
+}
+
 func (at *AccessToken[AttributesType]) VerifySignature(
 	verifier cryptointerfaces.Verifier,
 	publicKey string,
@@ -117,12 +254,12 @@ func (at *AccessToken[AttributesType]) VerifySignature(
 		return errors.NewInvalidMessageError("signature", "signature is null")
 	}
 
-	composedPayload, err := at.ComposePayload()
+	composedPayload, err := at.payloadBytes()
 	if err != nil {
 		return err
 	}
 
-	if err := verifier.Verify(*at.signature, publicKey, []byte(composedPayload)); err != nil {
+	if err := verifier.Verify(*at.signature, publicKey, composedPayload); err != nil {
 		return err
 	}
 
@@ -133,11 +270,21 @@ func (at *AccessToken[AttributesType]) VerifyTokenForAccess(
 	verifier cryptointerfaces.Verifier,
 	publicKey string,
 	timestamper encodinginterfaces.Timestamper,
+	skew ClockSkew,
 ) error {
 	if err := at.VerifySignature(verifier, publicKey); err != nil {
 		return err
 	}
 
+	return at.VerifyLifetime(timestamper, skew)
+}
+
+// VerifyLifetime checks that the token has been issued and has not expired, without checking its
+// signature. Verifiers that cache verified tokens run it on every use.
+func (at *AccessToken[AttributesType]) VerifyLifetime(
+	timestamper encodinginterfaces.Timestamper,
+	skew ClockSkew,
+) error {
 	now := timestamper.Now()
 
 	issuedAt, err := timestamper.Parse(at.IssuedAt)
@@ -150,32 +297,78 @@ func (at *AccessToken[AttributesType]) VerifyTokenForAccess(
 		return err
 	}
 
-	if now.Before(issuedAt) {
+	if now.Add(skew.Future).Before(issuedAt) {
 		nowStr := timestamper.Format(now)
 		timeDiff := issuedAt.Sub(now).Seconds()
-		return errors.NewFutureTokenError(at.IssuedAt, nowStr, timeDiff)
+		return errors.NewFutureTokenErrorWithSkew(at.IssuedAt, nowStr, timeDiff, skew.Future.Seconds())
 	}
 
-	if now.After(expiry) {
+	if now.After(expiry.Add(skew.Past)) {
 		nowStr := timestamper.Format(now)
-		return errors.NewExpiredTokenError(at.Expiry, nowStr, "access")
+		return errors.NewExpiredTokenErrorWithSkew(at.Expiry, nowStr, "access", skew.Past.Seconds())
+	}
+
+	return nil
+}
+
+// VerifyFreshness ensures the device key was proven (via CreateSession) within maximumAge.
+// Refreshing a session does not reset the authentication time.
+func (at *AccessToken[AttributesType]) VerifyFreshness(
+	timestamper encodinginterfaces.Timestamper,
+	maximumAge time.Duration,
+) error {
+	now := timestamper.Now()
+	nowStr := timestamper.Format(now)
+	maxAge := ceilSeconds(maximumAge)
+
+	if at.AuthenticatedAt == "" {
+		return errors.NewStaleAuthenticationError("", nowStr, maxAge)
+	}
+
+	authenticatedAt, err := timestamper.Parse(at.AuthenticatedAt)
+	if err != nil {
+		return err
+	}
+
+	if now.After(authenticatedAt.Add(maximumAge)) {
+		return errors.NewStaleAuthenticationError(at.AuthenticatedAt, nowStr, maxAge)
 	}
 
 	return nil
 }
 
+// ceilSeconds rounds d up to whole seconds for error context, so sub-second ages aren't reported as 0.
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
 func (at *AccessToken[AttributesType]) Sign(signingKey cryptointerfaces.SigningKey) error {
-	composedPayload, err := at.ComposePayload()
+	composedPayload, err := marshal(at)
 	if err != nil {
 		return err
 	}
 
-	signature, err := signingKey.Sign([]byte(composedPayload))
+	return at.signComposed(signingKey, composedPayload)
+}
+
+// SignCanonical signs the RFC 8785 (JCS) canonical form of the token, which is then encoded verbatim.
+func (at *AccessToken[AttributesType]) SignCanonical(signingKey cryptointerfaces.SigningKey) error {
+	composedPayload, err := canonicalPayload(at)
+	if err != nil {
+		return err
+	}
+
+	return at.signComposed(signingKey, composedPayload)
+}
+
+func (at *AccessToken[AttributesType]) signComposed(signingKey cryptointerfaces.SigningKey, composedPayload []byte) error {
+	signature, err := signingKey.Sign(composedPayload)
 	if err != nil {
 		return err
 	}
 
 	at.signature = &signature
+	at.raw = composedPayload
 
 	return nil
 }
@@ -191,6 +384,14 @@ type AccessRequestAccess struct {
 	Nonce     string `json:"nonce"`
 	Timestamp string `json:"timestamp"`
 	Token     string `json:"token"`
+	Version   string `json:"version,omitempty"`
+	// Batch is set on batch access requests, so that their signatures can't be presented as a
+	// single request whose payload is an array
+	Batch bool `json:"batch,omitempty"`
+}
+
+func (a *AccessRequestAccess) ProtocolVersion() string {
+	return protocolVersion(a.Version)
 }
 
 func NewAccessRequest[PayloadType any, AttributesType any, RequestType AccessRequest[PayloadType, AttributesType]](
@@ -212,15 +413,39 @@ func NewAccessRequest[PayloadType any, AttributesType any, RequestType AccessReq
 }
 
 func ParseAccessRequest[PayloadType any, AttributesType any, RequestType AccessRequest[PayloadType, AttributesType]](message string, u *RequestType) (*RequestType, error) {
-	if err := json.Unmarshal([]byte(message), u); err != nil {
+	if err := unmarshalMessage(message, u); err != nil {
+		return nil, err
+	}
+
+	if err := validateNested("", u); err != nil {
 		return nil, err
 	}
 
 	return u, nil
 }
 
+func (ar *AccessRequest[PayloadType, AttributesType]) UnmarshalJSON(data []byte) error {
+	raw, signature, err := unmarshalSignable(data, &ar.Payload)
+	if err != nil {
+		return err
+	}
+
+	ar.raw = raw
+	ar.Signature = signature
+
+	return nil
+}
+
+func (ar *AccessRequest[PayloadType, AttributesType]) payloadBytes() ([]byte, error) {
+	if ar.raw != nil {
+		return ar.raw, nil
+	}
+
+	return marshal(ar.Payload)
+}
+
 func (ar *AccessRequest[PayloadType, AttributesType]) ComposePayload() (string, error) {
-	composedPayload, err := json.Marshal(ar.Payload)
+	composedPayload, err := marshal(ar.Payload)
 	if err != nil {
 		return "", err
 	}
@@ -228,8 +453,18 @@ func (ar *AccessRequest[PayloadType, AttributesType]) ComposePayload() (string,
 	return string(composedPayload), nil
 }
 
+// ComposeCanonicalPayload composes the payload in RFC 8785 (JCS) canonical form.
+func (ar *AccessRequest[PayloadType, AttributesType]) ComposeCanonicalPayload() (string, error) {
+	canonical, err := canonicalPayload(ar.Payload)
+	if err != nil {
+		return "", err
+	}
+
+	return string(canonical), nil
+}
+
 func (ar *AccessRequest[PayloadType, AttributesType]) Serialize() (string, error) {
-	composedPayload, err := ar.ComposePayload()
+	composedPayload, err := ar.payloadBytes()
 	if err != nil {
 		return "", err
 	}
@@ -238,17 +473,32 @@ func (ar *AccessRequest[PayloadType, AttributesType]) Serialize() (string, error
 }
 
 func (ar *AccessRequest[PayloadType, AttributesType]) Sign(signer cryptointerfaces.SigningKey) error {
-	composedPayload, err := ar.ComposePayload()
+	composedPayload, err := marshal(ar.Payload)
 	if err != nil {
 		return err
 	}
 
-	signature, err := signer.Sign([]byte(composedPayload))
+	return ar.signComposed(signer, composedPayload)
+}
+
+// SignCanonical signs the RFC 8785 (JCS) canonical form of the payload, which is then serialized verbatim.
+func (ar *AccessRequest[PayloadType, AttributesType]) SignCanonical(signer cryptointerfaces.SigningKey) error {
+	composedPayload, err := canonicalPayload(ar.Payload)
+	if err != nil {
+		return err
+	}
+
+	return ar.signComposed(signer, composedPayload)
+}
+
+func (ar *AccessRequest[PayloadType, AttributesType]) signComposed(signer cryptointerfaces.SigningKey, composedPayload []byte) error {
+	signature, err := signer.Sign(composedPayload)
 	if err != nil {
 		return err
 	}
 
 	ar.Signature = &signature
+	ar.raw = composedPayload
 
 	return nil
 }
@@ -260,7 +510,34 @@ func (ar *AccessRequest[PayloadType, AttributesType]) VerifyAccess(
 	accessKeyStore storageinterfaces.VerificationKeyStore,
 	tokenEncoder encodinginterfaces.TokenEncoder,
 	timestamper encodinginterfaces.Timestamper,
+	skew ClockSkew,
 	attributes *AttributesType,
+) (*AccessToken[AttributesType], error) {
+	accessToken, err := ar.VerifyToken(ctx, accessKeyStore, tokenEncoder, timestamper, skew)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ar.VerifyRequest(ctx, nonceStore, verifier, accessToken, timestamper, skew); err != nil {
+		return nil, err
+	}
+
+	return accessToken, nil
+}
+
+// Token is the access token the request presents.
+func (ar *AccessRequest[PayloadType, AttributesType]) Token() string {
+	return ar.Payload.Access.Token
+}
+
+// VerifyToken decodes the request's access token and verifies it against the server access key it
+// names, over the token encoding if that is signed.
+func (ar *AccessRequest[PayloadType, AttributesType]) VerifyToken(
+	ctx context.Context,
+	accessKeyStore storageinterfaces.VerificationKeyStore,
+	tokenEncoder encodinginterfaces.TokenEncoder,
+	timestamper encodinginterfaces.Timestamper,
+	skew ClockSkew,
 ) (*AccessToken[AttributesType], error) {
 	accessToken, err := ParseAccessToken[AttributesType](
 		ar.Payload.Access.Token,
@@ -281,46 +558,65 @@ func (ar *AccessRequest[PayloadType, AttributesType]) VerifyAccess(
 	}
 
 	if err := accessToken.VerifyTokenForAccess(
-		accessKey.Verifier(),
+		EncodedVerifier(accessKey.Verifier(), tokenEncoder),
 		serverAccessPublicKey,
 		timestamper,
+		skew,
 	); err != nil {
 		return nil, err
 	}
 
-	composedPayload, err := ar.ComposePayload()
+	return accessToken, nil
+}
+
+// VerifyRequest verifies the request itself, made with the key accessToken was issued to: its
+// signature, its timestamp and that its nonce is unused.
+func (ar *AccessRequest[PayloadType, AttributesType]) VerifyRequest(
+	ctx context.Context,
+	nonceStore storageinterfaces.TimeLockStore,
+	verifier cryptointerfaces.Verifier,
+	accessToken *AccessToken[AttributesType],
+	timestamper encodinginterfaces.Timestamper,
+	skew ClockSkew,
+) error {
+	if ar.Signature == nil {
+		return errors.NewInvalidMessageError("signature", "signature is null")
+	}
+
+	composedPayload, err := ar.payloadBytes()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if err := verifier.Verify(*ar.Signature, accessToken.PublicKey, []byte(composedPayload)); err != nil {
-		return nil, err
+	if err := verifier.Verify(*ar.Signature, accessToken.PublicKey, composedPayload); err != nil {
+		return err
 	}
 
 	now := timestamper.Now()
 
 	accessTime, err := timestamper.Parse(ar.Payload.Access.Timestamp)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	expiry := accessTime.Add(nonceStore.Lifetime())
 
 	if now.After(expiry) {
 		nowStr := timestamper.Format(now)
-		maxAge := int64(nonceStore.Lifetime().Seconds())
-		return nil, errors.NewStaleRequestError(ar.Payload.Access.Timestamp, nowStr, maxAge)
+		maxAge := ceilSeconds(nonceStore.Lifetime())
+		return errors.NewStaleRequestError(ar.Payload.Access.Timestamp, nowStr, maxAge)
 	}
 
-	if now.Before(accessTime) {
+	if now.Add(skew.Future).Before(accessTime) {
 		nowStr := timestamper.Format(now)
 		timeDiff := accessTime.Sub(now).Seconds()
-		return nil, errors.NewFutureRequestError(ar.Payload.Access.Timestamp, nowStr, timeDiff)
+		return errors.NewFutureRequestErrorWithSkew(ar.Payload.Access.Timestamp, nowStr, timeDiff, skew.Future.Seconds())
 	}
 
-	if err := nonceStore.Reserve(ctx, ar.Payload.Access.Nonce); err != nil {
-		return nil, err
+	// the nonce must stay reserved for as long as the request would be accepted
+	if accessTime.After(now) {
+		return nonceStore.ReserveFor(ctx, ar.Payload.Access.Nonce, expiry.Sub(now))
 	}
 
-	return accessToken, nil
+	return nonceStore.Reserve(ctx, ar.Payload.Access.Nonce)
 }
@@ -0,0 +1,89 @@
+package messages
+
+import (
+	"fmt"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+)
+
+// BatchRequests is the request of a batch access request, a list of sub-requests sharing a single
+// access token, nonce, timestamp and signature.
+type BatchRequests[PayloadType any] []PayloadType
+
+// BatchResponses is the response to a batch access request, holding one result per sub-request in
+// request order. All results are covered by a single signature.
+type BatchResponses[PayloadType any] []PayloadType
+
+// request
+
+type BatchAccessRequest[PayloadType any, AttributesType any] = AccessRequest[BatchRequests[PayloadType], AttributesType]
+
+func NewBatchAccessRequest[PayloadType any, AttributesType any](
+	payloads []PayloadType,
+	timestamper encodinginterfaces.Timestamper,
+	token string,
+	nonce string,
+) *BatchAccessRequest[PayloadType, AttributesType] {
+	request := NewAccessRequest[BatchRequests[PayloadType], AttributesType, BatchAccessRequest[PayloadType, AttributesType]](
+		payloads,
+		timestamper,
+		token,
+		nonce,
+	)
+
+	request.Payload.Access.Batch = true
+
+	return request
+}
+
+func ParseBatchAccessRequest[PayloadType any, AttributesType any](message string) (*BatchAccessRequest[PayloadType, AttributesType], error) {
+	request, err := ParseAccessRequest(message, &BatchAccessRequest[PayloadType, AttributesType]{})
+	if err != nil {
+		return nil, err
+	}
+
+	if !request.Payload.Access.Batch {
+		return nil, errors.NewInvalidMessageError("payload.access.batch", "not a batch request")
+	}
+
+	return request, nil
+}
+
+// response
+
+type BatchResponse[PayloadType any] = ServerResponse[BatchResponses[PayloadType]]
+
+func NewBatchResponse[PayloadType any](
+	payloads []PayloadType,
+	serverIdentity string,
+	nonce string,
+) *BatchResponse[PayloadType] {
+	return NewServerResponse(BatchResponses[PayloadType](payloads), serverIdentity, nonce)
+}
+
+func ParseBatchResponse[PayloadType any](message string) (*BatchResponse[PayloadType], error) {
+	return ParseServerResponse(message, &BatchResponse[PayloadType]{})
+}
+
+func (b *BatchRequests[PayloadType]) Validate(path string) error {
+	if len(*b) == 0 {
+		return errors.NewInvalidMessageError(path, "batch is empty")
+	}
+
+	return validateItems(path, *b)
+}
+
+func (b *BatchResponses[PayloadType]) Validate(path string) error {
+	return validateItems(path, *b)
+}
+
+func validateItems[PayloadType any](path string, items []PayloadType) error {
+	for i := range items {
+		if err := validateNested(fmt.Sprintf("%s[%d]", path, i), &items[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
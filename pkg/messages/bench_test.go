@@ -0,0 +1,83 @@
+package messages_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+	"github.com/jasoncolburne/better-auth-go/testvectors"
+)
+
+func benchmarkToken(b *testing.B) string {
+	b.Helper()
+
+	corpus, err := testvectors.Load("../../testvectors/testdata")
+	if err != nil {
+		b.Fatalf("failed to load test vectors: %v", err)
+	}
+
+	return corpus.Tokens[0].Token
+}
+
+func BenchmarkParseAccessToken(b *testing.B) {
+	token := benchmarkToken(b)
+	tokenEncoder := encoding.NewTokenEncoder[testvectors.Attributes]()
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := messages.ParseAccessToken[testvectors.Attributes](token, tokenEncoder); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSerializeAccessToken(b *testing.B) {
+	tokenEncoder := encoding.NewTokenEncoder[testvectors.Attributes]()
+
+	accessToken, err := messages.ParseAccessToken[testvectors.Attributes](benchmarkToken(b), tokenEncoder)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := accessToken.SerializeToken(tokenEncoder); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseAccessRequest(b *testing.B) {
+	key, err := crypto.NewSecp256r1()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	request := messages.NewAccessRequest[json.RawMessage, testvectors.Attributes](
+		json.RawMessage(`{"foo":"bar","bar":"foo"}`),
+		encoding.NewRfc3339Nano(),
+		benchmarkToken(b),
+		validNonce,
+	)
+
+	if err := request.Sign(key); err != nil {
+		b.Fatal(err)
+	}
+
+	message, err := request.Serialize()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := messages.ParseAccessRequest(message, &messages.AccessRequest[json.RawMessage, testvectors.Attributes]{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
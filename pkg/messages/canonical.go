@@ -0,0 +1,208 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// marshal encodes value as compact JSON without HTML escaping, matching JSON.stringify and the
+// serializers used by the other implementations.
+func marshal(value any) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buffer.Bytes(), []byte{'\n'}), nil
+}
+
+// canonicalPayload encodes value in RFC 8785 (JCS) canonical form.
+func canonicalPayload(value any) ([]byte, error) {
+	composedPayload, err := marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return Canonicalize(composedPayload)
+}
+
+// Canonicalize transforms JSON into its RFC 8785 (JCS) canonical form: no insignificant whitespace,
+// object members sorted by UTF-16 code units, ECMAScript number formatting and minimal string escaping.
+func Canonicalize(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after json value")
+	}
+
+	buffer := bytes.Buffer{}
+	if err := writeCanonical(&buffer, value); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func writeCanonical(buffer *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case bool:
+		buffer.WriteString(strconv.FormatBool(v))
+	case string:
+		writeCanonicalString(buffer, v)
+	case json.Number:
+		number, err := formatCanonicalNumber(v)
+		if err != nil {
+			return err
+		}
+
+		buffer.WriteString(number)
+	case []any:
+		buffer.WriteByte('[')
+		for i, element := range v {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			if err := writeCanonical(buffer, element); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		slices.SortFunc(keys, func(a, b string) int {
+			return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+		})
+
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			writeCanonicalString(buffer, key)
+			buffer.WriteByte(':')
+
+			if err := writeCanonical(buffer, v[key]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported json value %T", value)
+	}
+
+	return nil
+}
+
+func writeCanonicalString(buffer *bytes.Buffer, value string) {
+	buffer.WriteByte('"')
+
+	for _, r := range value {
+		switch r {
+		case '"':
+			buffer.WriteString(`\"`)
+		case '\\':
+			buffer.WriteString(`\\`)
+		case '\b':
+			buffer.WriteString(`\b`)
+		case '\f':
+			buffer.WriteString(`\f`)
+		case '\n':
+			buffer.WriteString(`\n`)
+		case '\r':
+			buffer.WriteString(`\r`)
+		case '\t':
+			buffer.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buffer, `\u%04x`, r)
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+
+	buffer.WriteByte('"')
+}
+
+// formatCanonicalNumber implements ECMAScript's Number.prototype.toString for IEEE 754 doubles.
+func formatCanonicalNumber(number json.Number) (string, error) {
+	value, err := strconv.ParseFloat(string(number), 64)
+	if err != nil {
+		return "", err
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return "", fmt.Errorf("number not representable in json: %s", number)
+	}
+
+	if value == 0 {
+		return "0", nil
+	}
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	// shortest round-tripping digits, as d.ddde±x
+	scientific := strconv.FormatFloat(value, 'e', -1, 64)
+	mantissa, exponentText, _ := strings.Cut(scientific, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+
+	exponent, err := strconv.Atoi(exponentText)
+	if err != nil {
+		return "", err
+	}
+
+	k := len(digits)
+	n := exponent + 1
+
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k), nil
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:], nil
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits, nil
+	}
+
+	exponentSign := "+"
+	if n-1 < 0 {
+		exponentSign = "-"
+	}
+
+	exponentValue := n - 1
+	if exponentValue < 0 {
+		exponentValue = -exponentValue
+	}
+
+	if k == 1 {
+		return fmt.Sprintf("%s%se%s%d", sign, digits, exponentSign, exponentValue), nil
+	}
+
+	return fmt.Sprintf("%s%s.%se%s%d", sign, digits[:1], digits[1:], exponentSign, exponentValue), nil
+}
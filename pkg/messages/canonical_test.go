@@ -0,0 +1,78 @@
+package messages_test
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+type canonicalizationVectors struct {
+	Canonicalization []struct {
+		Name     string `json:"name"`
+		Input    string `json:"input"`
+		Expected string `json:"expected"`
+	} `json:"canonicalization"`
+	Numbers []struct {
+		Bits     string `json:"bits"`
+		Expected string `json:"expected"`
+	} `json:"numbers"`
+}
+
+func loadCanonicalizationVectors(t *testing.T) *canonicalizationVectors {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/jcs.json")
+	if err != nil {
+		t.Fatalf("failed to read vectors: %v", err)
+	}
+
+	vectors := &canonicalizationVectors{}
+	if err := json.Unmarshal(data, vectors); err != nil {
+		t.Fatalf("failed to parse vectors: %v", err)
+	}
+
+	return vectors
+}
+
+func TestCanonicalize(t *testing.T) {
+	vectors := loadCanonicalizationVectors(t)
+
+	for _, vector := range vectors.Canonicalization {
+		t.Run(vector.Name, func(t *testing.T) {
+			canonical, err := messages.Canonicalize([]byte(vector.Input))
+			if err != nil {
+				t.Fatalf("failed to canonicalize: %v", err)
+			}
+
+			if string(canonical) != vector.Expected {
+				t.Errorf("expected %s, got %s", vector.Expected, canonical)
+			}
+		})
+	}
+}
+
+func TestCanonicalNumbers(t *testing.T) {
+	vectors := loadCanonicalizationVectors(t)
+
+	for _, vector := range vectors.Numbers {
+		bits, err := strconv.ParseUint(vector.Bits, 16, 64)
+		if err != nil {
+			t.Fatalf("invalid bits %s: %v", vector.Bits, err)
+		}
+
+		input := strconv.FormatFloat(math.Float64frombits(bits), 'g', -1, 64)
+
+		canonical, err := messages.Canonicalize([]byte(input))
+		if err != nil {
+			t.Fatalf("failed to canonicalize %s: %v", input, err)
+		}
+
+		if string(canonical) != vector.Expected {
+			t.Errorf("%s: expected %s, got %s", vector.Bits, vector.Expected, canonical)
+		}
+	}
+}
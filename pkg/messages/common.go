@@ -19,10 +19,72 @@ type Serializable interface {
 type SignableMessage[PayloadType any] struct {
 	Payload   PayloadType `json:"payload"`
 	Signature *string     `json:"signature,omitempty"`
+
+	// raw holds the exact payload bytes that were parsed or signed. signatures are always verified
+	// over these when present, so fields unknown to this implementation don't break verification.
+	raw []byte `json:"-"`
+}
+
+type signableEnvelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature *string         `json:"signature,omitempty"`
+}
+
+func unmarshalSignable(data []byte, payload any) ([]byte, *string, error) {
+	envelope := signableEnvelope{}
+	if err := decodeEnvelope(data, &envelope); err != nil {
+		return nil, nil, err
+	}
+
+	if err := json.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, nil, decodeError("payload", err)
+	}
+
+	return envelope.Payload, envelope.Signature, nil
+}
+
+// unmarshalMessage decodes a whole message into u, a signable message. Envelopes are decoded
+// strictly, rejecting malformed json, so this spares the validation pass json.Unmarshal makes over
+// the message before decoding it.
+func unmarshalMessage(message string, u any) error {
+	return u.(json.Unmarshaler).UnmarshalJSON([]byte(message))
+}
+
+func (sm *SignableMessage[PayloadType]) UnmarshalJSON(data []byte) error {
+	raw, signature, err := unmarshalSignable(data, &sm.Payload)
+	if err != nil {
+		return err
+	}
+
+	sm.raw = raw
+	sm.Signature = signature
+
+	return nil
+}
+
+// MarshalJSON preserves the signed payload bytes of nested messages, such as link containers.
+func (sm SignableMessage[PayloadType]) MarshalJSON() ([]byte, error) {
+	payload, err := sm.payloadBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return marshal(signableEnvelope{
+		Payload:   payload,
+		Signature: sm.Signature,
+	})
+}
+
+func (sm *SignableMessage[PayloadType]) payloadBytes() ([]byte, error) {
+	if sm.raw != nil {
+		return sm.raw, nil
+	}
+
+	return marshal(sm.Payload)
 }
 
 func (sm *SignableMessage[PayloadType]) ComposePayload() (string, error) {
-	bytes, err := json.Marshal(sm.Payload)
+	bytes, err := marshal(sm.Payload)
 	if err != nil {
 		return "", err
 	}
@@ -30,8 +92,18 @@ func (sm *SignableMessage[PayloadType]) ComposePayload() (string, error) {
 	return string(bytes), nil
 }
 
+// ComposeCanonicalPayload composes the payload in RFC 8785 (JCS) canonical form.
+func (sm *SignableMessage[PayloadType]) ComposeCanonicalPayload() (string, error) {
+	canonical, err := canonicalPayload(sm.Payload)
+	if err != nil {
+		return "", err
+	}
+
+	return string(canonical), nil
+}
+
 func (sm *SignableMessage[PayloadType]) Serialize() (string, error) {
-	composedPayload, err := sm.ComposePayload()
+	composedPayload, err := sm.payloadBytes()
 	if err != nil {
 		return "", err
 	}
@@ -44,17 +116,32 @@ func (sm *SignableMessage[PayloadType]) Serialize() (string, error) {
 }
 
 func (sm *SignableMessage[PayloadType]) Sign(signer cryptointerfaces.SigningKey) error {
-	composedPayload, err := sm.ComposePayload()
+	composedPayload, err := marshal(sm.Payload)
 	if err != nil {
 		return err
 	}
 
-	signature, err := signer.Sign([]byte(composedPayload))
+	return sm.signComposed(signer, composedPayload)
+}
+
+// SignCanonical signs the RFC 8785 (JCS) canonical form of the payload, which is then serialized verbatim.
+func (sm *SignableMessage[PayloadType]) SignCanonical(signer cryptointerfaces.SigningKey) error {
+	composedPayload, err := canonicalPayload(sm.Payload)
+	if err != nil {
+		return err
+	}
+
+	return sm.signComposed(signer, composedPayload)
+}
+
+func (sm *SignableMessage[PayloadType]) signComposed(signer cryptointerfaces.SigningKey, composedPayload []byte) error {
+	signature, err := signer.Sign(composedPayload)
 	if err != nil {
 		return err
 	}
 
 	sm.Signature = &signature
+	sm.raw = composedPayload
 
 	return nil
 }
@@ -64,16 +151,33 @@ func (sm *SignableMessage[PayloadType]) Verify(verifier cryptointerfaces.Verifie
 		return errors.NewInvalidMessageError("signature", "signature is null")
 	}
 
-	composedPayload, err := sm.ComposePayload()
+	composedPayload, err := sm.payloadBytes()
 	if err != nil {
 		return err
 	}
 
-	return verifier.Verify(*sm.Signature, publicKey, []byte(composedPayload))
+	return verifier.Verify(*sm.Signature, publicKey, composedPayload)
+}
+
+// DefaultProtocolVersion is assumed for messages that don't carry a version.
+const DefaultProtocolVersion = "1"
+
+func protocolVersion(version string) string {
+	if version == "" {
+		return DefaultProtocolVersion
+	}
+
+	return version
 }
 
 type ClientAccess struct {
-	Nonce string `json:"nonce"`
+	Nonce   string `json:"nonce"`
+	Tenant  string `json:"tenant,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+func (ca *ClientAccess) ProtocolVersion() string {
+	return protocolVersion(ca.Version)
 }
 
 type ClientPayload[PayloadType any] struct {
@@ -95,8 +199,11 @@ func NewClientRequest[PayloadType any, RequestType ClientRequest[PayloadType]](p
 }
 
 func ParseClientRequest[PayloadType any, RequestType ClientRequest[PayloadType]](message string, u *RequestType) (*RequestType, error) {
-	err := json.Unmarshal([]byte(message), u)
-	if err != nil {
+	if err := unmarshalMessage(message, u); err != nil {
+		return nil, err
+	}
+
+	if err := validateNested("", u); err != nil {
 		return nil, err
 	}
 
@@ -106,6 +213,11 @@ func ParseClientRequest[PayloadType any, RequestType ClientRequest[PayloadType]]
 type ServerAccess struct {
 	Nonce          string `json:"nonce"`
 	ServerIdentity string `json:"serverIdentity"`
+	Version        string `json:"version,omitempty"`
+}
+
+func (sa *ServerAccess) ProtocolVersion() string {
+	return protocolVersion(sa.Version)
 }
 
 type ServerPayload[PayloadType any] struct {
@@ -132,8 +244,11 @@ func NewServerResponse[PayloadType any, ResponseType ServerResponse[PayloadType]
 }
 
 func ParseServerResponse[PayloadType any, ResponseType ServerResponse[PayloadType]](message string, u *ResponseType) (*ResponseType, error) {
-	err := json.Unmarshal([]byte(message), u)
-	if err != nil {
+	if err := unmarshalMessage(message, u); err != nil {
+		return nil, err
+	}
+
+	if err := validateNested("", u); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,62 @@
+package messages
+
+import (
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+)
+
+// EncodedSigningKey signs payloads in the given encoding, if it is an
+// encodinginterfaces.SignedEncoding, and as JSON otherwise. Messages and tokens sent in a signed
+// encoding must be signed with it.
+func EncodedSigningKey(key cryptointerfaces.SigningKey, encoding any) cryptointerfaces.SigningKey {
+	signed, ok := encoding.(encodinginterfaces.SignedEncoding)
+	if !ok {
+		return key
+	}
+
+	return &encodedSigningKey{SigningKey: key, encoding: signed}
+}
+
+// EncodedVerifier verifies payloads in the given encoding, if it is an
+// encodinginterfaces.SignedEncoding, and as JSON otherwise.
+func EncodedVerifier(verifier cryptointerfaces.Verifier, encoding any) cryptointerfaces.Verifier {
+	signed, ok := encoding.(encodinginterfaces.SignedEncoding)
+	if !ok {
+		return verifier
+	}
+
+	return &encodedVerifier{verifier: verifier, encoding: signed}
+}
+
+type encodedSigningKey struct {
+	cryptointerfaces.SigningKey
+	encoding encodinginterfaces.SignedEncoding
+}
+
+func (k *encodedSigningKey) Sign(message []byte) (string, error) {
+	encoded, err := k.encoding.EncodePayload(message)
+	if err != nil {
+		return "", err
+	}
+
+	return k.SigningKey.Sign(encoded)
+}
+
+func (k *encodedSigningKey) Verifier() cryptointerfaces.Verifier {
+	return EncodedVerifier(k.SigningKey.Verifier(), k.encoding)
+}
+
+type encodedVerifier struct {
+	verifier cryptointerfaces.Verifier
+	encoding encodinginterfaces.SignedEncoding
+}
+
+func (v *encodedVerifier) Verify(signature, publicKey string, message []byte) error {
+	encoded, err := v.encoding.EncodePayload(message)
+	if err != nil {
+		return errors.NewInvalidMessageError("payload", err.Error())
+	}
+
+	return v.verifier.Verify(signature, publicKey, encoded)
+}
@@ -0,0 +1,186 @@
+package messages_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+	"github.com/jasoncolburne/better-auth-go/testvectors"
+)
+
+type fuzzAttributes struct {
+	PermissionsByRole map[string][]string `json:"permissionsByRole"`
+}
+
+type parsed interface {
+	Serialize() (string, error)
+}
+
+func parser[T parsed](parse func(string) (T, error)) func(string) (parsed, error) {
+	return func(message string) (parsed, error) {
+		return parse(message)
+	}
+}
+
+var parsers = map[string]func(string) (parsed, error){
+	"CreateAccountRequest":       parser(messages.ParseCreateAccountRequest),
+	"CreateAccountResponse":      parser(messages.ParseCreateAccountResponse),
+	"RecoverAccountRequest":      parser(messages.ParseRecoverAccountRequest),
+	"RecoverAccountResponse":     parser(messages.ParseRecoverAccountResponse),
+	"DeleteAccountRequest":       parser(messages.ParseDeleteAccountRequest),
+	"DeleteAccountResponse":      parser(messages.ParseDeleteAccountResponse),
+	"ChangeRecoveryKeyRequest":   parser(messages.ParseChangeRecoveryKeyRequest),
+	"ChangeRecoveryKeyResponse":  parser(messages.ParseChangeRecoveryKeyResponse),
+	"LinkDeviceRequest":          parser(messages.ParseLinkDeviceRequest),
+	"LinkDeviceResponse":         parser(messages.ParseLinkDeviceResponse),
+	"UnlinkDeviceRequest":        parser(messages.ParseUnlinkDeviceRequest),
+	"UnlinkDeviceResponse":       parser(messages.ParseUnlinkDeviceResponse),
+	"RotateDeviceRequest":        parser(messages.ParseRotateDeviceRequest),
+	"RotateDeviceResponse":       parser(messages.ParseRotateDeviceResponse),
+	"RequestSessionRequest":      parser(messages.ParseRequestSessionRequest),
+	"RequestSessionResponse":     parser(messages.ParseRequestSessionResponse),
+	"CreateSessionRequest":       parser(messages.ParseCreateSessionRequest),
+	"CreateSessionResponse":      parser(messages.ParseCreateSessionResponse),
+	"RefreshSessionRequest":      parser(messages.ParseRefreshSessionRequest),
+	"RefreshSessionResponse":     parser(messages.ParseRefreshSessionResponse),
+	"BatchResponse":              parser(messages.ParseBatchResponse[json.RawMessage]),
+	"BatchAccessRequest":         parser(messages.ParseBatchAccessRequest[json.RawMessage, fuzzAttributes]),
+	"AccessRequest":              parser(parseAccessRequest),
+	"ClientRequest":              parser(parseClientRequest),
+	"ServerResponse":             parser(parseServerResponse),
+	"AccessRequestTypedPayload":  parser(parseTypedAccessRequest),
+	"ClientRequestTypedPayload":  parser(parseTypedClientRequest),
+	"ServerResponseTypedPayload": parser(parseTypedServerResponse),
+}
+
+type typedPayload struct {
+	Resource string               `json:"resource"`
+	Values   []int                `json:"values"`
+	Nested   *struct{ Flag bool } `json:"nested"`
+}
+
+func parseAccessRequest(message string) (*messages.AccessRequest[json.RawMessage, fuzzAttributes], error) {
+	return messages.ParseAccessRequest(message, &messages.AccessRequest[json.RawMessage, fuzzAttributes]{})
+}
+
+func parseTypedAccessRequest(message string) (*messages.AccessRequest[typedPayload, fuzzAttributes], error) {
+	return messages.ParseAccessRequest(message, &messages.AccessRequest[typedPayload, fuzzAttributes]{})
+}
+
+func parseClientRequest(message string) (*messages.ClientRequest[json.RawMessage], error) {
+	return messages.ParseClientRequest(message, &messages.ClientRequest[json.RawMessage]{})
+}
+
+func parseTypedClientRequest(message string) (*messages.ClientRequest[typedPayload], error) {
+	return messages.ParseClientRequest(message, &messages.ClientRequest[typedPayload]{})
+}
+
+func parseServerResponse(message string) (*messages.ServerResponse[json.RawMessage], error) {
+	return messages.ParseServerResponse(message, &messages.ServerResponse[json.RawMessage]{})
+}
+
+func parseTypedServerResponse(message string) (*messages.ServerResponse[typedPayload], error) {
+	return messages.ParseServerResponse(message, &messages.ServerResponse[typedPayload]{})
+}
+
+// seedMessages returns every request and response in the protocol test vectors.
+func seedMessages(f *testing.F) []string {
+	f.Helper()
+
+	corpus, err := testvectors.Load("../../testvectors/testdata")
+	if err != nil {
+		f.Fatalf("failed to load test vectors: %v", err)
+	}
+
+	seeds := []string{"", "{}", "null", `{"payload":null}`, validCreateAccountRequest()}
+	for _, scenario := range corpus.Scenarios {
+		for _, step := range scenario.Steps {
+			seeds = append(seeds, step.Request)
+			if step.Response != "" {
+				seeds = append(seeds, step.Response)
+			}
+		}
+	}
+
+	return seeds
+}
+
+// FuzzParse feeds every parser the same input. Parsing may fail but must not panic, and anything
+// accepted must serialize to a message that parses again.
+func FuzzParse(f *testing.F) {
+	for _, seed := range seedMessages(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, message string) {
+		for name, parse := range parsers {
+			parsed, err := parse(message)
+			if err != nil {
+				continue
+			}
+
+			serialized, err := parsed.Serialize()
+			if err != nil {
+				t.Fatalf("%s: failed to serialize accepted message: %v", name, err)
+			}
+
+			if _, err := parse(serialized); err != nil {
+				t.Fatalf("%s: failed to reparse %s: %v", name, serialized, err)
+			}
+		}
+	})
+}
+
+// untrustedEncoder reports whatever signature length it is given.
+type untrustedEncoder struct {
+	encodinginterfaces.TokenEncoder
+	signatureLength int
+}
+
+func (e untrustedEncoder) SignatureLength(token string) (int, error) {
+	return e.signatureLength, nil
+}
+
+// FuzzParseAccessToken parses tokens with each encoder, and with an encoder reporting arbitrary
+// signature lengths.
+func FuzzParseAccessToken(f *testing.F) {
+	corpus, err := testvectors.Load("../../testvectors/testdata")
+	if err != nil {
+		f.Fatalf("failed to load test vectors: %v", err)
+	}
+
+	for _, vector := range corpus.Tokens {
+		f.Add(vector.Token, 88)
+	}
+
+	f.Add("", 0)
+	f.Add("0I"+strings.Repeat("A", 86), -1)
+	f.Add("0I", 1<<20)
+
+	encoders := map[string]encodinginterfaces.TokenEncoder{
+		"gzip":   encoding.NewTokenEncoder[fuzzAttributes](),
+		"base64": encoding.NewBase64TokenEncoder[fuzzAttributes](),
+		"cbor":   encoding.NewCBORTokenEncoder[fuzzAttributes](),
+	}
+
+	f.Fuzz(func(t *testing.T, token string, signatureLength int) {
+		encoders["untrusted"] = untrustedEncoder{
+			TokenEncoder:    encoding.NewBase64TokenEncoder[fuzzAttributes](),
+			signatureLength: signatureLength,
+		}
+
+		for name, encoder := range encoders {
+			accessToken, err := messages.ParseAccessToken[fuzzAttributes](token, encoder)
+			if err != nil {
+				continue
+			}
+
+			if _, err := accessToken.SerializeToken(encoder); err != nil {
+				t.Fatalf("%s: failed to serialize accepted token: %v", name, err)
+			}
+		}
+	})
+}
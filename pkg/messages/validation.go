@@ -0,0 +1,413 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"strings"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/cesr"
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+)
+
+// Validatable is implemented by every payload in this package. Application payloads carried by
+// access requests and token attributes may implement it too, and are then validated on parse.
+// path is the JSON path of the receiver, and is used to report the offending field.
+type Validatable interface {
+	Validate(path string) error
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+
+	return path + "." + field
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateRequired(path, value string) error {
+	if value == "" {
+		return errors.NewInvalidMessageError(path, "required")
+	}
+
+	return nil
+}
+
+func validatePrimitive(path, value string, kinds ...cesr.Kind) error {
+	if err := validateRequired(path, value); err != nil {
+		return err
+	}
+
+	if err := cesr.Validate(value, kinds...); err != nil {
+		return errors.NewInvalidMessageError(path, err.Error())
+	}
+
+	return nil
+}
+
+func validateDigest(path, value string) error {
+	return validatePrimitive(path, value, cesr.Digest)
+}
+
+func validateNonce(path, value string) error {
+	return validatePrimitive(path, value, cesr.Salt)
+}
+
+func validatePublicKey(path, value string) error {
+	return validatePrimitive(path, value, cesr.PublicKey)
+}
+
+// identities are either digests or, for servers, their public keys
+func validateIdentity(path, value string) error {
+	return validatePrimitive(path, value, cesr.Digest, cesr.PublicKey)
+}
+
+func validateSignature(path string, value *string) error {
+	if value == nil {
+		return nil
+	}
+
+	return validatePrimitive(path, *value, cesr.Signature)
+}
+
+func validateNested(path string, value any) error {
+	if validatable, ok := value.(Validatable); ok {
+		return validatable.Validate(path)
+	}
+
+	return nil
+}
+
+// decodeError maps json decoding failures onto invalid message errors. offset is prefixed to the
+// path of type errors, since payloads are decoded separately from their envelope.
+func decodeError(offset string, err error) error {
+	var typeError *json.UnmarshalTypeError
+	if stderrors.As(err, &typeError) {
+		path := offset
+		if typeError.Field != "" {
+			path = joinPath(offset, typeError.Field)
+		}
+
+		return errors.NewInvalidMessageError(path, "expected "+typeError.Type.String())
+	}
+
+	// decoders report truncated input as EOF rather than a syntax error
+	var syntaxError *json.SyntaxError
+	if stderrors.As(err, &syntaxError) || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.NewInvalidMessageError("message", "malformed json")
+	}
+
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), "\"")
+		return errors.NewInvalidMessageError(joinPath(offset, field), "unknown field")
+	}
+
+	return err
+}
+
+// unknown fields are rejected in the envelope only. payloads are deliberately lenient, since newer
+// versions of the protocol may add fields to them: a payload's known fields are still validated,
+// and its signature is verified over its raw bytes, so the unknown ones are covered without being
+// understood.
+func decodeEnvelope(data []byte, envelope *signableEnvelope) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(envelope); err != nil {
+		return decodeError("", err)
+	}
+
+	// the decoder stops after the envelope, so check nothing but whitespace follows it
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.NewInvalidMessageError("message", "malformed json")
+	}
+
+	if len(envelope.Payload) == 0 || string(envelope.Payload) == "null" {
+		return errors.NewInvalidMessageError("payload", "required")
+	}
+
+	return nil
+}
+
+func (ca *ClientAccess) Validate(path string) error {
+	return validateNonce(joinPath(path, "nonce"), ca.Nonce)
+}
+
+func (cp *ClientPayload[PayloadType]) Validate(path string) error {
+	return firstError(
+		cp.Access.Validate(joinPath(path, "access")),
+		validateNested(joinPath(path, "request"), &cp.Request),
+	)
+}
+
+func (sa *ServerAccess) Validate(path string) error {
+	return firstError(
+		validateNonce(joinPath(path, "nonce"), sa.Nonce),
+		validateIdentity(joinPath(path, "serverIdentity"), sa.ServerIdentity),
+	)
+}
+
+func (sp *ServerPayload[PayloadType]) Validate(path string) error {
+	return firstError(
+		sp.Access.Validate(joinPath(path, "access")),
+		validateNested(joinPath(path, "response"), &sp.Response),
+	)
+}
+
+func (sm *SignableMessage[PayloadType]) Validate(path string) error {
+	return firstError(
+		validateNested(joinPath(path, "payload"), &sm.Payload),
+		validateSignature(joinPath(path, "signature"), sm.Signature),
+	)
+}
+
+// account
+
+func (a *CreateAccountRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "recoveryHash"), a.RecoveryHash),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (p *CreateAccountRequestPayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+func (a *RecoverAccountRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "recoveryHash"), a.RecoveryHash),
+		validatePublicKey(joinPath(path, "recoveryKey"), a.RecoveryKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (p *RecoverAccountRequestPayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+func (a *DeleteAccountRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (p *DeleteAccountRequestPayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+// recovery
+
+func (a *ChangeRecoveryKeyRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "recoveryHash"), a.RecoveryHash),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (p *ChangeRecoveryKeyRequestPayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+// device
+
+func (a *LinkContainerAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (p *LinkContainerPayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+func (a *LinkDeviceRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (p *LinkDeviceRequestPayload) Validate(path string) error {
+	linkPath := joinPath(path, "link")
+
+	return firstError(
+		p.Authentication.Validate(joinPath(path, "authentication")),
+		p.Link.Validate(linkPath),
+		validateRequired(joinPath(linkPath, "signature"), stringOrEmpty(p.Link.Signature)),
+	)
+}
+
+func (a *UnlinkDeviceRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (l *UnlinkDeviceRequestLink) Validate(path string) error {
+	return validateDigest(joinPath(path, "device"), l.Device)
+}
+
+func (p *UnlinkDeviceRequestPayload) Validate(path string) error {
+	return firstError(
+		p.Authentication.Validate(joinPath(path, "authentication")),
+		p.Link.Validate(joinPath(path, "link")),
+	)
+}
+
+func (a *RotateDeviceRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateDigest(joinPath(path, "identity"), a.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (p *RotateDeviceRequestPayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+// session
+
+func (a *RequestSessionRequestAuthentication) Validate(path string) error {
+	return validateDigest(joinPath(path, "identity"), a.Identity)
+}
+
+func (p *RequestSessionRequestPayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+func (a *RequestSessionResponseAuthentication) Validate(path string) error {
+	return validateNonce(joinPath(path, "nonce"), a.Nonce)
+}
+
+func (p *RequestSessionResponsePayload) Validate(path string) error {
+	return p.Authentication.Validate(joinPath(path, "authentication"))
+}
+
+func (a *CreateSessionRequestAccess) Validate(path string) error {
+	return firstError(
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+	)
+}
+
+func (a *CreateSessionRequestAuthentication) Validate(path string) error {
+	return firstError(
+		validateDigest(joinPath(path, "device"), a.Device),
+		validateNonce(joinPath(path, "nonce"), a.Nonce),
+	)
+}
+
+func (p *CreateSessionRequestPayload) Validate(path string) error {
+	return firstError(
+		p.Access.Validate(joinPath(path, "access")),
+		p.Authentication.Validate(joinPath(path, "authentication")),
+	)
+}
+
+func (a *CreateSessionResponseAccess) Validate(path string) error {
+	return validateRequired(joinPath(path, "token"), a.Token)
+}
+
+func (p *CreateSessionResponsePayload) Validate(path string) error {
+	return p.Access.Validate(joinPath(path, "access"))
+}
+
+func (a *RefreshSessionRequestAccess) Validate(path string) error {
+	return firstError(
+		validatePublicKey(joinPath(path, "publicKey"), a.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), a.RotationHash),
+		validateRequired(joinPath(path, "token"), a.Token),
+	)
+}
+
+func (p *RefreshSessionRequestPayload) Validate(path string) error {
+	return p.Access.Validate(joinPath(path, "access"))
+}
+
+func (a *RefreshSessionResponseAccess) Validate(path string) error {
+	return validateRequired(joinPath(path, "token"), a.Token)
+}
+
+func (p *RefreshSessionResponsePayload) Validate(path string) error {
+	return p.Access.Validate(joinPath(path, "access"))
+}
+
+// access
+
+func (at *AccessToken[AttributesType]) Validate(path string) error {
+	return firstError(
+		validateIdentity(joinPath(path, "serverIdentity"), at.ServerIdentity),
+		validateDigest(joinPath(path, "device"), at.Device),
+		validateDigest(joinPath(path, "identity"), at.Identity),
+		validatePublicKey(joinPath(path, "publicKey"), at.PublicKey),
+		validateDigest(joinPath(path, "rotationHash"), at.RotationHash),
+		validateRequired(joinPath(path, "issuedAt"), at.IssuedAt),
+		validateRequired(joinPath(path, "expiry"), at.Expiry),
+		validateRequired(joinPath(path, "refreshExpiry"), at.RefreshExpiry),
+		validateNested(joinPath(path, "attributes"), &at.Attributes),
+	)
+}
+
+func (a *AccessRequestAccess) Validate(path string) error {
+	return firstError(
+		validateNonce(joinPath(path, "nonce"), a.Nonce),
+		validateRequired(joinPath(path, "timestamp"), a.Timestamp),
+		validateRequired(joinPath(path, "token"), a.Token),
+	)
+}
+
+func (p *AccessRequestPayload[PayloadType]) Validate(path string) error {
+	return firstError(
+		p.Access.Validate(joinPath(path, "access")),
+		validateNested(joinPath(path, "request"), &p.Request),
+	)
+}
+
+func (ar *AccessRequest[PayloadType, AttributesType]) Validate(path string) error {
+	return firstError(
+		ar.Payload.Validate(joinPath(path, "payload")),
+		validateSignature(joinPath(path, "signature"), ar.Signature),
+	)
+}
+
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
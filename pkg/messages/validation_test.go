@@ -0,0 +1,134 @@
+package messages_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	baerrors "github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+var (
+	validDevice       = "E" + strings.Repeat("D", 43)
+	validIdentity     = "E" + strings.Repeat("I", 43)
+	validPublicKey    = "1AAI" + strings.Repeat("K", 44)
+	validRecoveryHash = "E" + strings.Repeat("R", 43)
+	validRotationHash = "E" + strings.Repeat("N", 43)
+	validNonce        = "0A" + strings.Repeat("n", 22)
+	validSignature    = "0I" + strings.Repeat("S", 86)
+)
+
+func validCreateAccountRequest() string {
+	return `{"payload":{"access":{"nonce":"` + validNonce + `"},"request":{"authentication":{` +
+		`"device":"` + validDevice + `",` +
+		`"identity":"` + validIdentity + `",` +
+		`"publicKey":"` + validPublicKey + `",` +
+		`"recoveryHash":"` + validRecoveryHash + `",` +
+		`"rotationHash":"` + validRotationHash + `"}}},` +
+		`"signature":"` + validSignature + `"}`
+}
+
+func expectInvalidField(t *testing.T, err error, field string) {
+	t.Helper()
+
+	var betterAuthError *baerrors.BetterAuthError
+	if !errors.As(err, &betterAuthError) {
+		t.Fatalf("expected invalid message error for %s, got %v", field, err)
+	}
+
+	if betterAuthError.Code != "BA101" {
+		t.Fatalf("expected BA101 for %s, got %s", field, betterAuthError.Code)
+	}
+
+	if betterAuthError.Context["field"] != field {
+		t.Fatalf("expected field %s, got %v (%s)", field, betterAuthError.Context["field"], betterAuthError.Message)
+	}
+}
+
+func TestParseAcceptsValidMessage(t *testing.T) {
+	if _, err := messages.ParseCreateAccountRequest(validCreateAccountRequest()); err != nil {
+		t.Fatalf("failed to parse valid request: %v", err)
+	}
+}
+
+func TestParseRejectsInvalidFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		old   string
+		new   string
+		field string
+	}{
+		{"missing nonce", `"nonce":"` + validNonce + `"`, `"other":"x"`, "payload.access.nonce"},
+		{"empty device", validDevice, "", "payload.request.authentication.device"},
+		{"digest as key", validPublicKey, validRotationHash, "payload.request.authentication.publicKey"},
+		{"unknown code", validIdentity, "X" + validIdentity[1:], "payload.request.authentication.identity"},
+		{"short digest", validRecoveryHash, validRecoveryHash[:43], "payload.request.authentication.recoveryHash"},
+		{"bad character", validRotationHash, validRotationHash[:43] + "+", "payload.request.authentication.rotationHash"},
+		{"wrong type", `"device":"` + validDevice + `"`, `"device":42`, "payload.request.authentication.device"},
+		{"bad signature", validSignature, validSignature[:87], "signature"},
+		{"unknown envelope field", `"signature":`, `"extra":true,"signature":`, "extra"},
+		{"missing payload", `{"payload":{"access"`, `{"other":{"access"`, "other"},
+		{"malformed", `}}}`, `}}`, "message"},
+		{"trailing data", `}}}`, `}}} {}`, "message"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := strings.Replace(validCreateAccountRequest(), test.old, test.new, 1)
+			_, err := messages.ParseCreateAccountRequest(message)
+			expectInvalidField(t, err, test.field)
+		})
+	}
+}
+
+// payloads tolerate unknown fields so that newer clients can add them, unlike envelopes
+func TestParseAcceptsUnknownPayloadFieldsForForwardCompatibility(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{"access", `"nonce":`, `"hint":"x","nonce":`},
+		{"request", `"request":{`, `"request":{"extra":[1],`},
+		{"authentication", `"device":`, `"futureField":{"a":true},"device":`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := strings.Replace(validCreateAccountRequest(), test.old, test.new, 1)
+			if _, err := messages.ParseCreateAccountRequest(message); err != nil {
+				t.Fatalf("expected unknown payload field to be accepted: %v", err)
+			}
+
+			// known fields beside it are still validated
+			message = strings.Replace(message, validDevice, "", 1)
+			_, err := messages.ParseCreateAccountRequest(message)
+			expectInvalidField(t, err, "payload.request.authentication.device")
+		})
+	}
+}
+
+func TestParseRejectsMissingPayload(t *testing.T) {
+	_, err := messages.ParseCreateAccountResponse(`{"signature":"` + validSignature + `"}`)
+	expectInvalidField(t, err, "payload")
+}
+
+func TestParseValidatesNestedLinkContainer(t *testing.T) {
+	authentication := `{"device":"` + validDevice + `","identity":"` + validIdentity + `","publicKey":"` +
+		validPublicKey + `","rotationHash":"` + validRotationHash + `"}`
+
+	message := `{"payload":{"access":{"nonce":"` + validNonce + `"},"request":{"authentication":` + authentication +
+		`,"link":{"payload":{"authentication":` + authentication + `}}}},"signature":"` + validSignature + `"}`
+
+	_, err := messages.ParseLinkDeviceRequest(message)
+	expectInvalidField(t, err, "payload.request.link.signature")
+}
+
+func TestParseValidatesServerResponse(t *testing.T) {
+	message := `{"payload":{"access":{"nonce":"` + validNonce + `","serverIdentity":"` + validNonce +
+		`"},"response":{}},"signature":"` + validSignature + `"}`
+
+	_, err := messages.ParseCreateAccountResponse(message)
+	expectInvalidField(t, err, "payload.access.serverIdentity")
+}
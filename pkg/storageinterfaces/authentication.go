@@ -1,6 +1,14 @@
 package storageinterfaces
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrRotationConflict is returned (possibly wrapped) by Rotate when the device's rotation hash is
+// no longer the one the caller expected, because the key was already rotated or was never committed
+// to.
+var ErrRotationConflict = errors.New("rotation hash does not match")
 
 type AuthenticationNonceStore interface {
 	Generate(ctx context.Context, identity string) (string, error)
@@ -9,7 +17,13 @@ type AuthenticationNonceStore interface {
 
 type AuthenticationKeyStore interface {
 	Register(ctx context.Context, identity, device, publicKey, rotationHash string, existingIdentity bool) error
-	Rotate(ctx context.Context, identity, device, publicKey, rotationHash string) error
+	// Rotate replaces the device's key and rotation hash, but only if its current rotation hash is
+	// previousRotationHash. The comparison and the write must be atomic, so that of several concurrent
+	// rotations expecting the same hash exactly one succeeds and the rest get ErrRotationConflict.
+	Rotate(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash string) error
+	// Link rotates device as Rotate does and registers linkedDevice for the same identity. Either both
+	// happen or neither does.
+	Link(ctx context.Context, identity, device, previousRotationHash, publicKey, rotationHash, linkedDevice, linkedPublicKey, linkedRotationHash string) error
 	Public(ctx context.Context, identity, device string) (string, error)
 	RevokeDevice(ctx context.Context, identity, device string) error
 	RevokeDevices(ctx context.Context, identity string) error
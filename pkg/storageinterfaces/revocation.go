@@ -0,0 +1,8 @@
+package storageinterfaces
+
+import "context"
+
+type RevocationStore interface {
+	Revoke(ctx context.Context, value string) error
+	IsRevoked(ctx context.Context, value string) (bool, error)
+}
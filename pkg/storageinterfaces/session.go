@@ -0,0 +1,27 @@
+package storageinterfaces
+
+import (
+	"context"
+	"time"
+)
+
+type Session struct {
+	Id          string
+	Identity    string
+	Device      string
+	CreatedAt   time.Time
+	RefreshedAt time.Time
+	// ExpiresAt is when the session can no longer be refreshed
+	ExpiresAt time.Time
+}
+
+type SessionStore interface {
+	// Create registers a session that is active until expiresAt
+	Create(ctx context.Context, session, identity, device string, expiresAt time.Time) error
+	// Refresh records a refresh, extending the session to expiresAt, and must fail if the session is
+	// unknown, expired or has been terminated
+	Refresh(ctx context.Context, session string, expiresAt time.Time) error
+	// List returns the identity's unexpired sessions, oldest first
+	List(ctx context.Context, identity string) ([]Session, error)
+	Terminate(ctx context.Context, identity, session string) error
+}
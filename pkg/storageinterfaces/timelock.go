@@ -2,10 +2,17 @@ package storageinterfaces
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrReserved is returned (possibly wrapped) by Reserve when the value is already reserved.
+var ErrReserved = errors.New("value reserved too recently")
+
 type TimeLockStore interface {
 	Lifetime() time.Duration
+	// Reserve reserves value for Lifetime
 	Reserve(ctx context.Context, value string) error
+	// ReserveFor reserves value for lifetime, which may exceed Lifetime
+	ReserveFor(ctx context.Context, value string, lifetime time.Duration) error
 }
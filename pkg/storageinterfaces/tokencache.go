@@ -0,0 +1,25 @@
+package storageinterfaces
+
+import "time"
+
+// CachedToken is an access token whose encoding and signature have been verified. Token holds the
+// parsed token, so caches live in the verifier's process. Verifiers hand out copies of it, and
+// nothing may modify it once cached.
+type CachedToken struct {
+	Token          any
+	ServerIdentity string
+	Session        string
+	Expiry         time.Time
+}
+
+// TokenCache holds verified access tokens keyed by the token as presented, so that verifiers can
+// skip decoding and token signature verification on repeat use. Entries should be dropped once
+// they expire.
+type TokenCache interface {
+	Get(token string) (*CachedToken, bool)
+	Put(token string, cached *CachedToken)
+	// InvalidateSession drops the tokens of a revoked session.
+	InvalidateSession(session string)
+	// InvalidateServerIdentity drops the tokens signed by a retired server access key.
+	InvalidateServerIdentity(serverIdentity string)
+}
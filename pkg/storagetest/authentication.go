@@ -0,0 +1,434 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// TestAuthenticationKeyStore checks device registration and revocation, and that Rotate is a
+// compare-and-swap on the rotation hash.
+func TestAuthenticationKeyStore(t *testing.T, newStore func(t *testing.T) storageinterfaces.AuthenticationKeyStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store storageinterfaces.AuthenticationKeyStore)
+	}{
+		{"Register", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device-1", "key-1", "hash-1")
+
+			if err := store.Register(ctx, "identity", "device-2", "key-2", "hash-2", true); err != nil {
+				t.Fatalf("failed to register a second device: %v", err)
+			}
+
+			expectPublic(t, store, "identity", "device-1", "key-1")
+			expectPublic(t, store, "identity", "device-2", "key-2")
+			expectActive(t, store, "identity", "device-1", true)
+			expectActive(t, store, "identity", "device-2", true)
+		}},
+		{"RegisterDuplicate", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			register(t, store, "identity", "device", "key-1", "hash-1")
+
+			if err := store.Register(context.Background(), "identity", "device", "key-2", "hash-2", true); err == nil {
+				t.Fatalf("expected duplicate registration to fail")
+			}
+
+			expectPublic(t, store, "identity", "device", "key-1")
+		}},
+		{"ConcurrentRegisterDuplicate", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			var registered atomic.Int32
+
+			concurrently(16, func(i int) {
+				key := fmt.Sprintf("key-%d", i)
+				if err := store.Register(context.Background(), "identity", "device", key, "hash", false); err == nil {
+					registered.Add(1)
+				}
+			})
+
+			if registered.Load() != 1 {
+				t.Fatalf("expected exactly one registration, got %d", registered.Load())
+			}
+		}},
+		{"ConcurrentRegisterDevices", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			register(t, store, "identity", "device", "key", "hash")
+
+			concurrently(16, func(i int) {
+				device := fmt.Sprintf("device-%d", i)
+				if err := store.Register(context.Background(), "identity", device, "key", "hash", true); err != nil {
+					t.Errorf("failed to register %s: %v", device, err)
+				}
+			})
+
+			for i := range 16 {
+				expectActive(t, store, "identity", fmt.Sprintf("device-%d", i), true)
+			}
+		}},
+		{"PublicUnknown", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			if _, err := store.Public(ctx, "identity", "device"); err == nil {
+				t.Fatalf("expected an unknown account to fail")
+			}
+
+			register(t, store, "identity", "device", "key", "hash")
+
+			if _, err := store.Public(ctx, "identity", "other"); err == nil {
+				t.Fatalf("expected an unknown device to fail")
+			}
+
+			expectActive(t, store, "identity", "other", false)
+			expectActive(t, store, "other", "device", false)
+		}},
+		{"Rotate", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device", "key-0", "hash-1")
+
+			if err := store.Rotate(ctx, "identity", "device", "hash-1", "key-1", "hash-2"); err != nil {
+				t.Fatalf("failed to rotate: %v", err)
+			}
+
+			expectPublic(t, store, "identity", "device", "key-1")
+
+			if err := store.Rotate(ctx, "identity", "device", "hash-2", "key-2", "hash-3"); err != nil {
+				t.Fatalf("failed to rotate again: %v", err)
+			}
+
+			expectPublic(t, store, "identity", "device", "key-2")
+		}},
+		{"RotateWrongHash", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device", "key-0", "hash-1")
+
+			if err := store.Rotate(ctx, "identity", "device", "hash-1", "key-1", "hash-2"); err != nil {
+				t.Fatalf("failed to rotate: %v", err)
+			}
+
+			// a replayed rotation expects the hash that was just consumed
+			err := store.Rotate(ctx, "identity", "device", "hash-1", "key-1", "hash-attacker")
+			if !errors.Is(err, storageinterfaces.ErrRotationConflict) {
+				t.Fatalf("expected ErrRotationConflict, got %v", err)
+			}
+
+			err = store.Rotate(ctx, "identity", "device", "hash-unknown", "key-x", "hash-x")
+			if !errors.Is(err, storageinterfaces.ErrRotationConflict) {
+				t.Fatalf("expected ErrRotationConflict, got %v", err)
+			}
+
+			expectPublic(t, store, "identity", "device", "key-1")
+
+			if err := store.Rotate(ctx, "identity", "device", "hash-2", "key-2", "hash-3"); err != nil {
+				t.Fatalf("expected a conflict to leave the device rotatable: %v", err)
+			}
+		}},
+		{"RotateUnknown", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			err := store.Rotate(ctx, "identity", "device", "hash-1", "key-1", "hash-2")
+			if err == nil || errors.Is(err, storageinterfaces.ErrRotationConflict) {
+				t.Fatalf("expected an unknown account to fail without a conflict, got %v", err)
+			}
+
+			register(t, store, "identity", "device", "key-0", "hash-1")
+
+			err = store.Rotate(ctx, "identity", "other", "hash-1", "key-1", "hash-2")
+			if err == nil || errors.Is(err, storageinterfaces.ErrRotationConflict) {
+				t.Fatalf("expected an unknown device to fail without a conflict, got %v", err)
+			}
+		}},
+		{"ConcurrentRotate", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			register(t, store, "identity", "device", "key-0", "hash-1")
+
+			for round := 1; round <= 10; round++ {
+				expected := fmt.Sprintf("hash-%d", round)
+				next := fmt.Sprintf("hash-%d", round+1)
+
+				// every caller holds the correct next key, as a stolen copy of the device's keys would
+				var rotated, conflicted atomic.Int32
+
+				concurrently(16, func(i int) {
+					key := fmt.Sprintf("key-%d-%d", round, i)
+
+					err := store.Rotate(context.Background(), "identity", "device", expected, key, next)
+					switch {
+					case err == nil:
+						rotated.Add(1)
+					case errors.Is(err, storageinterfaces.ErrRotationConflict):
+						conflicted.Add(1)
+					default:
+						t.Errorf("round %d: unexpected error %v", round, err)
+					}
+				})
+
+				if rotated.Load() != 1 || conflicted.Load() != 15 {
+					t.Fatalf("round %d: expected one rotation and 15 conflicts, got %d and %d", round, rotated.Load(), conflicted.Load())
+				}
+			}
+		}},
+		{"Link", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device", "key-0", "hash-1")
+
+			if err := store.Link(ctx, "identity", "device", "hash-1", "key-1", "hash-2", "linked", "linked-key-0", "linked-hash-1"); err != nil {
+				t.Fatalf("failed to link: %v", err)
+			}
+
+			expectPublic(t, store, "identity", "device", "key-1")
+			expectPublic(t, store, "identity", "linked", "linked-key-0")
+
+			if err := store.Rotate(ctx, "identity", "linked", "linked-hash-1", "linked-key-1", "linked-hash-2"); err != nil {
+				t.Fatalf("failed to rotate the linked device: %v", err)
+			}
+		}},
+		{"LinkIsAtomic", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device", "key-0", "hash-1")
+
+			// a conflicting rotation registers nothing
+			err := store.Link(ctx, "identity", "device", "hash-unknown", "key-1", "hash-2", "linked", "linked-key-0", "linked-hash-1")
+			if !errors.Is(err, storageinterfaces.ErrRotationConflict) {
+				t.Fatalf("expected ErrRotationConflict, got %v", err)
+			}
+
+			expectActive(t, store, "identity", "linked", false)
+
+			// and a refused registration rotates nothing
+			register(t, store, "identity", "linked", "linked-key-0", "linked-hash-1")
+
+			if err := store.Link(ctx, "identity", "device", "hash-1", "key-1", "hash-2", "linked", "linked-key-x", "linked-hash-x"); err == nil {
+				t.Fatalf("expected linking a registered device to fail")
+			}
+
+			expectPublic(t, store, "identity", "device", "key-0")
+			expectPublic(t, store, "identity", "linked", "linked-key-0")
+
+			if err := store.Rotate(ctx, "identity", "device", "hash-1", "key-1", "hash-2"); err != nil {
+				t.Fatalf("expected a refused link to leave the device rotatable: %v", err)
+			}
+		}},
+		{"RevokeDevice", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device-1", "key-1", "hash-1")
+			register(t, store, "identity", "device-2", "key-2", "hash-2")
+
+			if err := store.RevokeDevice(ctx, "identity", "device-1"); err != nil {
+				t.Fatalf("failed to revoke device: %v", err)
+			}
+
+			expectActive(t, store, "identity", "device-1", false)
+			expectActive(t, store, "identity", "device-2", true)
+
+			if err := store.Rotate(ctx, "identity", "device-1", "hash-1", "key-3", "hash-3"); err == nil {
+				t.Fatalf("expected a revoked device to be unrotatable")
+			}
+		}},
+		{"RevokeUnknownDevice", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			if err := store.RevokeDevice(ctx, "identity", "device"); err == nil {
+				t.Fatalf("expected revoking a device of an unknown account to fail")
+			}
+
+			register(t, store, "identity", "device", "key", "hash")
+
+			if err := store.RevokeDevice(ctx, "identity", "other"); err != nil {
+				t.Fatalf("expected revoking an unknown device of a known account to succeed: %v", err)
+			}
+
+			expectActive(t, store, "identity", "device", true)
+		}},
+		{"RevokeDevices", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device-1", "key-1", "hash-1")
+			register(t, store, "identity", "device-2", "key-2", "hash-2")
+			register(t, store, "other", "device-1", "key-1", "hash-1")
+
+			if err := store.RevokeDevices(ctx, "identity"); err != nil {
+				t.Fatalf("failed to revoke devices: %v", err)
+			}
+
+			expectActive(t, store, "identity", "device-1", false)
+			expectActive(t, store, "identity", "device-2", false)
+			expectActive(t, store, "other", "device-1", true)
+
+			// recovery registers a fresh device against the surviving identity
+			if err := store.Register(ctx, "identity", "device-3", "key-3", "hash-3", true); err != nil {
+				t.Fatalf("failed to register after revoking devices: %v", err)
+			}
+
+			expectActive(t, store, "identity", "device-3", true)
+		}},
+		{"DeleteIdentity", func(t *testing.T, store storageinterfaces.AuthenticationKeyStore) {
+			ctx := context.Background()
+
+			register(t, store, "identity", "device-1", "key-1", "hash-1")
+			register(t, store, "identity", "device-2", "key-2", "hash-2")
+			register(t, store, "other", "device-1", "key-1", "hash-1")
+
+			if err := store.DeleteIdentity(ctx, "identity"); err != nil {
+				t.Fatalf("failed to delete identity: %v", err)
+			}
+
+			expectActive(t, store, "identity", "device-1", false)
+			expectActive(t, store, "identity", "device-2", false)
+			expectActive(t, store, "other", "device-1", true)
+
+			if _, err := store.Public(ctx, "identity", "device-1"); err == nil {
+				t.Fatalf("expected a deleted identity's keys to be gone")
+			}
+
+			if err := store.DeleteIdentity(ctx, "identity"); err == nil {
+				t.Fatalf("expected deleting a deleted identity to fail")
+			}
+
+			if err := store.DeleteIdentity(ctx, "unknown"); err == nil {
+				t.Fatalf("expected deleting an unknown identity to fail")
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newStore(t))
+		})
+	}
+}
+
+// TestAuthenticationNonceStore checks that nonces are unique, resolve to the identity they were
+// generated for, and expire after lifetime.
+func TestAuthenticationNonceStore(
+	t *testing.T,
+	newStore func(t *testing.T, lifetime time.Duration) (storageinterfaces.AuthenticationNonceStore, Advance),
+) {
+	const lifetime = time.Minute
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store storageinterfaces.AuthenticationNonceStore, advance Advance)
+	}{
+		{"Verify", func(t *testing.T, store storageinterfaces.AuthenticationNonceStore, advance Advance) {
+			nonce := generate(t, store, "identity")
+			other := generate(t, store, "other")
+
+			expectNonce(t, store, nonce, "identity")
+			expectNonce(t, store, other, "other")
+		}},
+		{"VerifyUnknown", func(t *testing.T, store storageinterfaces.AuthenticationNonceStore, advance Advance) {
+			generate(t, store, "identity")
+
+			if _, err := store.Verify(context.Background(), "unknown"); err == nil {
+				t.Fatalf("expected an unknown nonce to fail")
+			}
+		}},
+		{"Expiry", func(t *testing.T, store storageinterfaces.AuthenticationNonceStore, advance Advance) {
+			nonce := generate(t, store, "identity")
+
+			advance(lifetime - time.Second)
+			expectNonce(t, store, nonce, "identity")
+
+			advance(time.Second)
+
+			if _, err := store.Verify(context.Background(), nonce); err == nil {
+				t.Fatalf("expected the nonce to expire after its lifetime")
+			}
+		}},
+		{"ConcurrentGenerate", func(t *testing.T, store storageinterfaces.AuthenticationNonceStore, advance Advance) {
+			nonces := make([]string, 32)
+
+			concurrently(len(nonces), func(i int) {
+				nonce, err := store.Generate(context.Background(), fmt.Sprintf("identity-%d", i))
+				if err != nil {
+					t.Errorf("failed to generate: %v", err)
+				}
+
+				nonces[i] = nonce
+			})
+
+			seen := map[string]bool{}
+			for i, nonce := range nonces {
+				if seen[nonce] {
+					t.Fatalf("nonce %s was generated twice", nonce)
+				}
+				seen[nonce] = true
+
+				expectNonce(t, store, nonce, fmt.Sprintf("identity-%d", i))
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, advance := newStore(t, lifetime)
+			test.run(t, store, advance)
+		})
+	}
+}
+
+func register(t *testing.T, store storageinterfaces.AuthenticationKeyStore, identity, device, publicKey, rotationHash string) {
+	t.Helper()
+
+	if err := store.Register(context.Background(), identity, device, publicKey, rotationHash, false); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+}
+
+func expectPublic(t *testing.T, store storageinterfaces.AuthenticationKeyStore, identity, device, expected string) {
+	t.Helper()
+
+	publicKey, err := store.Public(context.Background(), identity, device)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+
+	if publicKey != expected {
+		t.Fatalf("expected public key %s, got %s", expected, publicKey)
+	}
+}
+
+func expectActive(t *testing.T, store storageinterfaces.AuthenticationKeyStore, identity, device string, active bool) {
+	t.Helper()
+
+	err := store.EnsureActive(context.Background(), identity, device)
+	if active && err != nil {
+		t.Fatalf("expected %s/%s to be active: %v", identity, device, err)
+	}
+
+	if !active && err == nil {
+		t.Fatalf("expected %s/%s to be inactive", identity, device)
+	}
+}
+
+func generate(t *testing.T, store storageinterfaces.AuthenticationNonceStore, identity string) string {
+	t.Helper()
+
+	nonce, err := store.Generate(context.Background(), identity)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	return nonce
+}
+
+func expectNonce(t *testing.T, store storageinterfaces.AuthenticationNonceStore, nonce, expected string) {
+	t.Helper()
+
+	identity, err := store.Verify(context.Background(), nonce)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+
+	if identity != expected {
+		t.Fatalf("expected nonce to belong to %s, got %s", expected, identity)
+	}
+}
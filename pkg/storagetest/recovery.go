@@ -0,0 +1,109 @@
+package storagetest
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// TestRecoveryHashStore checks registration, and that Rotate only succeeds against the current hash.
+func TestRecoveryHashStore(t *testing.T, newStore func(t *testing.T) storageinterfaces.RecoveryHashStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store storageinterfaces.RecoveryHashStore)
+	}{
+		{"RegisterDuplicate", func(t *testing.T, store storageinterfaces.RecoveryHashStore) {
+			registerRecovery(t, store, "identity", "first")
+
+			if err := store.Register(context.Background(), "identity", "second"); err == nil {
+				t.Fatalf("expected duplicate registration to fail")
+			}
+
+			rotateRecovery(t, store, "identity", "first", "third")
+		}},
+		{"Rotate", func(t *testing.T, store storageinterfaces.RecoveryHashStore) {
+			registerRecovery(t, store, "identity", "first")
+			rotateRecovery(t, store, "identity", "first", "second")
+			rotateRecovery(t, store, "identity", "second", "third")
+		}},
+		{"RotateWrongHash", func(t *testing.T, store storageinterfaces.RecoveryHashStore) {
+			ctx := context.Background()
+
+			registerRecovery(t, store, "identity", "first")
+			rotateRecovery(t, store, "identity", "first", "second")
+
+			if err := store.Rotate(ctx, "identity", "first", "attacker"); err == nil {
+				t.Fatalf("expected rotation with a consumed hash to fail")
+			}
+
+			if err := store.Rotate(ctx, "identity", "wrong", "attacker"); err == nil {
+				t.Fatalf("expected rotation with the wrong hash to fail")
+			}
+
+			rotateRecovery(t, store, "identity", "second", "third")
+		}},
+		{"RotateUnknown", func(t *testing.T, store storageinterfaces.RecoveryHashStore) {
+			if err := store.Rotate(context.Background(), "identity", "first", "second"); err == nil {
+				t.Fatalf("expected rotating an unknown identity to fail")
+			}
+		}},
+		{"Change", func(t *testing.T, store storageinterfaces.RecoveryHashStore) {
+			ctx := context.Background()
+
+			if err := store.Change(ctx, "identity", "first"); err == nil {
+				t.Fatalf("expected changing an unknown identity to fail")
+			}
+
+			registerRecovery(t, store, "identity", "first")
+
+			if err := store.Change(ctx, "identity", "second"); err != nil {
+				t.Fatalf("failed to change: %v", err)
+			}
+
+			if err := store.Rotate(ctx, "identity", "first", "attacker"); err == nil {
+				t.Fatalf("expected the replaced hash to be unusable")
+			}
+
+			rotateRecovery(t, store, "identity", "second", "third")
+		}},
+		{"ConcurrentRotate", func(t *testing.T, store storageinterfaces.RecoveryHashStore) {
+			registerRecovery(t, store, "identity", "first")
+
+			var rotated atomic.Int32
+
+			concurrently(16, func(i int) {
+				if err := store.Rotate(context.Background(), "identity", "first", "second"); err == nil {
+					rotated.Add(1)
+				}
+			})
+
+			if rotated.Load() != 1 {
+				t.Fatalf("expected exactly one rotation, got %d", rotated.Load())
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newStore(t))
+		})
+	}
+}
+
+func registerRecovery(t *testing.T, store storageinterfaces.RecoveryHashStore, identity, hash string) {
+	t.Helper()
+
+	if err := store.Register(context.Background(), identity, hash); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+}
+
+func rotateRecovery(t *testing.T, store storageinterfaces.RecoveryHashStore, identity, oldHash, newHash string) {
+	t.Helper()
+
+	if err := store.Rotate(context.Background(), identity, oldHash, newHash); err != nil {
+		t.Fatalf("failed to rotate from %s to %s: %v", oldHash, newHash, err)
+	}
+}
@@ -0,0 +1,89 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// TestRevocationStore checks that revocations hold for lifetime from the latest Revoke.
+func TestRevocationStore(
+	t *testing.T,
+	newStore func(t *testing.T, lifetime time.Duration) (storageinterfaces.RevocationStore, Advance),
+) {
+	const lifetime = time.Hour
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store storageinterfaces.RevocationStore, advance Advance)
+	}{
+		{"Revoke", func(t *testing.T, store storageinterfaces.RevocationStore, advance Advance) {
+			expectRevoked(t, store, "value", false)
+
+			revoke(t, store, "value")
+
+			expectRevoked(t, store, "value", true)
+			expectRevoked(t, store, "other", false)
+		}},
+		{"Expiry", func(t *testing.T, store storageinterfaces.RevocationStore, advance Advance) {
+			revoke(t, store, "value")
+
+			advance(lifetime - time.Second)
+			expectRevoked(t, store, "value", true)
+
+			advance(time.Second)
+			expectRevoked(t, store, "value", false)
+		}},
+		{"RevokeExtends", func(t *testing.T, store storageinterfaces.RevocationStore, advance Advance) {
+			revoke(t, store, "value")
+
+			advance(lifetime / 2)
+			revoke(t, store, "value")
+
+			advance(lifetime - time.Second)
+			expectRevoked(t, store, "value", true)
+
+			advance(time.Second)
+			expectRevoked(t, store, "value", false)
+		}},
+		{"ConcurrentRevoke", func(t *testing.T, store storageinterfaces.RevocationStore, advance Advance) {
+			concurrently(16, func(int) {
+				if err := store.Revoke(context.Background(), "value"); err != nil {
+					t.Errorf("failed to revoke: %v", err)
+				}
+			})
+
+			expectRevoked(t, store, "value", true)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, advance := newStore(t, lifetime)
+			test.run(t, store, advance)
+		})
+	}
+}
+
+func revoke(t *testing.T, store storageinterfaces.RevocationStore, value string) {
+	t.Helper()
+
+	if err := store.Revoke(context.Background(), value); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+}
+
+func expectRevoked(t *testing.T, store storageinterfaces.RevocationStore, value string, expected bool) {
+	t.Helper()
+
+	revoked, err := store.IsRevoked(context.Background(), value)
+	if err != nil {
+		t.Fatalf("failed to check revocation: %v", err)
+	}
+
+	if revoked != expected {
+		t.Fatalf("expected %s revoked to be %v", value, expected)
+	}
+}
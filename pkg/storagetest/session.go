@@ -0,0 +1,180 @@
+package storagetest
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// sessionLifetime is how long the suite's sessions last. Stores under test must start their clocks at
+// the current time, since the suite computes expiries from it.
+const sessionLifetime = time.Hour
+
+// TestSessionStore checks the session registry, including that List orders sessions by creation and
+// omits expired sessions.
+func TestSessionStore(t *testing.T, newStore func(t *testing.T) (storageinterfaces.SessionStore, Advance)) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store storageinterfaces.SessionStore, advance Advance)
+	}{
+		{"Create", func(t *testing.T, store storageinterfaces.SessionStore, advance Advance) {
+			create(t, store, "session", "identity", "device")
+
+			sessions := list(t, store, "identity", 1)
+			session := sessions[0]
+
+			if session.Id != "session" || session.Identity != "identity" || session.Device != "device" {
+				t.Fatalf("unexpected session %+v", session)
+			}
+
+			if !session.RefreshedAt.Equal(session.CreatedAt) {
+				t.Fatalf("expected a new session to be refreshed at creation")
+			}
+		}},
+		{"CreateDuplicate", func(t *testing.T, store storageinterfaces.SessionStore, advance Advance) {
+			create(t, store, "session", "identity", "device")
+
+			if err := store.Create(context.Background(), "session", "other", "device", expiry()); err == nil {
+				t.Fatalf("expected duplicate creation to fail")
+			}
+
+			list(t, store, "other", 0)
+		}},
+		{"List", func(t *testing.T, store storageinterfaces.SessionStore, advance Advance) {
+			create(t, store, "session-b", "identity", "device")
+			advance(time.Second)
+			create(t, store, "session-a", "identity", "device")
+			create(t, store, "session-c", "other", "device")
+
+			sessions := list(t, store, "identity", 2)
+			if sessions[0].Id != "session-b" || sessions[1].Id != "session-a" {
+				t.Fatalf("expected sessions in creation order, got %+v", sessions)
+			}
+
+			list(t, store, "unknown", 0)
+		}},
+		{"Refresh", func(t *testing.T, store storageinterfaces.SessionStore, advance Advance) {
+			create(t, store, "session", "identity", "device")
+			advance(time.Minute)
+
+			extended := expiry().Add(time.Hour)
+			if err := store.Refresh(context.Background(), "session", extended); err != nil {
+				t.Fatalf("failed to refresh: %v", err)
+			}
+
+			session := list(t, store, "identity", 1)[0]
+			if session.RefreshedAt.Sub(session.CreatedAt) != time.Minute {
+				t.Fatalf("expected the refresh to be recorded a minute after creation, got %+v", session)
+			}
+
+			if session.ExpiresAt.Sub(extended).Abs() > time.Millisecond {
+				t.Fatalf("expected the refresh to extend the session to %v, got %+v", extended, session)
+			}
+
+			expectUnknownSession(t, store.Refresh(context.Background(), "unknown", expiry()))
+		}},
+		{"Expiry", func(t *testing.T, store storageinterfaces.SessionStore, advance Advance) {
+			create(t, store, "session-a", "identity", "device")
+
+			if err := store.Create(context.Background(), "session-b", "identity", "device", expiry().Add(sessionLifetime)); err != nil {
+				t.Fatalf("failed to create: %v", err)
+			}
+
+			advance(sessionLifetime + time.Second)
+
+			if sessions := list(t, store, "identity", 1); sessions[0].Id != "session-b" {
+				t.Fatalf("expected only session-b to remain, got %+v", sessions)
+			}
+
+			expectUnknownSession(t, store.Refresh(context.Background(), "session-a", expiry().Add(sessionLifetime)))
+
+			advance(sessionLifetime)
+			list(t, store, "identity", 0)
+		}},
+		{"Terminate", func(t *testing.T, store storageinterfaces.SessionStore, advance Advance) {
+			ctx := context.Background()
+
+			create(t, store, "session-a", "identity", "device")
+			create(t, store, "session-b", "identity", "device")
+
+			if err := store.Terminate(ctx, "other", "session-a"); err == nil {
+				t.Fatalf("expected terminating another identity's session to fail")
+			}
+
+			if err := store.Terminate(ctx, "identity", "session-a"); err != nil {
+				t.Fatalf("failed to terminate: %v", err)
+			}
+
+			expectUnknownSession(t, store.Terminate(ctx, "identity", "session-a"))
+
+			expectUnknownSession(t, store.Refresh(ctx, "session-a", expiry()))
+
+			if sessions := list(t, store, "identity", 1); sessions[0].Id != "session-b" {
+				t.Fatalf("expected session-b to remain, got %+v", sessions)
+			}
+		}},
+		{"ConcurrentCreate", func(t *testing.T, store storageinterfaces.SessionStore, advance Advance) {
+			var created atomic.Int32
+
+			concurrently(16, func(i int) {
+				if err := store.Create(context.Background(), "session", fmt.Sprintf("identity-%d", i), "device", expiry()); err == nil {
+					created.Add(1)
+				}
+			})
+
+			if created.Load() != 1 {
+				t.Fatalf("expected exactly one creation, got %d", created.Load())
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, advance := newStore(t)
+			test.run(t, store, advance)
+		})
+	}
+}
+
+func create(t *testing.T, store storageinterfaces.SessionStore, session, identity, device string) {
+	t.Helper()
+
+	if err := store.Create(context.Background(), session, identity, device, expiry()); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+}
+
+// expiry is sessionLifetime from the wall clock time, which stores' clocks start at.
+func expiry() time.Time {
+	return time.Now().Add(sessionLifetime)
+}
+
+func expectUnknownSession(t *testing.T, err error) {
+	t.Helper()
+
+	var betterAuthError *errors.BetterAuthError
+	if !stderrors.As(err, &betterAuthError) || betterAuthError.Code != "BA401" || betterAuthError.Context["reason"] != "unknown" {
+		t.Fatalf("expected an unknown session error, got %v", err)
+	}
+}
+
+func list(t *testing.T, store storageinterfaces.SessionStore, identity string, count int) []storageinterfaces.Session {
+	t.Helper()
+
+	sessions, err := store.List(context.Background(), identity)
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+
+	if len(sessions) != count {
+		t.Fatalf("expected %d sessions for %s, got %+v", count, identity, sessions)
+	}
+
+	return sessions
+}
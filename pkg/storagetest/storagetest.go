@@ -0,0 +1,27 @@
+// Package storagetest holds conformance suites for storageinterfaces implementations. A backend
+// runs them from its own tests, passing a constructor for an empty store. Constructors of stores
+// that expire values also return an Advance hook, so the suites can move time without sleeping.
+package storagetest
+
+import (
+	"sync"
+	"time"
+)
+
+// Advance moves the clock the store under test reads forward by d.
+type Advance func(d time.Duration)
+
+// concurrently runs fn on count goroutines and waits for them to finish.
+func concurrently(count int, fn func(i int)) {
+	var wg sync.WaitGroup
+
+	for i := range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i)
+		}()
+	}
+
+	wg.Wait()
+}
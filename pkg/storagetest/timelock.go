@@ -0,0 +1,116 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/pkg/storageinterfaces"
+)
+
+// TestTimeLockStore checks that a value can be reserved once per lifetime.
+func TestTimeLockStore(
+	t *testing.T,
+	newStore func(t *testing.T, lifetime time.Duration) (storageinterfaces.TimeLockStore, Advance),
+) {
+	const lifetime = 30 * time.Second
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store storageinterfaces.TimeLockStore, advance Advance)
+	}{
+		{"Lifetime", func(t *testing.T, store storageinterfaces.TimeLockStore, advance Advance) {
+			if store.Lifetime() != lifetime {
+				t.Fatalf("expected a lifetime of %s, got %s", lifetime, store.Lifetime())
+			}
+		}},
+		{"Reserve", func(t *testing.T, store storageinterfaces.TimeLockStore, advance Advance) {
+			reserve(t, store, "value")
+			expectReserved(t, store, "value")
+
+			reserve(t, store, "other")
+		}},
+		{"Expiry", func(t *testing.T, store storageinterfaces.TimeLockStore, advance Advance) {
+			reserve(t, store, "value")
+
+			advance(lifetime - time.Second)
+			expectReserved(t, store, "value")
+
+			advance(time.Second)
+			reserve(t, store, "value")
+
+			// the fresh reservation holds for a full lifetime again
+			advance(lifetime - time.Second)
+			expectReserved(t, store, "value")
+		}},
+		{"ReserveFor", func(t *testing.T, store storageinterfaces.TimeLockStore, advance Advance) {
+			if err := store.ReserveFor(context.Background(), "value", 2*lifetime); err != nil {
+				t.Fatalf("failed to reserve: %v", err)
+			}
+
+			advance(2*lifetime - time.Second)
+			expectReserved(t, store, "value")
+
+			advance(time.Second)
+			reserve(t, store, "value")
+		}},
+		{"RefusalDoesNotExtend", func(t *testing.T, store storageinterfaces.TimeLockStore, advance Advance) {
+			reserve(t, store, "value")
+
+			advance(lifetime / 2)
+			expectReserved(t, store, "value")
+
+			advance(lifetime / 2)
+			reserve(t, store, "value")
+		}},
+		{"ConcurrentReserve", func(t *testing.T, store storageinterfaces.TimeLockStore, advance Advance) {
+			for round := range 10 {
+				value := fmt.Sprintf("value-%d", round)
+
+				var reserved, refused atomic.Int32
+
+				concurrently(16, func(int) {
+					err := store.Reserve(context.Background(), value)
+					switch {
+					case err == nil:
+						reserved.Add(1)
+					case errors.Is(err, storageinterfaces.ErrReserved):
+						refused.Add(1)
+					default:
+						t.Errorf("round %d: unexpected error %v", round, err)
+					}
+				})
+
+				if reserved.Load() != 1 || refused.Load() != 15 {
+					t.Fatalf("round %d: expected one reservation and 15 refusals, got %d and %d", round, reserved.Load(), refused.Load())
+				}
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, advance := newStore(t, lifetime)
+			test.run(t, store, advance)
+		})
+	}
+}
+
+func reserve(t *testing.T, store storageinterfaces.TimeLockStore, value string) {
+	t.Helper()
+
+	if err := store.Reserve(context.Background(), value); err != nil {
+		t.Fatalf("failed to reserve %s: %v", value, err)
+	}
+}
+
+func expectReserved(t *testing.T, store storageinterfaces.TimeLockStore, value string) {
+	t.Helper()
+
+	if err := store.Reserve(context.Background(), value); !errors.Is(err, storageinterfaces.ErrReserved) {
+		t.Fatalf("expected %s to be reserved, got %v", value, err)
+	}
+}
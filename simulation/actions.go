@@ -0,0 +1,277 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+)
+
+// CreateAccount creates an account whose first device is named device.
+func CreateAccount(account, device string) Step {
+	return Step{
+		name: fmt.Sprintf("create account %s on %s", account, device),
+		action: func(s *Simulation) error {
+			if err := s.unused("account", account, s.accounts[account] != nil); err != nil {
+				return err
+			}
+
+			if err := s.unused("device", device, s.devices[device] != nil); err != nil {
+				return err
+			}
+
+			a, err := s.as(device).CreateAccount(s.ctx, account, device)
+			if err != nil {
+				return err
+			}
+
+			s.accounts[account] = a
+			s.devices[device] = &deviceState{Device: a.Device, account: a}
+
+			return nil
+		},
+	}
+}
+
+// RotateDevice rotates the device to its committed next key.
+func RotateDevice(device string) Step {
+	return Step{
+		name: "rotate " + device,
+		action: func(s *Simulation) error {
+			d, err := s.device(device)
+			if err != nil {
+				return err
+			}
+
+			return s.as(device).RotateDevice(s.ctx, d.Device, d.Next)
+		},
+	}
+}
+
+// ChangeRecoveryKey replaces the account's recovery key from device.
+func ChangeRecoveryKey(device string) Step {
+	return Step{
+		name: "change recovery key from " + device,
+		action: func(s *Simulation) error {
+			d, err := s.device(device)
+			if err != nil {
+				return err
+			}
+
+			return s.as(device).ChangeRecoveryKey(s.ctx, d.account, d.Device)
+		},
+	}
+}
+
+// DeleteAccount deletes the device's account.
+func DeleteAccount(device string) Step {
+	return Step{
+		name: "delete account from " + device,
+		action: func(s *Simulation) error {
+			d, err := s.device(device)
+			if err != nil {
+				return err
+			}
+
+			return s.as(device).DeleteAccount(s.ctx, d.Device)
+		},
+	}
+}
+
+// LinkDevice links a new device, named device, to the account of the existing device by.
+func LinkDevice(by, device string) Step {
+	return Step{
+		name: fmt.Sprintf("link %s from %s", device, by),
+		action: func(s *Simulation) error {
+			d, err := s.device(by)
+			if err != nil {
+				return err
+			}
+
+			if err := s.unused("device", device, s.devices[device] != nil); err != nil {
+				return err
+			}
+
+			linked, err := s.as(by).LinkDevice(s.ctx, d.Device, device, d.Identity)
+			if err != nil {
+				return err
+			}
+
+			s.devices[device] = &deviceState{Device: linked, account: d.account}
+
+			return nil
+		},
+	}
+}
+
+// UnlinkDevice unlinks target from its account, acting from the device by. The target keeps its
+// keys, so later steps can show that the server refuses it.
+func UnlinkDevice(by, target string) Step {
+	return Step{
+		name: fmt.Sprintf("unlink %s from %s", target, by),
+		action: func(s *Simulation) error {
+			d, err := s.device(by)
+			if err != nil {
+				return err
+			}
+
+			unlinked, ok := s.devices[target]
+			if !ok {
+				return misuse("unknown device %s", target)
+			}
+
+			return s.as(by).UnlinkDevice(s.ctx, d.Device, unlinked.Device)
+		},
+	}
+}
+
+// LoseDevice discards the device's keys on the client. The server is not told, and scripting any
+// further action on the device is an error.
+func LoseDevice(device string) Step {
+	return Step{
+		name: "lose " + device,
+		action: func(s *Simulation) error {
+			d, err := s.device(device)
+			if err != nil {
+				return err
+			}
+
+			d.lost = true
+
+			return nil
+		},
+	}
+}
+
+// RecoverAccount uses the account's recovery key to replace its devices with a new one, named
+// device.
+func RecoverAccount(account, device string) Step {
+	return Step{
+		name: fmt.Sprintf("recover %s on %s", account, device),
+		action: func(s *Simulation) error {
+			a, ok := s.accounts[account]
+			if !ok {
+				return misuse("unknown account %s", account)
+			}
+
+			if err := s.unused("device", device, s.devices[device] != nil); err != nil {
+				return err
+			}
+
+			d, err := s.as(device).RecoverAccount(s.ctx, a, device)
+			if err != nil {
+				return err
+			}
+
+			s.devices[device] = &deviceState{Device: d, account: a}
+
+			return nil
+		},
+	}
+}
+
+// CreateSession authenticates the device and opens a session named session.
+func CreateSession(device, session string) Step {
+	return Step{
+		name: fmt.Sprintf("create session %s on %s", session, device),
+		action: func(s *Simulation) error {
+			d, err := s.device(device)
+			if err != nil {
+				return err
+			}
+
+			if err := s.unused("session", session, s.sessions[session] != nil); err != nil {
+				return err
+			}
+
+			state, err := s.as(device).CreateSession(s.ctx, d.Device, session)
+			if err != nil {
+				return err
+			}
+
+			s.sessions[session] = state
+
+			return nil
+		},
+	}
+}
+
+// RefreshSession exchanges the session's token for a new one, rotating its access key.
+func RefreshSession(session string) Step {
+	return Step{
+		name: "refresh " + session,
+		action: func(s *Simulation) error {
+			state, err := s.session(session)
+			if err != nil {
+				return err
+			}
+
+			return s.as(session).RefreshSession(s.ctx, state)
+		},
+	}
+}
+
+// Access presents the session's token to the access verifier.
+func Access(session string) Step {
+	return Step{
+		name: "access with " + session,
+		action: func(s *Simulation) error {
+			state, err := s.session(session)
+			if err != nil {
+				return err
+			}
+
+			_, err = s.as(session).Access(s.ctx, state, AccessPayload{Resource: "documents", Action: "read"})
+			return err
+		},
+	}
+}
+
+// Steal gives an attacker, named thief, a copy of the session's current client state. From then
+// on the two sessions advance independently.
+func Steal(session, thief string) Step {
+	return Step{
+		name: fmt.Sprintf("%s steals %s", thief, session),
+		action: func(s *Simulation) error {
+			state, err := s.session(session)
+			if err != nil {
+				return err
+			}
+
+			if err := s.unused("session", thief, s.sessions[thief] != nil); err != nil {
+				return err
+			}
+
+			stolen := *state
+			stolen.Name = thief
+			s.sessions[thief] = &stolen
+
+			return nil
+		},
+	}
+}
+
+// Replay resends the last message the device or session sent, byte for byte.
+func Replay(actor string) Step {
+	return Step{
+		name: "replay from " + actor,
+		action: func(s *Simulation) error {
+			last, ok := s.transport.sent[actor]
+			if !ok {
+				return misuse("%s has sent nothing to replay", actor)
+			}
+
+			_, err := s.transport.node.Call(s.ctx, last.operation, last.message)
+			return err
+		},
+	}
+}
+
+// Wait advances the simulation's clock.
+func Wait(d time.Duration) Step {
+	return Step{
+		name: fmt.Sprintf("wait %s", d),
+		action: func(s *Simulation) error {
+			s.clock.Advance(d)
+			return nil
+		},
+	}
+}
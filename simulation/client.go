@@ -0,0 +1,83 @@
+package simulation
+
+import (
+	"context"
+
+	"github.com/jasoncolburne/better-auth-go/harness"
+)
+
+// AccessPayload is the request body of the simulated resource server.
+type AccessPayload struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// SessionAttributes are granted to every simulated session.
+var SessionAttributes = Attributes{
+	PermissionsByRole: map[string][]string{
+		"user": {"read"},
+	},
+}
+
+// deviceState is a client device and the account it belongs to.
+type deviceState struct {
+	*harness.Device
+
+	account *harness.Account
+	lost    bool
+}
+
+type sent struct {
+	operation harness.Operation
+	message   string
+}
+
+// transport hands requests to the node, remembering the last message each actor sent for replay.
+type transport struct {
+	node  *harness.Node
+	actor string
+	sent  map[string]sent
+}
+
+func (t *transport) Call(ctx context.Context, operation harness.Operation, message string) (string, error) {
+	t.sent[t.actor] = sent{operation: operation, message: message}
+
+	return t.node.Call(ctx, operation, message)
+}
+
+// as returns the client, sending on behalf of actor.
+func (s *Simulation) as(actor string) *harness.Client {
+	s.transport.actor = actor
+
+	return s.client
+}
+
+func (s *Simulation) device(name string) (*deviceState, error) {
+	d, ok := s.devices[name]
+	if !ok {
+		return nil, misuse("unknown device %s", name)
+	}
+
+	if d.lost {
+		return nil, misuse("device %s was lost", name)
+	}
+
+	return d, nil
+}
+
+func (s *Simulation) session(name string) (*harness.Session, error) {
+	session, ok := s.sessions[name]
+	if !ok {
+		return nil, misuse("unknown session %s", name)
+	}
+
+	return session, nil
+}
+
+func (s *Simulation) unused(kind, name string, taken bool) error {
+	if taken {
+		return misuse("%s %s already exists", kind, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package simulation runs scripted protocol scenarios against a harness node and any number of
+// virtual client devices. Scenarios are lists of steps, each asserting the outcome the server must
+// produce, and independent scripts can be interleaved at random to shake out ordering bugs.
+//
+// Every key and nonce is drawn from a seed and the clock only moves when a step waits, so a failing
+// scenario replays exactly.
+package simulation
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/api"
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/harness"
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+)
+
+// Epoch is the time every simulation starts at.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	AccessLifetime  = harness.AccessLifetime
+	RefreshLifetime = harness.RefreshLifetime
+	NonceLifetime   = harness.NonceLifetime
+	AccessWindow    = harness.AccessWindow
+)
+
+// Refused expects a step to fail, whether or not the failure carries a protocol error code. Storage
+// backends report revoked and unknown devices in their own terms.
+const Refused = "refused"
+
+type Attributes = harness.Attributes
+
+type Simulation struct {
+	ctx       context.Context
+	clock     *clock.ManualClock
+	client    *harness.Client
+	transport *transport
+
+	Server   *api.BetterAuthServer[Attributes]
+	Verifier *api.AccessVerifier[Attributes]
+
+	accounts map[string]*harness.Account
+	devices  map[string]*deviceState
+	sessions map[string]*harness.Session
+}
+
+// New builds a harness node, drawing every key and nonce of the server and its clients from seed.
+func New(seed string, options ...api.BetterAuthServerOption) (*Simulation, error) {
+	entropy := crypto.NewSeededEntropy([]byte(seed))
+	clock := clock.NewManualClock(Epoch)
+
+	node, err := harness.New(
+		harness.WithClock(clock),
+		harness.WithEntropy(entropy),
+		harness.WithSessionAttributes(SessionAttributes),
+		harness.WithServerOptions(options...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &transport{
+		node: node,
+		sent: map[string]sent{},
+	}
+
+	return &Simulation{
+		ctx:       context.Background(),
+		clock:     clock,
+		client:    harness.NewClient(transport, harness.NewKeyring(entropy), node.Timestamper),
+		transport: transport,
+		Server:    node.Server,
+		Verifier:  node.Verifier,
+		accounts:  map[string]*harness.Account{},
+		devices:   map[string]*deviceState{},
+		sessions:  map[string]*harness.Session{},
+	}, nil
+}
+
+// Step is one scripted action and the outcome it must have.
+type Step struct {
+	name   string
+	action func(s *Simulation) error
+
+	// expected lists the acceptable error codes, and is empty when the step must succeed.
+	expected []string
+	optional bool
+}
+
+// Fails expects the step to fail with one of codes, or with any error when they include Refused.
+func (st Step) Fails(codes ...string) Step {
+	st.expected = codes
+	st.optional = false
+	return st
+}
+
+// MayFail accepts either success or failure with one of codes, for steps whose outcome depends on
+// how they were interleaved.
+func (st Step) MayFail(codes ...string) Step {
+	st.expected = codes
+	st.optional = true
+	return st
+}
+
+func (st Step) String() string {
+	return st.name
+}
+
+// scriptError reports a scenario that can't be run as written, rather than a server outcome.
+type scriptError struct {
+	err error
+}
+
+func (e *scriptError) Error() string {
+	return e.err.Error()
+}
+
+func misuse(format string, args ...any) error {
+	return &scriptError{err: fmt.Errorf(format, args...)}
+}
+
+func (st Step) check(err error) error {
+	var script *scriptError
+	if stderrors.As(err, &script) {
+		return script
+	}
+
+	if err == nil {
+		if len(st.expected) == 0 || st.optional {
+			return nil
+		}
+
+		return fmt.Errorf("expected %s, got success", strings.Join(st.expected, " or "))
+	}
+
+	code := Refused
+	var betterAuthError *errors.BetterAuthError
+	if stderrors.As(err, &betterAuthError) {
+		code = betterAuthError.Code
+	}
+
+	if slices.Contains(st.expected, code) || slices.Contains(st.expected, Refused) {
+		return nil
+	}
+
+	if len(st.expected) == 0 {
+		return fmt.Errorf("expected success, got %v", err)
+	}
+
+	return fmt.Errorf("expected %s, got %s (%v)", strings.Join(st.expected, " or "), code, err)
+}
+
+// Run performs the steps in order, stopping at the first whose outcome is not the expected one.
+func (s *Simulation) Run(steps ...Step) error {
+	for i, step := range steps {
+		if err := step.check(step.action(s)); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i, step.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Interleave merges the scripts into one, keeping the order of each script's own steps. The merge
+// is drawn from seed, so a failing interleaving can be reproduced.
+func Interleave(seed uint64, scripts ...[]Step) []Step {
+	random := rand.New(rand.NewPCG(seed, 0))
+
+	remaining := make([][]Step, 0, len(scripts))
+	total := 0
+	for _, script := range scripts {
+		if len(script) > 0 {
+			remaining = append(remaining, script)
+			total += len(script)
+		}
+	}
+
+	merged := make([]Step, 0, total)
+	for len(merged) < total {
+		// weighting by the remaining length makes every interleaving equally likely
+		pick := random.IntN(total - len(merged))
+		for i, script := range remaining {
+			if pick >= len(script) {
+				pick -= len(script)
+				continue
+			}
+
+			merged = append(merged, script[0])
+			remaining[i] = script[1:]
+			break
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,219 @@
+package simulation_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/simulation"
+)
+
+func run(t *testing.T, seed string, steps ...simulation.Step) {
+	t.Helper()
+
+	s, err := simulation.New(seed)
+	if err != nil {
+		t.Fatalf("failed to build simulation: %v", err)
+	}
+
+	if err := s.Run(steps...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	run(t, "lifecycle",
+		simulation.CreateAccount("alice", "laptop"),
+		simulation.CreateSession("laptop", "browser"),
+		simulation.Access("browser"),
+		simulation.RotateDevice("laptop"),
+		simulation.ChangeRecoveryKey("laptop"),
+		simulation.Wait(simulation.AccessLifetime+time.Second),
+		simulation.Access("browser").Fails("BA401"),
+		simulation.RefreshSession("browser"),
+		simulation.Access("browser"),
+		simulation.DeleteAccount("laptop"),
+		simulation.RotateDevice("laptop").Fails(simulation.Refused),
+	)
+}
+
+func TestLinkAndUnlink(t *testing.T) {
+	run(t, "link and unlink",
+		simulation.CreateAccount("alice", "laptop"),
+		simulation.LinkDevice("laptop", "phone"),
+		simulation.CreateSession("phone", "app"),
+		simulation.RotateDevice("phone"),
+		simulation.UnlinkDevice("laptop", "phone"),
+		simulation.RotateDevice("phone").Fails(simulation.Refused),
+		simulation.CreateSession("phone", "second app").Fails(simulation.Refused),
+		simulation.RefreshSession("app").Fails(simulation.Refused),
+		simulation.RotateDevice("laptop"),
+	)
+}
+
+func TestLoseDeviceAndRecover(t *testing.T) {
+	run(t, "lose device and recover",
+		simulation.CreateAccount("alice", "laptop"),
+		simulation.LinkDevice("laptop", "phone"),
+		simulation.CreateSession("laptop", "browser"),
+		simulation.LoseDevice("laptop"),
+		simulation.RecoverAccount("alice", "new laptop"),
+		simulation.RotateDevice("phone").Fails(simulation.Refused),
+		simulation.RefreshSession("browser").Fails(simulation.Refused),
+		simulation.CreateSession("new laptop", "new browser"),
+		simulation.Access("new browser"),
+		simulation.LinkDevice("new laptop", "new phone"),
+		// the recovery key was replaced, so recovering again needs the new one
+		simulation.RecoverAccount("alice", "spare laptop"),
+	)
+}
+
+func TestReplayAttacker(t *testing.T) {
+	run(t, "replay attacker",
+		simulation.CreateAccount("alice", "laptop"),
+		simulation.Replay("laptop").Fails(simulation.Refused),
+		simulation.RotateDevice("laptop"),
+		simulation.Replay("laptop").Fails("BA104"),
+		simulation.CreateSession("laptop", "browser"),
+		simulation.Access("browser"),
+		simulation.Replay("browser").Fails(simulation.Refused),
+		simulation.Steal("browser", "mallory"),
+		simulation.RefreshSession("browser"),
+		simulation.RefreshSession("mallory").Fails("BA401"),
+		simulation.RefreshSession("browser").Fails("BA401"),
+		simulation.Access("browser").Fails("BA401"),
+	)
+}
+
+func TestScriptErrorsAreFatal(t *testing.T) {
+	s, err := simulation.New("script errors")
+	if err != nil {
+		t.Fatalf("failed to build simulation: %v", err)
+	}
+
+	steps := [][]simulation.Step{
+		{simulation.RotateDevice("laptop").MayFail(simulation.Refused)},
+		{simulation.CreateAccount("alice", "laptop"), simulation.CreateAccount("alice", "phone").Fails(simulation.Refused)},
+		{simulation.LoseDevice("laptop"), simulation.RotateDevice("laptop").Fails(simulation.Refused)},
+	}
+
+	for _, steps := range steps {
+		if err := s.Run(steps...); err == nil {
+			t.Errorf("expected %v to be rejected", steps)
+		}
+	}
+}
+
+func TestUnexpectedOutcomes(t *testing.T) {
+	s, err := simulation.New("unexpected outcomes")
+	if err != nil {
+		t.Fatalf("failed to build simulation: %v", err)
+	}
+
+	if err := s.Run(simulation.CreateAccount("alice", "laptop").Fails("BA101")); err == nil {
+		t.Error("expected success to be reported")
+	}
+
+	if err := s.Run(simulation.Replay("laptop")); err == nil {
+		t.Error("expected failure to be reported")
+	}
+
+	if err := s.Run(simulation.CreateSession("laptop", "browser"), simulation.Access("browser"), simulation.Replay("browser").Fails("BA401")); err == nil {
+		t.Error("expected the wrong error code to be reported")
+	}
+}
+
+func names(steps []simulation.Step) []string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.String()
+	}
+
+	return names
+}
+
+func TestInterleave(t *testing.T) {
+	scripts := [][]simulation.Step{
+		{simulation.Wait(1), simulation.Wait(2), simulation.Wait(3)},
+		{simulation.Wait(4), simulation.Wait(5)},
+		{},
+		{simulation.Wait(6)},
+	}
+
+	orders := map[string]bool{}
+	for seed := range uint64(100) {
+		merged := names(simulation.Interleave(seed, scripts...))
+		if !slices.Equal(merged, names(simulation.Interleave(seed, scripts...))) {
+			t.Fatalf("seed %d: interleaving is not reproducible", seed)
+		}
+
+		orders[fmt.Sprint(merged)] = true
+
+		for _, script := range scripts {
+			position := -1
+			for _, step := range script {
+				next := slices.Index(merged, step.String())
+				if next <= position {
+					t.Fatalf("seed %d: %v reorders %v", seed, merged, script)
+				}
+				position = next
+			}
+		}
+
+		if len(merged) != 6 {
+			t.Fatalf("seed %d: expected 6 steps, got %d", seed, len(merged))
+		}
+	}
+
+	// there are 60 interleavings of the scripts
+	if len(orders) < 30 {
+		t.Errorf("expected varied interleavings, got %d", len(orders))
+	}
+}
+
+// accountScript is an account's whole life. Its steps only touch its own account, so interleaving
+// it with other accounts only changes how much time has passed, and access tokens may expire.
+func accountScript(name string) []simulation.Step {
+	device := func(suffix string) string {
+		return name + " " + suffix
+	}
+
+	return []simulation.Step{
+		simulation.CreateAccount(name, device("laptop")),
+		simulation.CreateSession(device("laptop"), device("browser")),
+		simulation.LinkDevice(device("laptop"), device("phone")),
+		simulation.CreateSession(device("phone"), device("app")),
+		simulation.Access(device("app")).MayFail("BA401"),
+		simulation.RotateDevice(device("phone")),
+		simulation.Steal(device("app"), device("thief")),
+		simulation.RefreshSession(device("app")),
+		simulation.RefreshSession(device("thief")).Fails("BA401"),
+		simulation.Access(device("app")).Fails("BA401"),
+		simulation.UnlinkDevice(device("laptop"), device("phone")),
+		simulation.RefreshSession(device("browser")),
+		simulation.RotateDevice(device("phone")).Fails(simulation.Refused),
+		simulation.LoseDevice(device("laptop")),
+		simulation.RecoverAccount(name, device("spare")),
+		simulation.Access(device("browser")).MayFail("BA401"),
+		simulation.CreateSession(device("spare"), device("spare browser")),
+		simulation.Access(device("spare browser")).MayFail("BA401"),
+		simulation.DeleteAccount(device("spare")),
+	}
+}
+
+func TestRandomInterleavings(t *testing.T) {
+	for seed := range uint64(20) {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			waits := []simulation.Step{
+				simulation.Wait(time.Minute),
+				simulation.Wait(simulation.AccessLifetime),
+				simulation.Wait(time.Minute),
+			}
+
+			run(t, fmt.Sprintf("interleaving %d", seed),
+				simulation.Interleave(seed, accountScript("alice"), accountScript("bob"), accountScript("carol"), waits)...,
+			)
+		})
+	}
+}
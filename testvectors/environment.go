@@ -0,0 +1,208 @@
+package testvectors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/harness"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/errors"
+)
+
+// Epoch is the time every scenario starts at.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type Attributes = harness.Attributes
+
+// SessionAttributes are granted to every session created in the scenarios.
+var SessionAttributes = Attributes{
+	PermissionsByRole: map[string][]string{
+		"admin": {"read", "write"},
+	},
+}
+
+// DeriveScalar derives the private scalar of the named key.
+func DeriveScalar(name string) []byte {
+	scalar := sha256.Sum256([]byte("better-auth-go test vector " + name))
+	return scalar[:]
+}
+
+// NewKey derives the named key, which signs deterministically.
+func NewKey(name string) (cryptointerfaces.SigningKey, error) {
+	return crypto.NewSecp256r1FromScalar(DeriveScalar(name))
+}
+
+// sequenceNoncer generates the nonces of a labelled sequence, in the same format as crypto.Noncer.
+type sequenceNoncer struct {
+	mu    sync.Mutex
+	label string
+	count int
+}
+
+func newSequenceNoncer(label string) *sequenceNoncer {
+	return &sequenceNoncer{label: label}
+}
+
+func (n *sequenceNoncer) Generate128() (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.count++
+	digest := sha256.Sum256(fmt.Appendf(nil, "%s:%d", n.label, n.count))
+
+	entropy := [18]byte{}
+	copy(entropy[2:], digest[:16])
+	salt := base64.URLEncoding.EncodeToString(entropy[:])
+
+	return "0A" + salt[2:], nil
+}
+
+// nonceStore is an authentication nonce store drawing from a sequence.
+type nonceStore struct {
+	mu          sync.Mutex
+	clock       *clock.ManualClock
+	noncer      *sequenceNoncer
+	identities  map[string]string
+	expirations map[string]time.Time
+}
+
+func newNonceStore(clock *clock.ManualClock) *nonceStore {
+	return &nonceStore{
+		clock:       clock,
+		noncer:      newSequenceNoncer("authentication"),
+		identities:  map[string]string{},
+		expirations: map[string]time.Time{},
+	}
+}
+
+func (s *nonceStore) Generate(ctx context.Context, identity string) (string, error) {
+	nonce, err := s.noncer.Generate128()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.identities[nonce] = identity
+	s.expirations[nonce] = s.clock.Now().Add(harness.NonceLifetime)
+
+	return nonce, nil
+}
+
+func (s *nonceStore) Verify(ctx context.Context, nonce string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := s.identities[nonce]
+	if !ok {
+		return "", fmt.Errorf("nonce not found")
+	}
+
+	if !s.clock.Now().Before(s.expirations[nonce]) {
+		return "", fmt.Errorf("expired nonce")
+	}
+
+	return identity, nil
+}
+
+// environment is a harness node, with every source of time and entropy fixed.
+type environment struct {
+	ctx   context.Context
+	clock *clock.ManualClock
+	node  *harness.Node
+}
+
+func newEnvironment() (*environment, error) {
+	clock := clock.NewManualClock(Epoch)
+
+	serverResponseKey, err := NewKey(ServerResponseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	serverAccessKey, err := NewKey(ServerAccessKey)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := harness.New(
+		harness.WithClock(clock),
+		harness.WithKeys(serverResponseKey, serverAccessKey),
+		harness.WithNoncer(newSequenceNoncer("session")),
+		harness.WithAuthenticationNonceStore(newNonceStore(clock)),
+		harness.WithSessionAttributes(SessionAttributes),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &environment{
+		ctx:   context.Background(),
+		clock: clock,
+		node:  node,
+	}, nil
+}
+
+// Call hands the request to the node, so the environment is the transport of scenario clients.
+// Access requests respond with the verified request payload.
+func (e *environment) Call(ctx context.Context, operation harness.Operation, message string) (string, error) {
+	return e.node.Call(ctx, operation, message)
+}
+
+// execute advances the clock and hands the request to the operation's handler.
+func (e *environment) execute(step Step) (string, error) {
+	e.clock.Advance(time.Duration(step.AdvanceSeconds) * time.Second)
+
+	return e.Call(e.ctx, harness.Operation(step.Operation), step.Request)
+}
+
+// Replay runs the scenario against a fresh environment, failing at the first step whose outcome
+// differs from the transcript.
+func Replay(scenario Scenario) error {
+	e, err := newEnvironment()
+	if err != nil {
+		return err
+	}
+
+	for i, step := range scenario.Steps {
+		response, err := e.execute(step)
+
+		code, err := errorCode(err)
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %w", i, step.Operation, err)
+		}
+
+		if code != step.Error {
+			return fmt.Errorf("step %d (%s): expected error %q, got %q", i, step.Operation, step.Error, code)
+		}
+
+		if response != step.Response {
+			return fmt.Errorf("step %d (%s): expected response %s, got %s", i, step.Operation, step.Response, response)
+		}
+	}
+
+	return nil
+}
+
+// errorCode extracts the protocol error code. Failures without one are not part of the protocol,
+// so they are returned as errors.
+func errorCode(err error) (string, error) {
+	if err == nil {
+		return "", nil
+	}
+
+	var betterAuthError *errors.BetterAuthError
+	if stderrors.As(err, &betterAuthError) {
+		return betterAuthError.Code, nil
+	}
+
+	return "", err
+}
@@ -0,0 +1,676 @@
+package testvectors
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jasoncolburne/better-auth-go/examples/clock"
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/harness"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/encodinginterfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+)
+
+const (
+	ServerResponseKey = "server response"
+	ServerAccessKey   = "server access"
+)
+
+// AccessPayload is the request body of every access request in the scenarios.
+type AccessPayload struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+type generator struct {
+	corpus *Corpus
+	keys   map[string]cryptointerfaces.SigningKey
+	hashes map[string]bool
+}
+
+// Generate derives the complete corpus. It fails if any scenario step does not have the outcome it
+// was scripted with.
+func Generate() (*Corpus, error) {
+	g := &generator{
+		corpus: &Corpus{},
+		keys:   map[string]cryptointerfaces.SigningKey{},
+		hashes: map[string]bool{},
+	}
+
+	steps := []func() error{
+		g.generateScenarios,
+		g.generateSignatures,
+		g.generateTokens,
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.corpus, nil
+}
+
+// key derives the named key, recording it the first time it is used.
+func (g *generator) key(name string) (cryptointerfaces.SigningKey, error) {
+	if key, ok := g.keys[name]; ok {
+		return key, nil
+	}
+
+	key, err := NewKey(name)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := key.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	g.keys[name] = key
+	g.corpus.Keys = append(g.corpus.Keys, Key{
+		Name:      name,
+		Scalar:    hex.EncodeToString(DeriveScalar(name)),
+		PublicKey: publicKey,
+	})
+
+	return key, nil
+}
+
+// hash digests the concatenated inputs, recording the named digest the first time it is computed.
+func (g *generator) hash(hasher *crypto.Blake3, name string, inputs ...string) string {
+	message := ""
+	for _, input := range inputs {
+		message += input
+	}
+
+	digest := hasher.Sum([]byte(message))
+
+	if !g.hashes[name] {
+		g.hashes[name] = true
+		g.corpus.Hashes = append(g.corpus.Hashes, Hash{
+			Name:   name,
+			Inputs: inputs,
+			Digest: digest,
+		})
+	}
+
+	return digest
+}
+
+func (g *generator) generateSignatures() error {
+	inputs := []string{
+		"",
+		"better-auth",
+		`{"access":{"nonce":"0A0123456789abcdefghij"},"request":{}}`,
+		"ünïcödé ✓",
+	}
+
+	for _, name := range []string{ServerResponseKey, "alice device 0"} {
+		key, err := g.key(name)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range inputs {
+			signature, err := key.Sign([]byte(message))
+			if err != nil {
+				return err
+			}
+
+			g.corpus.Signatures = append(g.corpus.Signatures, Signature{
+				Key:       name,
+				Message:   message,
+				Signature: signature,
+			})
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) generateTokens() error {
+	key, err := g.key(ServerAccessKey)
+	if err != nil {
+		return err
+	}
+
+	serverIdentity, err := key.Identity()
+	if err != nil {
+		return err
+	}
+
+	accessKey, err := g.key("alice access 0")
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := accessKey.Public()
+	if err != nil {
+		return err
+	}
+
+	noncer := newSequenceNoncer("token")
+
+	session, err := noncer.Generate128()
+	if err != nil {
+		return err
+	}
+
+	chain, err := noncer.Generate128()
+	if err != nil {
+		return err
+	}
+
+	hasher := crypto.NewBlake3()
+	timestamper := encoding.NewRfc3339WithClock(clock.NewManualClock(Epoch))
+	at := func(d time.Duration) string {
+		return timestamper.Format(Epoch.Add(d))
+	}
+
+	tokens := []struct {
+		name  string
+		token *messages.AccessToken[Attributes]
+	}{
+		{
+			"minimal",
+			messages.NewAccessToken(
+				serverIdentity,
+				hasher.Sum([]byte("device")),
+				hasher.Sum([]byte("identity")),
+				publicKey,
+				hasher.Sum([]byte("rotation")),
+				at(0),
+				at(harness.AccessLifetime),
+				at(harness.RefreshLifetime),
+				Attributes{},
+			),
+		},
+		{
+			"complete",
+			messages.NewAccessToken(
+				serverIdentity,
+				hasher.Sum([]byte("device")),
+				hasher.Sum([]byte("identity")),
+				publicKey,
+				hasher.Sum([]byte("rotation")),
+				at(time.Hour),
+				at(time.Hour+harness.AccessLifetime),
+				at(time.Hour+harness.RefreshLifetime),
+				SessionAttributes,
+				messages.WithSessionExpiry(at(7*24*time.Hour)),
+				messages.WithAuthenticatedAt(at(0)),
+				messages.WithSession(session),
+				messages.WithChain(chain),
+				messages.WithTenant("tenant-a"),
+			),
+		},
+	}
+
+	for _, token := range tokens {
+		if err := token.token.Sign(key); err != nil {
+			return err
+		}
+
+		payload, err := token.token.ComposePayload()
+		if err != nil {
+			return err
+		}
+
+		serialized, err := token.token.SerializeToken(encoding.NewTokenEncoder[Attributes]())
+		if err != nil {
+			return err
+		}
+
+		g.corpus.Tokens = append(g.corpus.Tokens, Token{
+			Name:    token.name,
+			Key:     ServerAccessKey,
+			Payload: payload,
+			Token:   serialized,
+		})
+	}
+
+	return nil
+}
+
+// script records a scenario as it is run. It is the keyring and transport of the scenario's client,
+// so every key and digest the client uses is recorded, and every request becomes a step. Errors are
+// sticky: once a step fails, the rest of the script does nothing and the first error is reported.
+type script struct {
+	g        *generator
+	env      *environment
+	noncer   *sequenceNoncer
+	client   *harness.Client
+	scenario Scenario
+	advance  time.Duration
+	err      error
+}
+
+func (g *generator) script(name, description string, body func(s *script)) error {
+	env, err := newEnvironment()
+	if err != nil {
+		return err
+	}
+
+	s := &script{
+		g:      g,
+		env:    env,
+		noncer: newSequenceNoncer("client"),
+		scenario: Scenario{
+			Name:        name,
+			Description: description,
+			Steps:       []Step{},
+		},
+	}
+	s.client = harness.NewClient(s, s, env.node.Timestamper)
+
+	// the server keys are recorded with every scenario's, so that they are first in the corpus
+	for _, name := range []string{ServerResponseKey, ServerAccessKey} {
+		s.key(name)
+	}
+
+	body(s)
+
+	if s.err != nil {
+		return fmt.Errorf("%s: %w", name, s.err)
+	}
+
+	g.corpus.Scenarios = append(g.corpus.Scenarios, s.scenario)
+
+	return nil
+}
+
+func (s *script) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *script) Key(name string) (cryptointerfaces.SigningKey, error) {
+	return s.g.key(name)
+}
+
+func (s *script) Hash(name string, inputs ...string) string {
+	return s.g.hash(s.env.node.Hasher, name, inputs...)
+}
+
+func (s *script) Nonce() (string, error) {
+	return s.noncer.Generate128()
+}
+
+// Call records a step. The clock has already been moved by wait, and the step records the advance.
+func (s *script) Call(ctx context.Context, operation harness.Operation, message string) (string, error) {
+	response, err := s.env.Call(ctx, operation, message)
+
+	code, codeErr := errorCode(err)
+	if codeErr == nil {
+		s.scenario.Steps = append(s.scenario.Steps, Step{
+			Operation:      string(operation),
+			AdvanceSeconds: int64(s.advance / time.Second),
+			Request:        message,
+			Response:       response,
+			Error:          code,
+		})
+		s.advance = 0
+	}
+
+	return response, err
+}
+
+// expect fails the script unless err carries the expected error code, or is nil when expected is
+// empty.
+func (s *script) expect(operation string, err error, expected string) {
+	code, err := errorCode(err)
+	if err != nil {
+		s.fail(fmt.Errorf("%s: %w", operation, err))
+		return
+	}
+
+	if code != expected {
+		s.fail(fmt.Errorf("%s: expected error %q, got %q", operation, expected, code))
+	}
+}
+
+func (s *script) key(name string) cryptointerfaces.SigningKey {
+	if s.err != nil {
+		return nil
+	}
+
+	key, err := s.Key(name)
+	if err != nil {
+		s.fail(err)
+		return nil
+	}
+
+	return key
+}
+
+func (s *script) public(key cryptointerfaces.SigningKey) string {
+	if s.err != nil {
+		return ""
+	}
+
+	publicKey, err := key.Public()
+	if err != nil {
+		s.fail(err)
+		return ""
+	}
+
+	return publicKey
+}
+
+func (s *script) hash(name string, inputs ...string) string {
+	return s.Hash(name, inputs...)
+}
+
+func (s *script) nonce() string {
+	nonce, err := s.Nonce()
+	if err != nil {
+		s.fail(err)
+	}
+
+	return nonce
+}
+
+// wait advances the shared clock. The step that follows records the advance.
+func (s *script) wait(d time.Duration) {
+	s.env.clock.Advance(d)
+	s.advance += d
+}
+
+// message signs request with key, if given, and serializes it.
+func (s *script) message(request harness.Signable, key cryptointerfaces.SigningKey) string {
+	if s.err != nil {
+		return ""
+	}
+
+	message, err := harness.Message(request, key)
+	if err != nil {
+		s.fail(err)
+		return ""
+	}
+
+	return message
+}
+
+// send sends a prepared message, failing the script unless it fails with expected, or succeeds when
+// expected is empty.
+func (s *script) send(operation harness.Operation, message, expected string) string {
+	if s.err != nil {
+		return ""
+	}
+
+	response, err := s.Call(s.env.ctx, operation, message)
+	s.expect(string(operation), err, expected)
+
+	return response
+}
+
+func (s *script) createAccount(name string, expected string) *harness.Account {
+	if s.err != nil {
+		return nil
+	}
+
+	a, err := s.client.CreateAccount(s.env.ctx, name, name)
+	s.expect("createAccount", err, expected)
+
+	return a
+}
+
+// rotateDevice presents key, normally the committed next key, as the device's new key.
+func (s *script) rotateDevice(d *harness.Device, key cryptointerfaces.SigningKey, expected string) {
+	if s.err != nil {
+		return
+	}
+
+	s.expect("rotateDevice", s.client.RotateDevice(s.env.ctx, d, key), expected)
+}
+
+func (s *script) changeRecoveryKey(a *harness.Account) {
+	if s.err != nil {
+		return
+	}
+
+	s.expect("changeRecoveryKey", s.client.ChangeRecoveryKey(s.env.ctx, a, a.Device), "")
+}
+
+// recoverAccount replaces the account's device with a new one, named name, using the recovery key.
+func (s *script) recoverAccount(a *harness.Account, name string) {
+	if s.err != nil {
+		return
+	}
+
+	_, err := s.client.RecoverAccount(s.env.ctx, a, name)
+	s.expect("recoverAccount", err, "")
+}
+
+func (s *script) deleteAccount(a *harness.Account) {
+	if s.err != nil {
+		return
+	}
+
+	s.expect("deleteAccount", s.client.DeleteAccount(s.env.ctx, a.Device), "")
+}
+
+// linkDevice links a new device, named name, to the identity claimed in its link container.
+func (s *script) linkDevice(a *harness.Account, name, identity, expected string) *harness.Device {
+	if s.err != nil {
+		return nil
+	}
+
+	linked, err := s.client.LinkDevice(s.env.ctx, a.Device, name, identity)
+	s.expect("linkDevice", err, expected)
+
+	return linked
+}
+
+func (s *script) unlinkDevice(a *harness.Account, linked *harness.Device) {
+	if s.err != nil {
+		return
+	}
+
+	s.expect("unlinkDevice", s.client.UnlinkDevice(s.env.ctx, a.Device, linked), "")
+}
+
+// createSession authenticates the device and opens a session, whose access keys are named for the
+// device.
+func (s *script) createSession(d *harness.Device) *harness.Session {
+	if s.err != nil {
+		return nil
+	}
+
+	session, err := s.client.CreateSession(s.env.ctx, d, d.Name)
+	s.expect("createSession", err, "")
+
+	return session
+}
+
+// refreshMessage builds a refresh request for the session's current link, and the key it commits to.
+func (s *script) refreshMessage(session *harness.Session) (string, cryptointerfaces.SigningKey) {
+	if s.err != nil {
+		return "", nil
+	}
+
+	message, nextNext, err := s.client.RefreshMessage(session)
+	if err != nil {
+		s.fail(err)
+	}
+
+	return message, nextNext
+}
+
+// refreshSession sends a refresh message, advancing the session on success.
+func (s *script) refreshSession(session *harness.Session, message string, nextNext cryptointerfaces.SigningKey, expected string) {
+	reply := s.send(harness.RefreshSession, message, expected)
+	if s.err != nil || expected != "" {
+		return
+	}
+
+	if err := s.client.Refreshed(session, reply, nextNext); err != nil {
+		s.fail(err)
+	}
+}
+
+// accessMessage builds an access request timestamped by timestamper, the shared clock when nil.
+func (s *script) accessMessage(session *harness.Session, timestamper encodinginterfaces.Timestamper, payload AccessPayload) string {
+	if s.err != nil {
+		return ""
+	}
+
+	message, err := s.client.AccessMessage(session, timestamper, payload)
+	if err != nil {
+		s.fail(err)
+	}
+
+	return message
+}
+
+func (s *script) access(session *harness.Session, expected string) {
+	s.send(harness.Access, s.accessMessage(session, nil, AccessPayload{Resource: "documents", Action: "read"}), expected)
+}
+
+func (g *generator) generateScenarios() error {
+	scripts := []struct {
+		name        string
+		description string
+		body        func(s *script)
+	}{
+		{
+			"account lifecycle",
+			"an account is created, rotates its device key, changes its recovery key and is deleted",
+			func(s *script) {
+				a := s.createAccount("alice", "")
+				s.wait(time.Minute)
+				s.rotateDevice(a.Device, a.Device.Next, "")
+				s.changeRecoveryKey(a)
+				s.deleteAccount(a)
+			},
+		},
+		{
+			"session lifecycle",
+			"a session is created and used, refreshed as its token expires, and its refreshed token expires",
+			func(s *script) {
+				a := s.createAccount("alice", "")
+				session := s.createSession(a.Device)
+				s.access(session, "")
+				s.wait(14 * time.Minute)
+				message, nextNext := s.refreshMessage(session)
+				s.refreshSession(session, message, nextNext, "")
+				s.access(session, "")
+				s.wait(harness.AccessLifetime + time.Second)
+				s.access(session, "BA401")
+			},
+		},
+		{
+			"refresh reuse",
+			"a refresh token is presented twice, which revokes the session",
+			func(s *script) {
+				a := s.createAccount("alice", "")
+				session := s.createSession(a.Device)
+				stolen, _ := s.refreshMessage(session)
+				message, nextNext := s.refreshMessage(session)
+				s.refreshSession(session, message, nextNext, "")
+				s.access(session, "")
+				s.send(harness.RefreshSession, stolen, "BA401")
+				s.access(session, "BA401")
+				message, nextNext = s.refreshMessage(session)
+				s.refreshSession(session, message, nextNext, "BA401")
+			},
+		},
+		{
+			"rotation conflict",
+			"a rotation is replayed with the key it already consumed, and with a key never committed to",
+			func(s *script) {
+				a := s.createAccount("alice", "")
+				previous := a.Device.Next
+				s.rotateDevice(a.Device, previous, "")
+				s.rotateDevice(a.Device, previous, "BA104")
+				s.rotateDevice(a.Device, s.key("mallory device 0"), "BA104")
+				s.rotateDevice(a.Device, a.Device.Next, "")
+			},
+		},
+		{
+			"device linking",
+			"a second device is linked and opens its own session, then is unlinked; a link container for another identity is refused",
+			func(s *script) {
+				a := s.createAccount("alice", "")
+				linked := s.linkDevice(a, "alice phone", a.Identity, "")
+				s.createSession(linked)
+				s.unlinkDevice(a, linked)
+				s.linkDevice(a, "alice tablet", s.hash("mallory link identity", "mallory"), "BA302")
+			},
+		},
+		{
+			"account recovery",
+			"a lost device is replaced using the recovery key, and the new device opens a session",
+			func(s *script) {
+				a := s.createAccount("alice", "")
+				s.recoverAccount(a, "alice laptop")
+				s.createSession(a.Device)
+			},
+		},
+		{
+			"request validation",
+			"requests with a bad device hash, an unsupported version, or a stale or future timestamp are refused",
+			func(s *script) {
+				current := s.key("mallory device 0")
+				next := s.key("mallory device 1")
+				publicKey := s.public(current)
+				rotationHash := s.hash("mallory device 0 rotation hash", s.public(next))
+				recoveryHash := s.hash("mallory recovery 0 hash", s.public(s.key("mallory recovery 0")))
+
+				request := messages.NewCreateAccountRequest(
+					messages.CreateAccountRequestPayload{
+						Authentication: messages.CreateAccountRequestAuthentication{
+							Device:       s.hash("mallory device 0 mismatched", publicKey),
+							Identity:     s.hash("mallory identity", publicKey, rotationHash, recoveryHash),
+							PublicKey:    publicKey,
+							RecoveryHash: recoveryHash,
+							RotationHash: rotationHash,
+						},
+					},
+					s.nonce(),
+				)
+
+				s.send(harness.CreateAccount, s.message(request, current), "BA103")
+
+				a := s.createAccount("alice", "")
+
+				versioned := messages.NewRequestSessionRequest(
+					messages.RequestSessionRequestPayload{
+						Authentication: messages.RequestSessionRequestAuthentication{
+							Identity: a.Identity,
+						},
+					},
+					s.nonce(),
+				)
+				versioned.Payload.Access.Version = "2"
+
+				s.send(harness.RequestSession, s.message(versioned, nil), "BA101")
+
+				session := s.createSession(a.Device)
+				payload := AccessPayload{Resource: "documents", Action: "write"}
+
+				stale := s.accessMessage(session, nil, payload)
+				s.wait(time.Minute)
+				s.send(harness.Access, stale, "BA501")
+
+				ahead := encoding.NewRfc3339WithClock(clock.NewManualClock(s.env.clock.Now().Add(time.Minute)))
+				s.send(harness.Access, s.accessMessage(session, ahead, payload), "BA502")
+			},
+		},
+	}
+
+	for _, script := range scripts {
+		if err := g.script(script.name, script.description, script.body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+// Package testvectors generates and replays the protocol test vectors shared with the other
+// better-auth implementations: deterministic keys, derived hashes, signatures, access tokens and
+// transcripts of every operation with their exact responses or expected error codes.
+//
+// Everything is derived from fixed names and a fixed epoch, and all signatures are RFC 6979, so
+// regenerating the vectors reproduces the fixtures byte for byte. The one exception across
+// implementations is the gzip stream in access tokens, which is exactly what Go's compress/gzip
+// writes: another compressor may produce different bytes for the same payload, so compare tokens by
+// their decompressed payloads and signatures.
+package testvectors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Key is a secp256r1 key, named for where it is used in the other vectors.
+type Key struct {
+	Name string `json:"name"`
+	// Scalar is the hex encoded private scalar.
+	Scalar    string `json:"scalar"`
+	PublicKey string `json:"publicKey"`
+}
+
+// Hash is the Blake3 digest of the concatenated inputs, as the protocol derives rotation hashes,
+// devices and identities.
+type Hash struct {
+	Name   string   `json:"name"`
+	Inputs []string `json:"inputs"`
+	Digest string   `json:"digest"`
+}
+
+// Signature is the deterministic signature of Message by the named key.
+type Signature struct {
+	Key       string `json:"key"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+// Token is a serialized access token, signed by the named key over Payload. Its body is compressed
+// by Go's compress/gzip at gzip.BestCompression (level 9).
+type Token struct {
+	Name    string `json:"name"`
+	Key     string `json:"key"`
+	Payload string `json:"payload"`
+	Token   string `json:"token"`
+}
+
+// Step is one operation against the server. The clock is advanced before the request is handled,
+// which either succeeds with exactly Response or fails with the error code Error.
+type Step struct {
+	Operation      string `json:"operation"`
+	AdvanceSeconds int64  `json:"advanceSeconds,omitempty"`
+	Request        string `json:"request"`
+	Response       string `json:"response,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Scenario is a transcript replayed in order against a fresh server.
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Steps       []Step `json:"steps"`
+}
+
+type Corpus struct {
+	Keys       []Key
+	Hashes     []Hash
+	Signatures []Signature
+	Tokens     []Token
+	Scenarios  []Scenario
+}
+
+type fixture struct {
+	file        string
+	description string
+	field       string
+	value       any
+}
+
+func (c *Corpus) fixtures() []fixture {
+	return []fixture{
+		{"keys.json", "secp256r1 keys used throughout the vectors", "keys", &c.Keys},
+		{"hashes.json", "Blake3 digests of concatenated inputs", "hashes", &c.Hashes},
+		{"signatures.json", "RFC 6979 secp256r1 signatures over SHA-256", "signatures", &c.Signatures},
+		{"tokens.json", "access tokens signed by the server access key; token bodies are gzip exactly as written by Go's compress/gzip at level 9, so compare decompressed payloads rather than token bytes", "tokens", &c.Tokens},
+		{"scenarios.json", "server transcripts, starting at " + Epoch.Format("2006-01-02T15:04:05Z") + "; access tokens in responses are gzip exactly as written by Go's compress/gzip at level 9", "scenarios", &c.Scenarios},
+	}
+}
+
+// Marshal renders each fixture file of the corpus.
+func (c *Corpus) Marshal() (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	for _, fixture := range c.fixtures() {
+		value, err := json.Marshal(fixture.value)
+		if err != nil {
+			return nil, err
+		}
+
+		document := fmt.Sprintf(`{"description":%q,%q:%s}`, fixture.description, fixture.field, value)
+
+		var buffer bytes.Buffer
+		if err := json.Indent(&buffer, []byte(document), "", "  "); err != nil {
+			return nil, err
+		}
+		buffer.WriteByte('\n')
+
+		files[fixture.file] = buffer.Bytes()
+	}
+
+	return files, nil
+}
+
+// Write writes the fixture files of the corpus to directory.
+func (c *Corpus) Write(directory string) error {
+	files, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), contents, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Load reads the fixture files in directory.
+func Load(directory string) (*Corpus, error) {
+	corpus := &Corpus{}
+
+	for _, fixture := range corpus.fixtures() {
+		data, err := os.ReadFile(filepath.Join(directory, fixture.file))
+		if err != nil {
+			return nil, err
+		}
+
+		document := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("%s: %w", fixture.file, err)
+		}
+
+		if err := json.Unmarshal(document[fixture.field], fixture.value); err != nil {
+			return nil, fmt.Errorf("%s: %w", fixture.file, err)
+		}
+	}
+
+	return corpus, nil
+}
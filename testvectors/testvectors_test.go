@@ -0,0 +1,193 @@
+package testvectors_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/jasoncolburne/better-auth-go/examples/crypto"
+	"github.com/jasoncolburne/better-auth-go/examples/encoding"
+	"github.com/jasoncolburne/better-auth-go/pkg/cesr"
+	"github.com/jasoncolburne/better-auth-go/pkg/cryptointerfaces"
+	"github.com/jasoncolburne/better-auth-go/pkg/messages"
+	"github.com/jasoncolburne/better-auth-go/testvectors"
+)
+
+func loadCorpus(t *testing.T) *testvectors.Corpus {
+	t.Helper()
+
+	corpus, err := testvectors.Load("testdata")
+	if err != nil {
+		t.Fatalf("failed to load test vectors: %v", err)
+	}
+
+	return corpus
+}
+
+func loadKeys(t *testing.T, corpus *testvectors.Corpus) map[string]cryptointerfaces.SigningKey {
+	t.Helper()
+
+	keys := map[string]cryptointerfaces.SigningKey{}
+	for _, vector := range corpus.Keys {
+		scalar, err := hex.DecodeString(vector.Scalar)
+		if err != nil {
+			t.Fatalf("%s: failed to decode scalar: %v", vector.Name, err)
+		}
+
+		key, err := crypto.NewSecp256r1FromScalar(scalar)
+		if err != nil {
+			t.Fatalf("%s: failed to load key: %v", vector.Name, err)
+		}
+
+		keys[vector.Name] = key
+	}
+
+	return keys
+}
+
+func TestFixturesAreCurrent(t *testing.T) {
+	corpus, err := testvectors.Generate()
+	if err != nil {
+		t.Fatalf("failed to generate test vectors: %v", err)
+	}
+
+	files, err := corpus.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal test vectors: %v", err)
+	}
+
+	loaded, err := loadCorpus(t).Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal loaded test vectors: %v", err)
+	}
+
+	for name, contents := range files {
+		if !bytes.Equal(contents, loaded[name]) {
+			t.Errorf("%s is out of date, run make vectors", name)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	corpus := loadCorpus(t)
+	keys := loadKeys(t, corpus)
+
+	for _, vector := range corpus.Keys {
+		if vector.Scalar != hex.EncodeToString(testvectors.DeriveScalar(vector.Name)) {
+			t.Errorf("%s: scalar is not derived from the name", vector.Name)
+		}
+
+		publicKey, err := keys[vector.Name].Public()
+		if err != nil {
+			t.Fatalf("%s: failed to derive public key: %v", vector.Name, err)
+		}
+
+		if publicKey != vector.PublicKey {
+			t.Errorf("%s: expected public key %s, got %s", vector.Name, vector.PublicKey, publicKey)
+		}
+	}
+}
+
+func TestHashes(t *testing.T) {
+	hasher := crypto.NewBlake3()
+
+	for _, vector := range loadCorpus(t).Hashes {
+		message := ""
+		for _, input := range vector.Inputs {
+			message += input
+		}
+
+		if digest := hasher.Sum([]byte(message)); digest != vector.Digest {
+			t.Errorf("%s: expected %s, got %s", vector.Name, vector.Digest, digest)
+		}
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	corpus := loadCorpus(t)
+	keys := loadKeys(t, corpus)
+	verifier := crypto.NewSecp256r1Verifier()
+
+	for _, vector := range corpus.Signatures {
+		key := keys[vector.Key]
+
+		publicKey, err := key.Public()
+		if err != nil {
+			t.Fatalf("%s: failed to derive public key: %v", vector.Key, err)
+		}
+
+		if err := verifier.Verify(vector.Signature, publicKey, []byte(vector.Message)); err != nil {
+			t.Errorf("%s %q: signature does not verify: %v", vector.Key, vector.Message, err)
+		}
+
+		signature, err := key.Sign([]byte(vector.Message))
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", vector.Key, err)
+		}
+
+		if signature != vector.Signature {
+			t.Errorf("%s %q: expected signature %s, got %s", vector.Key, vector.Message, vector.Signature, signature)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	corpus := loadCorpus(t)
+	keys := loadKeys(t, corpus)
+	verifier := crypto.NewSecp256r1Verifier()
+	tokenEncoder := encoding.NewTokenEncoder[testvectors.Attributes]()
+
+	for _, vector := range corpus.Tokens {
+		token, err := messages.ParseAccessToken[testvectors.Attributes](vector.Token, tokenEncoder)
+		if err != nil {
+			t.Fatalf("%s: failed to parse token: %v", vector.Name, err)
+		}
+
+		publicKey, err := keys[vector.Key].Public()
+		if err != nil {
+			t.Fatalf("%s: failed to derive public key: %v", vector.Name, err)
+		}
+
+		if err := token.VerifySignature(verifier, publicKey); err != nil {
+			t.Errorf("%s: signature does not verify: %v", vector.Name, err)
+		}
+
+		for name, value := range map[string]string{"session": token.Session, "chain": token.Chain} {
+			if value == "" {
+				continue
+			}
+
+			if err := cesr.Validate(value, cesr.Salt); err != nil {
+				t.Errorf("%s: %s is not a salt: %v", vector.Name, name, err)
+			}
+		}
+
+		payload, err := token.ComposePayload()
+		if err != nil {
+			t.Fatalf("%s: failed to compose payload: %v", vector.Name, err)
+		}
+
+		if payload != vector.Payload {
+			t.Errorf("%s: expected payload %s, got %s", vector.Name, vector.Payload, payload)
+		}
+
+		serialized, err := token.SerializeToken(tokenEncoder)
+		if err != nil {
+			t.Fatalf("%s: failed to serialize token: %v", vector.Name, err)
+		}
+
+		if serialized != vector.Token {
+			t.Errorf("%s: token does not reserialize", vector.Name)
+		}
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	for _, scenario := range loadCorpus(t).Scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			if err := testvectors.Replay(scenario); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}